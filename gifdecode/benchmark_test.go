@@ -1,6 +1,13 @@
 package gifdecode
 
-import "testing"
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+)
 
 func BenchmarkDecodeSmall(b *testing.B) {
 	data := makeTestGIF(4)
@@ -48,3 +55,74 @@ func BenchmarkDecodeFixtures(b *testing.B) {
 		})
 	}
 }
+
+func BenchmarkDecodeScaled(b *testing.B) {
+	data := readFixture(b, "walk-cycle.gif")
+	for _, filter := range []Filter{FilterNearest, FilterBilinear, FilterCatmullRom} {
+		opts := DefaultOptions()
+		opts.TargetCells = CellBox{Cols: 20, Rows: 10}
+		opts.Filter = filter
+		b.Run(filter.String(), func(b *testing.B) {
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := Decode(data, opts); err != nil {
+					b.Fatalf("decode failed: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkDecodeWorkers(b *testing.B) {
+	inputs := []struct {
+		name string
+		data []byte
+	}{
+		{name: "long-200", data: makeLongGIF(200, 320, 240)},
+		{name: "walk-cycle", data: readFixture(b, "walk-cycle.gif")},
+	}
+	for _, input := range inputs {
+		for _, workers := range []int{1, 0} {
+			opts := DefaultOptions()
+			opts.MaxFrames = -1
+			opts.Workers = workers
+			name := fmt.Sprintf("%s/workers=%d", input.name, workers)
+			if workers == 0 {
+				name = input.name + "/workers=auto"
+			}
+			b.Run(name, func(b *testing.B) {
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if _, err := Decode(input.data, opts); err != nil {
+						b.Fatalf("decode failed: %v", err)
+					}
+				}
+			})
+		}
+	}
+}
+
+// makeLongGIF builds a GIF whose frames each redraw a shifted pattern, so
+// every frame is a full-size PNG encode.
+func makeLongGIF(count, width, height int) []byte {
+	pal := make(color.Palette, 0, 16)
+	for i := 0; i < 16; i++ {
+		pal = append(pal, color.RGBA{R: uint8(i * 16), G: uint8(255 - i*16), B: uint8(i * 8), A: 0xff})
+	}
+	g := &gif.GIF{
+		Config: image.Config{Width: width, Height: height, ColorModel: pal},
+	}
+	for f := 0; f < count; f++ {
+		frame := image.NewPaletted(image.Rect(0, 0, width, height), pal)
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				frame.SetColorIndex(x, y, uint8((x*y+f*7+x/3)%len(pal)))
+			}
+		}
+		g.Image = append(g.Image, frame)
+		g.Delay = append(g.Delay, 4)
+	}
+	var buf bytes.Buffer
+	_ = gif.EncodeAll(&buf, g)
+	return buf.Bytes()
+}
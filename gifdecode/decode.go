@@ -17,12 +17,24 @@ import (
 type Frame struct {
 	PNG   []byte
 	Delay time.Duration
+	// Delta is set by Options.Deltas for frames after the first.
+	Delta *Delta
+}
+
+// Delta holds the pixels that changed since the previous frame: drawing PNG
+// at Bounds.Min over the previous frame, replacing what is there, gives
+// this frame.
+type Delta struct {
+	PNG    []byte
+	Bounds image.Rectangle
 }
 
 type Frames struct {
 	Frames []Frame
 	Width  int
 	Height int
+	// Warnings lists problems that Options.Recover decoded around.
+	Warnings []string
 }
 
 var (
@@ -46,6 +58,11 @@ func DecodeReader(r io.Reader, opts Options) (*Frames, error) {
 func decodeBytes(data []byte, opts Options) (*Frames, error) {
 	g, err := gif.DecodeAll(bytes.NewReader(data))
 	if err != nil {
+		if opts.Recover {
+			if frames, ok := decodeRecovered(data, err, opts); ok {
+				return frames, nil
+			}
+		}
 		if opts.StrictGIF {
 			return nil, err
 		}
@@ -58,6 +75,19 @@ func decodeBytes(data []byte, opts Options) (*Frames, error) {
 	return decodeGIF(g, opts)
 }
 
+func decodeRecovered(data []byte, cause error, opts Options) (*Frames, bool) {
+	g := recoverGIF(data)
+	if g == nil {
+		return nil, false
+	}
+	frames, err := decodeGIF(g, opts)
+	if err != nil {
+		return nil, false
+	}
+	frames.Warnings = append(frames.Warnings, fmt.Sprintf("gif truncated or corrupt after frame %d: %v", len(g.Image), cause))
+	return frames, true
+}
+
 func decodeGIF(g *gif.GIF, opts Options) (*Frames, error) {
 	if len(g.Image) == 0 {
 		return nil, ErrNoFrames
@@ -79,11 +109,21 @@ func decodeGIF(g *gif.GIF, opts Options) (*Frames, error) {
 	prev := image.NewRGBA(canvas.Bounds())
 	bg := backgroundColor(g)
 
+	outW, outH := targetSize(width, height, opts)
+
 	limit := len(g.Image)
 	if opts.MaxFrames > 0 && opts.MaxFrames < limit {
 		limit = opts.MaxFrames
 	}
-	frames := make([]Frame, 0, limit)
+	enc := newFrameEncoder(opts, width, height, outW, outH, limit)
+	delays := make([]time.Duration, 0, limit)
+	var last []byte
+	if opts.MergeDuplicates || opts.Deltas {
+		last = make([]byte, len(canvas.Pix))
+	}
+	// dirty collects the areas drawn or disposed since the last submitted
+	// frame; only they can differ from it.
+	var dirty image.Rectangle
 
 	for i := 0; i < limit; i++ {
 		frame := g.Image[i]
@@ -96,21 +136,74 @@ func decodeGIF(g *gif.GIF, opts Options) (*Frames, error) {
 		}
 
 		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
-		pngData, err := encodePNG(canvas)
-		if err != nil {
-			return nil, err
+		dirty = dirty.Union(frame.Bounds())
+		delay := frameDelay(g, i, opts)
+		if last != nil && len(delays) > 0 && opts.MergeDuplicates && bytes.Equal(last, canvas.Pix) {
+			delays[len(delays)-1] += delay
+		} else {
+			var delta image.Rectangle
+			if opts.Deltas && len(delays) > 0 {
+				delta = changedRect(canvas, last, dirty)
+			}
+			if err := enc.submit(len(delays), canvas, delta); err != nil {
+				break
+			}
+			delays = append(delays, delay)
+			if last != nil {
+				copy(last, canvas.Pix)
+			}
+			dirty = image.Rectangle{}
 		}
-		frames = append(frames, Frame{PNG: pngData, Delay: frameDelay(g, i, opts)})
 
 		switch disposal {
 		case gif.DisposalBackground:
 			draw.Draw(canvas, frame.Bounds(), &image.Uniform{C: bg}, image.Point{}, draw.Src)
+			dirty = dirty.Union(frame.Bounds())
 		case gif.DisposalPrevious:
 			copy(canvas.Pix, prev.Pix)
+			dirty = dirty.Union(frame.Bounds())
 		}
 	}
 
-	return &Frames{Frames: frames, Width: width, Height: height}, nil
+	pngs, deltas, err := enc.wait()
+	if err != nil {
+		return nil, err
+	}
+	frames := make([]Frame, len(delays))
+	for i := range frames {
+		frames[i] = Frame{PNG: pngs[i], Delay: delays[i], Delta: deltas[i]}
+	}
+
+	return &Frames{Frames: frames, Width: outW, Height: outH}, nil
+}
+
+// changedRect returns the bounding box of the pixels inside area that differ
+// between canvas and the previous frame's pixels. Identical frames yield a
+// single pixel, since a delta can't be empty.
+func changedRect(canvas *image.RGBA, prev []byte, area image.Rectangle) image.Rectangle {
+	area = area.Intersect(canvas.Bounds())
+	var r image.Rectangle
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		start := canvas.PixOffset(area.Min.X, y)
+		end := canvas.PixOffset(area.Max.X, y)
+		row, before := canvas.Pix[start:end], prev[start:end]
+		if bytes.Equal(row, before) {
+			continue
+		}
+		left, right := 0, len(row)/4
+		for left < right && bytes.Equal(row[left*4:left*4+4], before[left*4:left*4+4]) {
+			left++
+		}
+		for right > left && bytes.Equal(row[right*4-4:right*4], before[right*4-4:right*4]) {
+			right--
+		}
+		r = r.Union(image.Rect(area.Min.X+left, y, area.Min.X+right, y+1))
+	}
+	if r.Empty() {
+		b := canvas.Bounds()
+		return image.Rect(b.Min.X, b.Min.Y, b.Min.X+1, b.Min.Y+1)
+	}
+	return r
 }
 
 func singleFrame(img image.Image, opts Options) (*Frames, error) {
@@ -122,6 +215,12 @@ func singleFrame(img image.Image, opts Options) (*Frames, error) {
 	if exceedsPixels(width, height, opts.MaxPixels) {
 		return nil, fmt.Errorf("%w: pixels=%d limit=%d", ErrTooLarge, width*height, opts.MaxPixels)
 	}
+	if outW, outH := targetSize(width, height, opts); outW != width || outH != height {
+		scaled := image.NewRGBA(image.Rect(0, 0, outW, outH))
+		scaleInto(scaled, toRGBA(img), opts.Filter)
+		img = scaled
+		width, height = outW, outH
+	}
 	pngData, err := encodePNG(img)
 	if err != nil {
 		return nil, err
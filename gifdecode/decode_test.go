@@ -289,6 +289,58 @@ func TestWithDefaultsMaxDelayClamp(t *testing.T) {
 	}
 }
 
+func TestDecodeMergeDuplicates(t *testing.T) {
+	data := makeRepeatGIF([]int{0, 0, 0, 1, 1, 0}, []int{10, 20, 30, 40, 50, 60})
+
+	plain, err := Decode(data, DefaultOptions())
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if len(plain.Frames) != 6 {
+		t.Fatalf("expected 6 frames without merging, got %d", len(plain.Frames))
+	}
+
+	opts := DefaultOptions()
+	opts.MergeDuplicates = true
+	for _, workers := range []int{1, 3} {
+		opts.Workers = workers
+		merged, err := Decode(data, opts)
+		if err != nil {
+			t.Fatalf("decode failed: %v", err)
+		}
+		want := []time.Duration{600 * time.Millisecond, 900 * time.Millisecond, 600 * time.Millisecond}
+		if len(merged.Frames) != len(want) {
+			t.Fatalf("expected %d merged frames, got %d", len(want), len(merged.Frames))
+		}
+		for i, delay := range want {
+			if merged.Frames[i].Delay != delay {
+				t.Fatalf("frame %d: expected delay %v, got %v", i, delay, merged.Frames[i].Delay)
+			}
+		}
+		if !bytes.Equal(merged.Frames[0].PNG, plain.Frames[2].PNG) || !bytes.Equal(merged.Frames[1].PNG, plain.Frames[3].PNG) {
+			t.Fatalf("merged frames do not match source frames")
+		}
+	}
+}
+
+// makeRepeatGIF builds a 2x2 GIF whose frames light pixel (0,0) or (1,1)
+// according to pattern, so equal pattern entries composite identically.
+func makeRepeatGIF(pattern []int, delays []int) []byte {
+	pal := color.Palette{color.Black, color.White}
+	g := &gif.GIF{
+		Delay:  delays,
+		Config: image.Config{Width: 2, Height: 2, ColorModel: pal},
+	}
+	for _, p := range pattern {
+		frame := image.NewPaletted(image.Rect(0, 0, 2, 2), pal)
+		frame.SetColorIndex(p, p, 1)
+		g.Image = append(g.Image, frame)
+	}
+	var buf bytes.Buffer
+	_ = gif.EncodeAll(&buf, g)
+	return buf.Bytes()
+}
+
 func makeTestGIF(count int) []byte {
 	pal := color.Palette{color.Black, color.White}
 	frames := make([]*image.Paletted, 0, count)
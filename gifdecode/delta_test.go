@@ -0,0 +1,92 @@
+package gifdecode
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"testing"
+)
+
+func TestDecodeDeltasRebuildFrames(t *testing.T) {
+	data := makeMovingGIF()
+	for _, opts := range []Options{
+		{Deltas: true},
+		{Deltas: true, MaxWidth: 20},
+		{Deltas: true, MergeDuplicates: true, Workers: 2},
+	} {
+		decoded, err := Decode(data, opts)
+		if err != nil {
+			t.Fatalf("decode failed: %v", err)
+		}
+		if decoded.Frames[0].Delta != nil {
+			t.Fatalf("first frame must not have a delta")
+		}
+		canvas := image.NewRGBA(image.Rect(0, 0, decoded.Width, decoded.Height))
+		draw.Draw(canvas, canvas.Bounds(), decodeTestPNG(t, decoded.Frames[0].PNG), image.Point{}, draw.Src)
+		for i := 1; i < len(decoded.Frames); i++ {
+			d := decoded.Frames[i].Delta
+			if d == nil {
+				t.Fatalf("frame %d: missing delta", i)
+			}
+			if d.Bounds.Dx()*d.Bounds.Dy() >= decoded.Width*decoded.Height/2 {
+				t.Fatalf("frame %d: delta %v not smaller than the frame", i, d.Bounds)
+			}
+			patch := decodeTestPNG(t, d.PNG)
+			draw.Draw(canvas, d.Bounds, patch, patch.Bounds().Min, draw.Src)
+			want := decodeTestPNG(t, decoded.Frames[i].PNG)
+			if !bytes.Equal(canvas.Pix, toRGBA(want).Pix) {
+				t.Fatalf("opts %+v frame %d: delta does not rebuild the frame", opts, i)
+			}
+		}
+	}
+}
+
+func TestChangedRect(t *testing.T) {
+	canvas := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	prev := make([]byte, len(canvas.Pix))
+	if got := changedRect(canvas, prev, canvas.Bounds()); got != image.Rect(0, 0, 1, 1) {
+		t.Fatalf("expected 1px rect for identical frames, got %v", got)
+	}
+	canvas.SetRGBA(2, 1, color.RGBA{R: 1, A: 255})
+	canvas.SetRGBA(1, 3, color.RGBA{G: 1, A: 255})
+	if got := changedRect(canvas, prev, canvas.Bounds()); got != image.Rect(1, 1, 3, 4) {
+		t.Fatalf("unexpected changed rect: %v", got)
+	}
+	// Only the dirty area is inspected.
+	if got := changedRect(canvas, prev, image.Rect(2, 0, 4, 2)); got != image.Rect(2, 1, 3, 2) {
+		t.Fatalf("unexpected changed rect in area: %v", got)
+	}
+}
+
+// makeMovingGIF moves a 4x4 square across a 40x40 background, disposing
+// each frame to the background, with one repeated frame in the middle.
+func makeMovingGIF() []byte {
+	pal := color.Palette{color.RGBA{0x20, 0x40, 0x60, 0xff}, color.RGBA{0xff, 0xcc, 0x00, 0xff}}
+	g := &gif.GIF{Config: image.Config{Width: 40, Height: 40, ColorModel: pal}}
+	base := image.NewPaletted(image.Rect(0, 0, 40, 40), pal)
+	g.Image = append(g.Image, base)
+	g.Delay = append(g.Delay, 5)
+	g.Disposal = append(g.Disposal, gif.DisposalNone)
+	for _, x := range []int{4, 10, 10, 16, 22} {
+		frame := image.NewPaletted(image.Rect(x, 8, x+4, 12), pal)
+		draw.Draw(frame, frame.Bounds(), &image.Uniform{C: pal[1]}, image.Point{}, draw.Src)
+		g.Image = append(g.Image, frame)
+		g.Delay = append(g.Delay, 5)
+		g.Disposal = append(g.Disposal, gif.DisposalBackground)
+	}
+	var buf bytes.Buffer
+	_ = gif.EncodeAll(&buf, g)
+	return buf.Bytes()
+}
+
+func decodeTestPNG(t *testing.T, data []byte) image.Image {
+	t.Helper()
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("png decode failed: %v", err)
+	}
+	return img
+}
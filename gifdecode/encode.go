@@ -0,0 +1,217 @@
+package gifdecode
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"io"
+	"time"
+)
+
+const defaultEncodeColors = 256
+
+type EncodeOptions struct {
+	// Colors caps the palette size per frame (2-256). One slot is reserved
+	// for transparency when a frame has transparent pixels.
+	Colors int
+	// Dither enables Floyd-Steinberg error diffusion during quantization.
+	Dither bool
+	// LoopCount follows image/gif semantics: 0 loops forever, -1 plays once,
+	// n plays n+1 times.
+	LoopCount int
+}
+
+func (o EncodeOptions) withDefaults() EncodeOptions {
+	if o.Colors <= 0 || o.Colors > 256 {
+		o.Colors = defaultEncodeColors
+	}
+	if o.Colors < 2 {
+		o.Colors = 2
+	}
+	return o
+}
+
+// Encode writes composited frames (as produced by Decode) back out as a GIF.
+func Encode(frames *Frames, opts EncodeOptions) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := EncodeWriter(&buf, frames, opts); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// EncodeWriter is Encode writing to w. Every frame is quantized to its own
+// palette and covers the full canvas.
+func EncodeWriter(w io.Writer, frames *Frames, opts EncodeOptions) error {
+	if frames == nil || len(frames.Frames) == 0 {
+		return ErrNoFrames
+	}
+	opts = opts.withDefaults()
+
+	images := make([]*image.RGBA, 0, len(frames.Frames))
+	for _, frame := range frames.Frames {
+		img, err := png.Decode(bytes.NewReader(frame.PNG))
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrBadFrame, err)
+		}
+		images = append(images, toRGBA(img))
+	}
+
+	width, height := frames.Width, frames.Height
+	if width <= 0 || height <= 0 {
+		b := images[0].Bounds()
+		width, height = b.Dx(), b.Dy()
+	}
+	if width <= 0 || height <= 0 {
+		return ErrInvalidSize
+	}
+	if width > 0xffff || height > 0xffff {
+		return ErrTooLarge
+	}
+
+	out := &gif.GIF{
+		Image:     make([]*image.Paletted, 0, len(images)),
+		Delay:     make([]int, 0, len(images)),
+		Disposal:  make([]byte, 0, len(images)),
+		LoopCount: opts.LoopCount,
+		Config:    image.Config{Width: width, Height: height},
+	}
+	transparent := make([]bool, len(images))
+	for i, img := range images {
+		canvas := image.NewRGBA(image.Rect(0, 0, width, height))
+		draw.Draw(canvas, canvas.Bounds(), img, img.Bounds().Min, draw.Src)
+		transparent[i] = hasTransparency(canvas)
+		out.Image = append(out.Image, quantizeFrame(canvas, transparent[i], opts))
+		out.Delay = append(out.Delay, delayCentis(frames.Frames[i].Delay))
+	}
+	// Every frame covers the full canvas, so the only reason to dispose is to
+	// let the next frame's transparent pixels show through to the background.
+	for i := range images {
+		next := (i + 1) % len(images)
+		if transparent[next] {
+			out.Disposal = append(out.Disposal, gif.DisposalBackground)
+		} else {
+			out.Disposal = append(out.Disposal, gif.DisposalNone)
+		}
+	}
+	return gif.EncodeAll(w, out)
+}
+
+// Quantize reduces img to a palette of at most maxColors (2-256) colors.
+// When img has transparent pixels, the last palette entry is transparent.
+func Quantize(img image.Image, maxColors int, dither bool) *image.Paletted {
+	opts := EncodeOptions{Colors: maxColors, Dither: dither}.withDefaults()
+	rgba := toRGBA(img)
+	return quantizeFrame(rgba, hasTransparency(rgba), opts)
+}
+
+func quantizeFrame(img *image.RGBA, transparent bool, opts EncodeOptions) *image.Paletted {
+	maxColors := opts.Colors
+	if transparent {
+		maxColors--
+	}
+	pal := medianCut(img, maxColors)
+	if transparent {
+		pal = append(pal, color.RGBA{})
+	}
+	dst := image.NewPaletted(img.Bounds(), pal)
+	indexer := newPaletteIndexer(pal)
+	clearIdx := uint8(len(pal) - 1)
+	if opts.Dither {
+		ditherFrame(dst, img, transparent, indexer, clearIdx)
+		return dst
+	}
+
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		src := img.Pix[img.PixOffset(b.Min.X, y):img.PixOffset(b.Max.X, y)]
+		row := dst.Pix[dst.PixOffset(b.Min.X, y):dst.PixOffset(b.Max.X, y)]
+		for x := range row {
+			px := src[x*4 : x*4+4]
+			if transparent && px[3] < alphaCutoff {
+				row[x] = clearIdx
+				continue
+			}
+			r, g, bl := unpremultiply(px[0], px[1], px[2], px[3])
+			row[x] = indexer.index(r, g, bl)
+		}
+	}
+	return dst
+}
+
+// ditherFrame quantizes img into dst with Floyd-Steinberg error diffusion.
+// Transparent pixels map to clearIdx and take no part in the diffusion:
+// they neither pass on error nor keep the error diffused into them.
+func ditherFrame(dst *image.Paletted, img *image.RGBA, transparent bool, indexer *paletteIndexer, clearIdx uint8) {
+	b := img.Bounds()
+	// Errors are kept in sixteenths, offset by one so x-1 and x+1 stay in
+	// range at the row ends.
+	cur := make([][3]int32, b.Dx()+2)
+	next := make([][3]int32, b.Dx()+2)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		src := img.Pix[img.PixOffset(b.Min.X, y):img.PixOffset(b.Max.X, y)]
+		row := dst.Pix[dst.PixOffset(b.Min.X, y):dst.PixOffset(b.Max.X, y)]
+		for x := range row {
+			px := src[x*4 : x*4+4]
+			if transparent && px[3] < alphaCutoff {
+				row[x] = clearIdx
+				continue
+			}
+			r, g, bl := unpremultiply(px[0], px[1], px[2], px[3])
+			want := [3]int32{int32(r), int32(g), int32(bl)}
+			for c := range want {
+				want[c] = max(0, min(0xff, want[c]+cur[x+1][c]/16))
+			}
+			idx := indexer.index(uint8(want[0]), uint8(want[1]), uint8(want[2]))
+			row[x] = idx
+			pr, pg, pb, _ := dst.Palette[idx].RGBA()
+			got := [3]int32{int32(pr >> 8), int32(pg >> 8), int32(pb >> 8)}
+			for c := range want {
+				e := want[c] - got[c]
+				cur[x+2][c] += e * 7
+				next[x][c] += e * 3
+				next[x+1][c] += e * 5
+				next[x+2][c] += e
+			}
+		}
+		cur, next = next, cur
+		clear(next)
+	}
+}
+
+func hasTransparency(img *image.RGBA) bool {
+	for i := 3; i < len(img.Pix); i += 4 {
+		if img.Pix[i] < alphaCutoff {
+			return true
+		}
+	}
+	return false
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok {
+		return rgba
+	}
+	b := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+	return rgba
+}
+
+func delayCentis(delay time.Duration) int {
+	if delay <= 0 {
+		return 0
+	}
+	cs := int((delay + 5*time.Millisecond) / (10 * time.Millisecond))
+	if cs < 1 {
+		cs = 1
+	}
+	if cs > 0xffff {
+		cs = 0xffff
+	}
+	return cs
+}
@@ -0,0 +1,214 @@
+package gifdecode
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"testing"
+	"time"
+)
+
+func TestEncodeRoundTrip(t *testing.T) {
+	decoded, err := Decode(makeTestGIF(3), DefaultOptions())
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	data, err := Encode(decoded, EncodeOptions{LoopCount: 2})
+	if err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("gif decode failed: %v", err)
+	}
+	if len(g.Image) != 3 {
+		t.Fatalf("expected 3 frames, got %d", len(g.Image))
+	}
+	if g.LoopCount != 2 {
+		t.Fatalf("expected loop count 2, got %d", g.LoopCount)
+	}
+	if g.Config.Width != 2 || g.Config.Height != 2 {
+		t.Fatalf("unexpected size %dx%d", g.Config.Width, g.Config.Height)
+	}
+	if g.Delay[0] != 5 || g.Delay[1] != 7 || g.Delay[2] != 9 {
+		t.Fatalf("unexpected delays: %v", g.Delay)
+	}
+
+	again, err := Decode(data, DefaultOptions())
+	if err != nil {
+		t.Fatalf("re-decode failed: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(again.Frames[1].PNG))
+	if err != nil {
+		t.Fatalf("png decode failed: %v", err)
+	}
+	r, _, _, _ := img.At(1, 1).RGBA()
+	if r != 0xffff {
+		t.Fatalf("expected white pixel at (1,1), got %d", r)
+	}
+	if again.Frames[2].Delay != 90*time.Millisecond {
+		t.Fatalf("unexpected delay after round trip: %v", again.Frames[2].Delay)
+	}
+}
+
+func TestEncodeQuantizesManyColors(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 4), G: uint8(y * 4), B: uint8((x + y) * 2), A: 0xff})
+		}
+	}
+	frames := &Frames{Frames: []Frame{{PNG: mustPNG(t, img), Delay: 100 * time.Millisecond}}}
+
+	for _, dither := range []bool{false, true} {
+		data, err := Encode(frames, EncodeOptions{Colors: 32, Dither: dither})
+		if err != nil {
+			t.Fatalf("encode failed (dither=%v): %v", dither, err)
+		}
+		g, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("gif decode failed: %v", err)
+		}
+		if n := len(g.Image[0].Palette); n > 32 {
+			t.Fatalf("expected at most 32 colors, got %d", n)
+		}
+		r, gg, _, _ := g.Image[0].At(63, 63).RGBA()
+		if r>>8 < 200 || gg>>8 < 200 {
+			t.Fatalf("expected bright corner, got %d %d (dither=%v)", r>>8, gg>>8, dither)
+		}
+	}
+}
+
+func TestEncodeKeepsTransparency(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	img.Set(0, 0, color.RGBA{R: 0xff, A: 0xff})
+	frames := &Frames{
+		Frames: []Frame{{PNG: mustPNG(t, img), Delay: 50 * time.Millisecond}},
+		Width:  2,
+		Height: 2,
+	}
+	data, err := Encode(frames, EncodeOptions{})
+	if err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("gif decode failed: %v", err)
+	}
+	if g.Disposal[0] != gif.DisposalBackground {
+		t.Fatalf("expected background disposal, got %d", g.Disposal[0])
+	}
+	if _, _, _, a := g.Image[0].At(1, 1).RGBA(); a != 0 {
+		t.Fatalf("expected transparent pixel, got alpha %d", a)
+	}
+	if r, _, _, a := g.Image[0].At(0, 0).RGBA(); r != 0xffff || a != 0xffff {
+		t.Fatalf("expected opaque red pixel")
+	}
+}
+
+func TestEncodeErrors(t *testing.T) {
+	if _, err := Encode(nil, EncodeOptions{}); !errors.Is(err, ErrNoFrames) {
+		t.Fatalf("expected ErrNoFrames, got %v", err)
+	}
+	bad := &Frames{Frames: []Frame{{PNG: []byte("nope")}}}
+	if _, err := Encode(bad, EncodeOptions{}); !errors.Is(err, ErrBadFrame) {
+		t.Fatalf("expected ErrBadFrame, got %v", err)
+	}
+}
+
+func TestEncodeFixtureRoundTrip(t *testing.T) {
+	decoded, err := Decode(readFixture(t, "walk-cycle.gif"), DefaultOptions())
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	data, err := Encode(decoded, EncodeOptions{Dither: true})
+	if err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+	again, err := Decode(data, DefaultOptions())
+	if err != nil {
+		t.Fatalf("re-decode failed: %v", err)
+	}
+	if len(again.Frames) != len(decoded.Frames) {
+		t.Fatalf("expected %d frames, got %d", len(decoded.Frames), len(again.Frames))
+	}
+	if again.Width != decoded.Width || again.Height != decoded.Height {
+		t.Fatalf("size changed: %dx%d -> %dx%d", decoded.Width, decoded.Height, again.Width, again.Height)
+	}
+	if !hasTransparentPixel(again) {
+		t.Fatalf("expected transparency to survive re-encoding")
+	}
+}
+
+func TestDelayCentis(t *testing.T) {
+	cases := map[time.Duration]int{
+		0:                      0,
+		time.Millisecond:       1,
+		80 * time.Millisecond:  8,
+		84 * time.Millisecond:  8,
+		85 * time.Millisecond:  9,
+		time.Hour:              0xffff,
+		-10 * time.Millisecond: 0,
+	}
+	for in, want := range cases {
+		if got := delayCentis(in); got != want {
+			t.Fatalf("delayCentis(%v) = %d, want %d", in, got, want)
+		}
+	}
+}
+
+func mustPNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("png encode failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestQuantize(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 1))
+	img.Set(0, 0, color.RGBA{R: 0xff, A: 0xff})
+	img.Set(1, 0, color.RGBA{G: 0xff, A: 0xff})
+	img.Set(2, 0, color.RGBA{B: 0xff, A: 0xff})
+
+	pal := Quantize(img, 8, false)
+	if n := len(pal.Palette); n != 4 {
+		t.Fatalf("expected 3 colors plus transparency, got %d", n)
+	}
+	if _, _, _, a := pal.Palette[len(pal.Palette)-1].RGBA(); a != 0 {
+		t.Fatalf("expected transparent last entry")
+	}
+	if pal.ColorIndexAt(3, 0) != uint8(len(pal.Palette)-1) {
+		t.Fatalf("expected transparent pixel to use the last entry")
+	}
+	if r, _, _, _ := pal.At(0, 0).RGBA(); r != 0xffff {
+		t.Fatalf("expected red pixel, got %d", r)
+	}
+}
+
+func TestEncodeDitherKeepsTransparency(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 8; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 30), G: uint8(y * 15), B: 0x80, A: 0xff})
+		}
+	}
+	pal := Quantize(img, 4, true)
+	clearIdx := uint8(len(pal.Palette) - 1)
+	if _, _, _, a := pal.Palette[clearIdx].RGBA(); a != 0 {
+		t.Fatalf("expected the last palette entry transparent")
+	}
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			idx := pal.ColorIndexAt(x, y)
+			if (x >= 8) != (idx == clearIdx) {
+				t.Fatalf("pixel %d,%d: index %d, clear index %d", x, y, idx, clearIdx)
+			}
+		}
+	}
+}
@@ -6,4 +6,5 @@ var (
 	ErrTooLarge    = errors.New("gif exceeds limits")
 	ErrNoFrames    = errors.New("gif has no frames")
 	ErrInvalidSize = errors.New("gif has invalid size")
+	ErrBadFrame    = errors.New("gif frame has invalid image data")
 )
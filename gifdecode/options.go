@@ -1,6 +1,9 @@
 package gifdecode
 
-import "time"
+import (
+	"runtime"
+	"time"
+)
 
 const (
 	defaultMaxFrames = 60
@@ -14,6 +17,13 @@ const (
 	maxDelay     = 1 * time.Second
 )
 
+// Cell pixel defaults for TargetCells; roughly a HiDPI terminal cell, so the
+// terminal rarely has to scale frames back up.
+const (
+	defaultCellWidth  = 16
+	defaultCellHeight = 32
+)
+
 type Options struct {
 	MaxFrames    int
 	MaxPixels    int
@@ -22,6 +32,30 @@ type Options struct {
 	MinDelay     time.Duration
 	MaxDelay     time.Duration
 	StrictGIF    bool
+	// Recover keeps the frames decoded before a truncated or corrupt block
+	// instead of failing, and reports the problem in Frames.Warnings.
+	Recover bool
+
+	// MaxWidth/MaxHeight and TargetCells downscale frames while compositing.
+	// Frames are never upscaled; Frames.Width/Height report the output size.
+	MaxWidth    int
+	MaxHeight   int
+	TargetCells CellBox
+	CellWidth   int
+	CellHeight  int
+	Filter      Filter
+
+	// MergeDuplicates folds pixel-identical consecutive frames into one
+	// frame whose Delay is the sum; MaxFrames still counts source frames.
+	MergeDuplicates bool
+
+	// Deltas also encodes, for every frame after the first, just the
+	// rectangle that changed since the previous frame (Frame.Delta).
+	Deltas bool
+
+	// Workers bounds the goroutines that scale and PNG-encode frames;
+	// 0 uses GOMAXPROCS, 1 encodes on the calling goroutine.
+	Workers int
 }
 
 func (o Options) withDefaults() Options {
@@ -43,6 +77,15 @@ func (o Options) withDefaults() Options {
 	if o.MaxDelay == 0 {
 		o.MaxDelay = maxDelay
 	}
+	if o.Workers <= 0 {
+		o.Workers = runtime.GOMAXPROCS(0)
+	}
+	if o.CellWidth <= 0 {
+		o.CellWidth = defaultCellWidth
+	}
+	if o.CellHeight <= 0 {
+		o.CellHeight = defaultCellHeight
+	}
 	if o.MaxDelay < o.MinDelay {
 		o.MaxDelay = o.MinDelay
 	}
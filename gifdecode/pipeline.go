@@ -0,0 +1,180 @@
+package gifdecode
+
+import (
+	"image"
+	"sync"
+)
+
+// frameEncoder scales and PNG-encodes composited canvases. Compositing has to
+// stay sequential, so callers submit snapshots in frame order and the encoder
+// fans them out to a bounded worker pool, storing results by frame index.
+type frameEncoder struct {
+	filter Filter
+	outW   int
+	outH   int
+	width  int
+	height int
+	pngs   [][]byte
+	deltas []*Delta
+
+	// Sequential mode (one worker) encodes inline, reusing one buffer.
+	inline *frameScaler
+
+	jobs      chan frameJob
+	snapshots sync.Pool
+	wg        sync.WaitGroup
+	mu        sync.Mutex
+	err       error
+}
+
+type frameJob struct {
+	index int
+	img   *image.RGBA
+	delta image.Rectangle
+}
+
+type frameScaler struct {
+	scaled *image.RGBA
+}
+
+func newFrameEncoder(opts Options, width, height, outW, outH, count int) *frameEncoder {
+	e := &frameEncoder{
+		filter: opts.Filter,
+		outW:   outW,
+		outH:   outH,
+		width:  width,
+		height: height,
+		pngs:   make([][]byte, count),
+		deltas: make([]*Delta, count),
+	}
+	workers := opts.Workers
+	if workers > count {
+		workers = count
+	}
+	if workers <= 1 {
+		e.inline = &frameScaler{}
+		return e
+	}
+
+	bounds := image.Rect(0, 0, width, height)
+	e.snapshots.New = func() any { return image.NewRGBA(bounds) }
+	// The channel buffer plus one job per busy worker bounds the number of
+	// canvas snapshots alive at once.
+	e.jobs = make(chan frameJob, workers)
+	for w := 0; w < workers; w++ {
+		e.wg.Add(1)
+		go e.work()
+	}
+	return e
+}
+
+func (e *frameEncoder) work() {
+	defer e.wg.Done()
+	scaler := &frameScaler{}
+	for job := range e.jobs {
+		if e.failed() == nil {
+			e.encode(scaler, job.index, job.img, job.delta)
+		}
+		e.snapshots.Put(job.img)
+	}
+}
+
+// submit queues the canvas for frame index. delta, in canvas coordinates,
+// is the area that changed since the previous frame; when it is not empty a
+// cropped Delta is encoded too. The canvas is copied before submit returns,
+// so the caller may keep compositing into it.
+func (e *frameEncoder) submit(index int, canvas *image.RGBA, delta image.Rectangle) error {
+	if e.inline != nil {
+		e.encode(e.inline, index, canvas, delta)
+		return e.failed()
+	}
+	if err := e.failed(); err != nil {
+		return err
+	}
+	snap, ok := e.snapshots.Get().(*image.RGBA)
+	if !ok || snap == nil {
+		snap = image.NewRGBA(canvas.Bounds())
+	}
+	copy(snap.Pix, canvas.Pix)
+	e.jobs <- frameJob{index: index, img: snap, delta: delta}
+	return nil
+}
+
+// wait blocks until every submitted frame is encoded and returns the PNGs
+// and deltas in frame order.
+func (e *frameEncoder) wait() ([][]byte, []*Delta, error) {
+	if e.jobs != nil {
+		close(e.jobs)
+		e.wg.Wait()
+	}
+	if err := e.failed(); err != nil {
+		return nil, nil, err
+	}
+	return e.pngs, e.deltas, nil
+}
+
+func (e *frameEncoder) encode(s *frameScaler, index int, img *image.RGBA, delta image.Rectangle) {
+	out := s.scale(img, e.filter, e.outW, e.outH)
+	pngData, err := encodePNG(out)
+	var d *Delta
+	if err == nil && !delta.Empty() {
+		d, err = encodeDelta(out, e.outRect(delta))
+	}
+	e.store(index, pngData, d, err)
+}
+
+// outRect maps a canvas rectangle to output pixels, padded by the resampling
+// kernel's reach so every output pixel it influenced is included.
+func (e *frameEncoder) outRect(r image.Rectangle) image.Rectangle {
+	if e.outW == e.width && e.outH == e.height {
+		return r
+	}
+	const pad = 3
+	out := image.Rect(
+		r.Min.X*e.outW/e.width-pad,
+		r.Min.Y*e.outH/e.height-pad,
+		(r.Max.X*e.outW+e.width-1)/e.width+pad,
+		(r.Max.Y*e.outH+e.height-1)/e.height+pad,
+	)
+	return out.Intersect(image.Rect(0, 0, e.outW, e.outH))
+}
+
+func (e *frameEncoder) store(index int, pngData []byte, delta *Delta, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err != nil {
+		if e.err == nil {
+			e.err = err
+		}
+		return
+	}
+	e.pngs[index] = pngData
+	e.deltas[index] = delta
+}
+
+func (e *frameEncoder) failed() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.err
+}
+
+// scale returns img resized to outW x outH, reusing the scaler's buffer.
+func (s *frameScaler) scale(img *image.RGBA, filter Filter, outW, outH int) *image.RGBA {
+	b := img.Bounds()
+	if b.Dx() == outW && b.Dy() == outH {
+		return img
+	}
+	if s.scaled == nil {
+		s.scaled = image.NewRGBA(image.Rect(0, 0, outW, outH))
+	}
+	scaleInto(s.scaled, img, filter)
+	return s.scaled
+}
+
+func encodeDelta(img *image.RGBA, r image.Rectangle) (*Delta, error) {
+	pngData, err := encodePNG(img.SubImage(r))
+	if err != nil {
+		return nil, err
+	}
+	return &Delta{PNG: pngData, Bounds: r.Sub(img.Bounds().Min)}, nil
+}
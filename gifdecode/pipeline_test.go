@@ -0,0 +1,74 @@
+package gifdecode
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"testing"
+)
+
+func TestDecodeWorkersMatchSequential(t *testing.T) {
+	inputs := map[string][]byte{
+		"walk-cycle": readFixture(t, "walk-cycle.gif"),
+		"long":       makeLongGIF(24, 40, 30),
+	}
+	for name, data := range inputs {
+		seqOpts := DefaultOptions()
+		seqOpts.MaxFrames = 0
+		seqOpts.Workers = 1
+		seq, err := Decode(data, seqOpts)
+		if err != nil {
+			t.Fatalf("%s: sequential decode failed: %v", name, err)
+		}
+		for _, workers := range []int{2, 4, 64} {
+			opts := seqOpts
+			opts.Workers = workers
+			par, err := Decode(data, opts)
+			if err != nil {
+				t.Fatalf("%s: decode with %d workers failed: %v", name, workers, err)
+			}
+			if len(par.Frames) != len(seq.Frames) {
+				t.Fatalf("%s: expected %d frames, got %d", name, len(seq.Frames), len(par.Frames))
+			}
+			for i := range seq.Frames {
+				if !bytes.Equal(par.Frames[i].PNG, seq.Frames[i].PNG) || par.Frames[i].Delay != seq.Frames[i].Delay {
+					t.Fatalf("%s: frame %d differs with %d workers", name, i, workers)
+				}
+			}
+		}
+	}
+}
+
+func TestDecodeWorkersScaled(t *testing.T) {
+	data := makeLongGIF(12, 80, 60)
+	opts := DefaultOptions()
+	opts.MaxWidth = 20
+	opts.Workers = 1
+	seq, err := Decode(data, opts)
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	opts.Workers = 3
+	par, err := Decode(data, opts)
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	for i := range seq.Frames {
+		if !bytes.Equal(par.Frames[i].PNG, seq.Frames[i].PNG) {
+			t.Fatalf("frame %d differs", i)
+		}
+	}
+}
+
+func TestFrameEncoderStopsOnError(t *testing.T) {
+	enc := newFrameEncoder(Options{Workers: 2}, 2, 2, 2, 2, 4)
+	canvas := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	boom := errors.New("boom")
+	enc.store(0, nil, nil, boom)
+	if err := enc.submit(1, canvas, image.Rectangle{}); !errors.Is(err, boom) {
+		t.Fatalf("expected submit to report the first error, got %v", err)
+	}
+	if _, _, err := enc.wait(); !errors.Is(err, boom) {
+		t.Fatalf("expected wait to report the first error, got %v", err)
+	}
+}
@@ -0,0 +1,215 @@
+package gifdecode
+
+import (
+	"image"
+	"image/color"
+	"sort"
+)
+
+// Colors are bucketed to 5 bits per channel before the median cut; the
+// per-bucket sums keep the final palette entries exact averages.
+const (
+	quantBits    = 5
+	quantShift   = 8 - quantBits
+	quantBuckets = 1 << (3 * quantBits)
+	alphaCutoff  = 0x80
+)
+
+type colorBucket struct {
+	key   int
+	count int
+	r     int
+	g     int
+	b     int
+}
+
+type colorBox struct {
+	buckets []colorBucket
+	count   int
+}
+
+// medianCut builds a palette of at most maxColors opaque colors for img.
+// Pixels with alpha below alphaCutoff are ignored.
+func medianCut(img *image.RGBA, maxColors int) color.Palette {
+	if maxColors < 1 {
+		maxColors = 1
+	}
+	hist := make([]colorBucket, quantBuckets)
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row := img.Pix[img.PixOffset(b.Min.X, y):img.PixOffset(b.Max.X, y)]
+		for i := 0; i+3 < len(row); i += 4 {
+			if row[i+3] < alphaCutoff {
+				continue
+			}
+			r, g, bl := unpremultiply(row[i], row[i+1], row[i+2], row[i+3])
+			key := bucketKey(r, g, bl)
+			bucket := &hist[key]
+			bucket.key = key
+			bucket.count++
+			bucket.r += int(r)
+			bucket.g += int(g)
+			bucket.b += int(bl)
+		}
+	}
+
+	used := make([]colorBucket, 0, 256)
+	total := 0
+	for _, bucket := range hist {
+		if bucket.count > 0 {
+			used = append(used, bucket)
+			total += bucket.count
+		}
+	}
+	if len(used) == 0 {
+		return color.Palette{color.RGBA{A: 0xff}}
+	}
+
+	boxes := []colorBox{{buckets: used, count: total}}
+	for len(boxes) < maxColors {
+		idx := -1
+		best := 0
+		for i, box := range boxes {
+			if len(box.buckets) < 2 {
+				continue
+			}
+			_, span := box.widestChannel()
+			if score := span * box.count; score > best {
+				best = score
+				idx = i
+			}
+		}
+		if idx < 0 {
+			break
+		}
+		left, right := boxes[idx].split()
+		boxes[idx] = left
+		boxes = append(boxes, right)
+	}
+
+	pal := make(color.Palette, 0, len(boxes))
+	for _, box := range boxes {
+		pal = append(pal, box.average())
+	}
+	return pal
+}
+
+func (box colorBox) widestChannel() (channel int, span int) {
+	minC := [3]int{255, 255, 255}
+	maxC := [3]int{}
+	for _, bucket := range box.buckets {
+		c := bucketChannels(bucket.key)
+		for ch := 0; ch < 3; ch++ {
+			if c[ch] < minC[ch] {
+				minC[ch] = c[ch]
+			}
+			if c[ch] > maxC[ch] {
+				maxC[ch] = c[ch]
+			}
+		}
+	}
+	for ch := 0; ch < 3; ch++ {
+		if s := maxC[ch] - minC[ch]; s > span {
+			span = s
+			channel = ch
+		}
+	}
+	return channel, span
+}
+
+func (box colorBox) split() (colorBox, colorBox) {
+	channel, _ := box.widestChannel()
+	sort.Slice(box.buckets, func(i, j int) bool {
+		return bucketChannels(box.buckets[i].key)[channel] < bucketChannels(box.buckets[j].key)[channel]
+	})
+	half := box.count / 2
+	acc := 0
+	cut := 1
+	for i, bucket := range box.buckets {
+		acc += bucket.count
+		if acc >= half {
+			cut = i + 1
+			break
+		}
+	}
+	if cut >= len(box.buckets) {
+		cut = len(box.buckets) - 1
+	}
+	left := colorBox{buckets: box.buckets[:cut]}
+	right := colorBox{buckets: box.buckets[cut:]}
+	for _, bucket := range left.buckets {
+		left.count += bucket.count
+	}
+	right.count = box.count - left.count
+	return left, right
+}
+
+func (box colorBox) average() color.RGBA {
+	var r, g, b, n int
+	for _, bucket := range box.buckets {
+		r += bucket.r
+		g += bucket.g
+		b += bucket.b
+		n += bucket.count
+	}
+	if n == 0 {
+		return color.RGBA{A: 0xff}
+	}
+	return color.RGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(b / n), A: 0xff}
+}
+
+func bucketKey(r, g, b uint8) int {
+	return int(r>>quantShift)<<(2*quantBits) | int(g>>quantShift)<<quantBits | int(b>>quantShift)
+}
+
+func bucketChannels(key int) [3]int {
+	mask := (1 << quantBits) - 1
+	return [3]int{key >> (2 * quantBits) & mask, key >> quantBits & mask, key & mask}
+}
+
+func unpremultiply(r, g, b, a uint8) (uint8, uint8, uint8) {
+	if a == 0xff || a == 0 {
+		return r, g, b
+	}
+	return uint8(int(r) * 0xff / int(a)), uint8(int(g) * 0xff / int(a)), uint8(int(b) * 0xff / int(a))
+}
+
+// paletteIndexer maps colors to their nearest palette entry, caching the
+// answer per histogram bucket.
+type paletteIndexer struct {
+	pal   color.Palette
+	cache []int16
+}
+
+func newPaletteIndexer(pal color.Palette) *paletteIndexer {
+	cache := make([]int16, quantBuckets)
+	for i := range cache {
+		cache[i] = -1
+	}
+	return &paletteIndexer{pal: pal, cache: cache}
+}
+
+func (p *paletteIndexer) index(r, g, b uint8) uint8 {
+	key := bucketKey(r, g, b)
+	if idx := p.cache[key]; idx >= 0 {
+		return uint8(idx)
+	}
+	best := 0
+	bestDist := -1
+	for i, c := range p.pal {
+		pc, ok := c.(color.RGBA)
+		if !ok || pc.A == 0 {
+			continue
+		}
+		dr := int(pc.R) - int(r)
+		dg := int(pc.G) - int(g)
+		db := int(pc.B) - int(b)
+		dist := dr*dr + dg*dg + db*db
+		if bestDist < 0 || dist < bestDist {
+			best = i
+			bestDist = dist
+		}
+	}
+	p.cache[key] = int16(best)
+	return uint8(best)
+}
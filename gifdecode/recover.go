@@ -0,0 +1,102 @@
+package gifdecode
+
+import (
+	"bytes"
+	"image/gif"
+	"sort"
+)
+
+const (
+	gifExtension  = 0x21
+	gifImageBlock = 0x2C
+	gifTrailer    = 0x3B
+)
+
+// recoverGIF decodes the longest prefix of a broken GIF that still parses,
+// cutting after the last good frame. It returns nil when no frame survives.
+func recoverGIF(data []byte) *gif.GIF {
+	ends := frameEnds(data)
+	if len(ends) == 0 {
+		return nil
+	}
+	decodePrefix := func(end int) *gif.GIF {
+		buf := make([]byte, end+1)
+		copy(buf, data[:end])
+		buf[end] = gifTrailer
+		g, err := gif.DecodeAll(bytes.NewReader(buf))
+		if err != nil || len(g.Image) == 0 {
+			return nil
+		}
+		return g
+	}
+	// Prefixes stop decoding at the first bad frame, so the good ones form a
+	// leading run that can be binary searched.
+	n := sort.Search(len(ends), func(i int) bool {
+		return decodePrefix(ends[i]) == nil
+	})
+	if n == 0 {
+		return nil
+	}
+	return decodePrefix(ends[n-1])
+}
+
+// frameEnds walks the GIF block structure and returns the offset just past
+// each complete image block. Walking stops at the trailer or at the first
+// block that is truncated or unknown.
+func frameEnds(data []byte) []int {
+	const headerLen = 13
+	if len(data) < headerLen {
+		return nil
+	}
+	hdr := string(data[:6])
+	if hdr != "GIF87a" && hdr != "GIF89a" {
+		return nil
+	}
+	pos := headerLen
+	if flags := data[10]; flags&0x80 != 0 {
+		pos += 3 << ((flags & 0x07) + 1)
+	}
+
+	var ends []int
+	for pos < len(data) {
+		switch data[pos] {
+		case gifExtension:
+			next, ok := skipSubBlocks(data, pos+2)
+			if !ok {
+				return ends
+			}
+			pos = next
+		case gifImageBlock:
+			if pos+10 > len(data) {
+				return ends
+			}
+			flags := data[pos+9]
+			pos += 10
+			if flags&0x80 != 0 {
+				pos += 3 << ((flags & 0x07) + 1)
+			}
+			// Skip the LZW minimum code size byte.
+			next, ok := skipSubBlocks(data, pos+1)
+			if !ok {
+				return ends
+			}
+			pos = next
+			ends = append(ends, pos)
+		default:
+			return ends
+		}
+	}
+	return ends
+}
+
+func skipSubBlocks(data []byte, pos int) (int, bool) {
+	for pos < len(data) {
+		n := int(data[pos])
+		pos++
+		if n == 0 {
+			return pos, true
+		}
+		pos += n
+	}
+	return pos, false
+}
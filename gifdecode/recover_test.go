@@ -0,0 +1,107 @@
+package gifdecode
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDecodeRecoverTruncated(t *testing.T) {
+	data := readFixture(t, "walk-cycle.gif")
+	full, err := Decode(data, DefaultOptions())
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	ends := frameEnds(data)
+	if len(ends) < 3 {
+		t.Fatalf("expected several frames, got %d", len(ends))
+	}
+	// Cut in the middle of the third frame.
+	truncated := data[:ends[1]+(ends[2]-ends[1])/2]
+
+	opts := DefaultOptions()
+	opts.StrictGIF = true
+	if _, err := Decode(truncated, opts); err == nil {
+		t.Fatalf("expected error without recover")
+	}
+
+	opts.Recover = true
+	frames, err := Decode(truncated, opts)
+	if err != nil {
+		t.Fatalf("recover decode failed: %v", err)
+	}
+	if len(frames.Frames) != 2 {
+		t.Fatalf("expected 2 recovered frames, got %d", len(frames.Frames))
+	}
+	if frames.Width != full.Width || frames.Height != full.Height {
+		t.Fatalf("unexpected size %dx%d", frames.Width, frames.Height)
+	}
+	if len(frames.Warnings) != 1 || !strings.Contains(frames.Warnings[0], "after frame 2") {
+		t.Fatalf("unexpected warnings: %v", frames.Warnings)
+	}
+}
+
+func TestDecodeRecoverBadLateFrame(t *testing.T) {
+	data := append([]byte(nil), makeTestGIF(3)...)
+	ends := frameEnds(data)
+	if len(ends) != 3 {
+		t.Fatalf("expected 3 frame ends, got %d", len(ends))
+	}
+	// Break the LZW code size of the last frame.
+	pos := ends[1]
+	for data[pos] == gifExtension {
+		next, ok := skipSubBlocks(data, pos+2)
+		if !ok {
+			t.Fatalf("bad test gif")
+		}
+		pos = next
+	}
+	if data[pos] != gifImageBlock {
+		t.Fatalf("expected image block at %d", pos)
+	}
+	flags := data[pos+9]
+	pos += 10
+	if flags&0x80 != 0 {
+		pos += 3 << ((flags & 0x07) + 1)
+	}
+	data[pos] = 0x0f
+
+	if _, err := Decode(data, Options{StrictGIF: true}); err == nil {
+		t.Fatalf("expected error without recover")
+	}
+	frames, err := Decode(data, Options{StrictGIF: true, Recover: true})
+	if err != nil {
+		t.Fatalf("recover decode failed: %v", err)
+	}
+	if len(frames.Frames) != 2 || len(frames.Warnings) != 1 {
+		t.Fatalf("expected 2 frames and a warning, got %d frames, %v", len(frames.Frames), frames.Warnings)
+	}
+}
+
+func TestDecodeRecoverNothingLeft(t *testing.T) {
+	data := makeTestGIF(1)
+	ends := frameEnds(data)
+	if len(ends) != 1 {
+		t.Fatalf("expected 1 frame end, got %d", len(ends))
+	}
+	_, err := Decode(data[:ends[0]-2], Options{StrictGIF: true, Recover: true})
+	if err == nil {
+		t.Fatalf("expected error when no frame survives")
+	}
+	if errors.Is(err, ErrNoFrames) {
+		t.Fatalf("expected the original decode error, got %v", err)
+	}
+	if got := recoverGIF([]byte("nope")); got != nil {
+		t.Fatalf("expected nil for non-gif data")
+	}
+}
+
+func TestDecodeRecoverCleanGIFHasNoWarnings(t *testing.T) {
+	frames, err := Decode(makeTestGIF(2), Options{Recover: true})
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if len(frames.Warnings) != 0 {
+		t.Fatalf("unexpected warnings: %v", frames.Warnings)
+	}
+}
@@ -0,0 +1,225 @@
+package gifdecode
+
+import (
+	"image"
+	"math"
+)
+
+type Filter int
+
+const (
+	FilterCatmullRom Filter = iota
+	FilterBilinear
+	FilterNearest
+)
+
+func (f Filter) String() string {
+	switch f {
+	case FilterCatmullRom:
+		return "catmull-rom"
+	case FilterBilinear:
+		return "bilinear"
+	case FilterNearest:
+		return "nearest"
+	default:
+		return "catmull-rom"
+	}
+}
+
+// CellBox is a terminal cell rectangle; with a cell size it bounds the
+// pixel size of decoded frames.
+type CellBox struct {
+	Cols int
+	Rows int
+}
+
+// targetSize returns the output size for a source of w x h under the scaling
+// options. Frames are only ever scaled down, keeping their aspect ratio.
+func targetSize(w, h int, opts Options) (int, int) {
+	maxW := opts.MaxWidth
+	maxH := opts.MaxHeight
+	if opts.TargetCells.Cols > 0 {
+		if cw := opts.TargetCells.Cols * opts.CellWidth; maxW <= 0 || cw < maxW {
+			maxW = cw
+		}
+	}
+	if opts.TargetCells.Rows > 0 {
+		if ch := opts.TargetCells.Rows * opts.CellHeight; maxH <= 0 || ch < maxH {
+			maxH = ch
+		}
+	}
+	scale := 1.0
+	if maxW > 0 && w > maxW {
+		scale = float64(maxW) / float64(w)
+	}
+	if maxH > 0 && h > maxH {
+		scale = math.Min(scale, float64(maxH)/float64(h))
+	}
+	if scale >= 1 {
+		return w, h
+	}
+	tw := int(math.Round(float64(w) * scale))
+	th := int(math.Round(float64(h) * scale))
+	return maxInt(1, tw), maxInt(1, th)
+}
+
+type kernel struct {
+	support float64
+	at      func(float64) float64
+}
+
+var (
+	bilinearKernel   = kernel{support: 1, at: func(x float64) float64 { return 1 - x }}
+	catmullRomKernel = kernel{support: 2, at: func(x float64) float64 {
+		if x < 1 {
+			return (1.5*x-2.5)*x*x + 1
+		}
+		return ((-0.5*x+2.5)*x-4)*x + 2
+	}}
+)
+
+// scaleInto resamples src into dst, replacing every dst pixel.
+func scaleInto(dst, src *image.RGBA, f Filter) {
+	switch f {
+	case FilterNearest:
+		scaleNearest(dst, src)
+	case FilterBilinear:
+		scaleKernel(dst, src, bilinearKernel)
+	case FilterCatmullRom:
+		scaleKernel(dst, src, catmullRomKernel)
+	default:
+		scaleKernel(dst, src, catmullRomKernel)
+	}
+}
+
+func scaleNearest(dst, src *image.RGBA) {
+	sb, db := src.Bounds(), dst.Bounds()
+	sw, sh, dw, dh := sb.Dx(), sb.Dy(), db.Dx(), db.Dy()
+	for y := 0; y < dh; y++ {
+		sy := sb.Min.Y + (y*sh+sh/2)/dh
+		drow := dst.Pix[dst.PixOffset(db.Min.X, db.Min.Y+y):]
+		for x := 0; x < dw; x++ {
+			sx := sb.Min.X + (x*sw+sw/2)/dw
+			si := src.PixOffset(sx, sy)
+			copy(drow[x*4:x*4+4], src.Pix[si:si+4])
+		}
+	}
+}
+
+type contrib struct {
+	first   int
+	weights []float64
+}
+
+// contributions precomputes the kernel weights mapping n source samples onto
+// m destination samples.
+func contributions(n, m int, k kernel) []contrib {
+	ratio := float64(n) / float64(m)
+	filterScale := math.Max(1, ratio)
+	support := k.support * filterScale
+	out := make([]contrib, m)
+	for i := 0; i < m; i++ {
+		center := (float64(i)+0.5)*ratio - 0.5
+		first := int(math.Ceil(center - support))
+		last := int(math.Floor(center + support))
+		weights := make([]float64, 0, last-first+1)
+		sum := 0.0
+		for j := first; j <= last; j++ {
+			x := math.Abs(float64(j)-center) / filterScale
+			w := 0.0
+			if x < k.support {
+				w = k.at(x)
+			}
+			weights = append(weights, w)
+			sum += w
+		}
+		if sum != 0 {
+			for j := range weights {
+				weights[j] /= sum
+			}
+		}
+		out[i] = contrib{first: first, weights: weights}
+	}
+	return out
+}
+
+func scaleKernel(dst, src *image.RGBA, k kernel) {
+	sb, db := src.Bounds(), dst.Bounds()
+	sw, sh, dw, dh := sb.Dx(), sb.Dy(), db.Dx(), db.Dy()
+	if sw == 0 || sh == 0 || dw == 0 || dh == 0 {
+		return
+	}
+
+	// Horizontal pass into a float buffer, then vertical pass into dst.
+	// RGBA is premultiplied, so filtering the channels directly is correct.
+	xs := contributions(sw, dw, k)
+	tmp := make([]float64, dw*sh*4)
+	for y := 0; y < sh; y++ {
+		srow := src.Pix[src.PixOffset(sb.Min.X, sb.Min.Y+y):]
+		trow := tmp[y*dw*4:]
+		for x, c := range xs {
+			var r, g, b, a float64
+			for i, w := range c.weights {
+				sx := clampIndex(c.first+i, sw) * 4
+				r += w * float64(srow[sx])
+				g += w * float64(srow[sx+1])
+				b += w * float64(srow[sx+2])
+				a += w * float64(srow[sx+3])
+			}
+			trow[x*4] = r
+			trow[x*4+1] = g
+			trow[x*4+2] = b
+			trow[x*4+3] = a
+		}
+	}
+
+	ys := contributions(sh, dh, k)
+	for y, c := range ys {
+		drow := dst.Pix[dst.PixOffset(db.Min.X, db.Min.Y+y):]
+		for x := 0; x < dw; x++ {
+			var r, g, b, a float64
+			for i, w := range c.weights {
+				ti := (clampIndex(c.first+i, sh)*dw + x) * 4
+				r += w * tmp[ti]
+				g += w * tmp[ti+1]
+				b += w * tmp[ti+2]
+				a += w * tmp[ti+3]
+			}
+			alpha := clampChannel(a, 255)
+			drow[x*4] = clampChannel(r, alpha)
+			drow[x*4+1] = clampChannel(g, alpha)
+			drow[x*4+2] = clampChannel(b, alpha)
+			drow[x*4+3] = alpha
+		}
+	}
+}
+
+func clampIndex(i, n int) int {
+	if i < 0 {
+		return 0
+	}
+	if i >= n {
+		return n - 1
+	}
+	return i
+}
+
+// clampChannel rounds v into [0, limit]; premultiplied color channels must
+// not exceed alpha, which Catmull-Rom overshoot can otherwise cause.
+func clampChannel(v float64, limit uint8) uint8 {
+	if v <= 0 {
+		return 0
+	}
+	v = math.Round(v)
+	if v >= float64(limit) {
+		return limit
+	}
+	return uint8(v)
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,128 @@
+package gifdecode
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func TestTargetSize(t *testing.T) {
+	cases := []struct {
+		name  string
+		w, h  int
+		opts  Options
+		wantW int
+		wantH int
+	}{
+		{name: "no limits", w: 400, h: 200, opts: Options{}, wantW: 400, wantH: 200},
+		{name: "max width", w: 400, h: 200, opts: Options{MaxWidth: 100}, wantW: 100, wantH: 50},
+		{name: "max height", w: 400, h: 200, opts: Options{MaxHeight: 20}, wantW: 40, wantH: 20},
+		{name: "both limits", w: 400, h: 200, opts: Options{MaxWidth: 100, MaxHeight: 10}, wantW: 20, wantH: 10},
+		{name: "never upscale", w: 40, h: 20, opts: Options{MaxWidth: 100, MaxHeight: 100}, wantW: 40, wantH: 20},
+		{
+			name:  "cells",
+			w:     1000,
+			h:     1000,
+			opts:  Options{TargetCells: CellBox{Cols: 10, Rows: 5}, CellWidth: 10, CellHeight: 20},
+			wantW: 100,
+			wantH: 100,
+		},
+		{
+			name:  "cells and max width",
+			w:     1000,
+			h:     1000,
+			opts:  Options{MaxWidth: 50, TargetCells: CellBox{Cols: 10, Rows: 5}, CellWidth: 10, CellHeight: 20},
+			wantW: 50,
+			wantH: 50,
+		},
+		{name: "min size", w: 1000, h: 2, opts: Options{MaxWidth: 10}, wantW: 10, wantH: 1},
+	}
+	for _, tc := range cases {
+		gotW, gotH := targetSize(tc.w, tc.h, tc.opts)
+		if gotW != tc.wantW || gotH != tc.wantH {
+			t.Fatalf("%s: got %dx%d, want %dx%d", tc.name, gotW, gotH, tc.wantW, tc.wantH)
+		}
+	}
+}
+
+func TestDecodeScalesFrames(t *testing.T) {
+	data := readFixture(t, "walk-cycle.gif")
+	full, err := Decode(data, DefaultOptions())
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	for _, filter := range []Filter{FilterNearest, FilterBilinear, FilterCatmullRom} {
+		opts := DefaultOptions()
+		opts.MaxWidth = full.Width / 3
+		opts.Filter = filter
+		small, err := Decode(data, opts)
+		if err != nil {
+			t.Fatalf("decode (%s) failed: %v", filter, err)
+		}
+		if small.Width != opts.MaxWidth || small.Width >= full.Width || small.Height >= full.Height {
+			t.Fatalf("%s: unexpected size %dx%d (full %dx%d)", filter, small.Width, small.Height, full.Width, full.Height)
+		}
+		if len(small.Frames) != len(full.Frames) {
+			t.Fatalf("%s: expected %d frames, got %d", filter, len(full.Frames), len(small.Frames))
+		}
+		img, err := png.Decode(bytes.NewReader(small.Frames[0].PNG))
+		if err != nil {
+			t.Fatalf("png decode failed: %v", err)
+		}
+		if b := img.Bounds(); b.Dx() != small.Width || b.Dy() != small.Height {
+			t.Fatalf("%s: frame size %v does not match %dx%d", filter, b, small.Width, small.Height)
+		}
+		if len(small.Frames[0].PNG) >= len(full.Frames[0].PNG) {
+			t.Fatalf("%s: expected smaller frame payload", filter)
+		}
+	}
+}
+
+func TestDecodeScalesStill(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("png encode failed: %v", err)
+	}
+	opts := DefaultOptions()
+	opts.TargetCells = CellBox{Cols: 2, Rows: 2}
+	opts.CellWidth = 5
+	opts.CellHeight = 10
+	frames, err := Decode(buf.Bytes(), opts)
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if frames.Width != 10 || frames.Height != 5 {
+		t.Fatalf("unexpected size %dx%d", frames.Width, frames.Height)
+	}
+}
+
+func TestScaleKeepsSolidColors(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			c := color.RGBA{R: 0xff, A: 0xff}
+			if x >= 4 {
+				c = color.RGBA{}
+			}
+			src.SetRGBA(x, y, c)
+		}
+	}
+	for _, filter := range []Filter{FilterNearest, FilterBilinear, FilterCatmullRom} {
+		dst := image.NewRGBA(image.Rect(0, 0, 4, 4))
+		scaleInto(dst, src, filter)
+		if got := dst.RGBAAt(0, 0); got != (color.RGBA{R: 0xff, A: 0xff}) {
+			t.Fatalf("%s: expected opaque red, got %v", filter, got)
+		}
+		if got := dst.RGBAAt(3, 3); got.A != 0 {
+			t.Fatalf("%s: expected transparent pixel, got %v", filter, got)
+		}
+		for i := 0; i < len(dst.Pix); i += 4 {
+			if dst.Pix[i] > dst.Pix[i+3] {
+				t.Fatalf("%s: channel exceeds alpha at %d: %v", filter, i/4, dst.Pix[i:i+4])
+			}
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package gifdecode
+
+import (
+	"bytes"
+	"fmt"
+	"image/gif"
+	"time"
+)
+
+// Stats describes a GIF's frames without compositing or encoding them.
+type Stats struct {
+	Frames int
+	// Duration is one loop: the frame delays as Decode applies them.
+	Duration time.Duration
+	// Warnings lists problems that Options.Recover counted around.
+	Warnings []string
+}
+
+// Stat counts every frame of a GIF and sums their delays, using opts'
+// DefaultDelay, MinDelay and MaxDelay like Decode. MaxFrames and
+// MergeDuplicates don't apply. With opts.Recover, a truncated GIF reports
+// the frames before the damage.
+func Stat(data []byte, opts Options) (Stats, error) {
+	opts = opts.withDefaults()
+	var stats Stats
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		if !opts.Recover {
+			return Stats{}, err
+		}
+		if g = recoverGIF(data); g == nil {
+			return Stats{}, err
+		}
+		stats.Warnings = append(stats.Warnings, fmt.Sprintf("gif truncated or corrupt after frame %d: %v", len(g.Image), err))
+	}
+	if len(g.Image) == 0 {
+		return Stats{}, ErrNoFrames
+	}
+	stats.Frames = len(g.Image)
+	for i := range g.Image {
+		stats.Duration += frameDelay(g, i, opts)
+	}
+	return stats, nil
+}
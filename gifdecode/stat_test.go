@@ -0,0 +1,60 @@
+package gifdecode
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStatCountsEveryFrame(t *testing.T) {
+	opts := DefaultOptions()
+	opts.MergeDuplicates = true
+	stats, err := Stat(makeLongGIF(90, 8, 8), opts)
+	if err != nil {
+		t.Fatalf("stat failed: %v", err)
+	}
+	if stats.Frames != 90 || stats.Duration != 90*40*time.Millisecond || len(stats.Warnings) != 0 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	data := readFixture(t, "walk-cycle.gif")
+	full := DefaultOptions()
+	full.MaxFrames = -1
+	decoded, err := Decode(data, full)
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	stats, err = Stat(data, DefaultOptions())
+	if err != nil {
+		t.Fatalf("stat failed: %v", err)
+	}
+	var total time.Duration
+	for _, frame := range decoded.Frames {
+		total += frame.Delay
+	}
+	if stats.Frames != len(decoded.Frames) || stats.Duration != total {
+		t.Fatalf("expected %d frames in %v, got %+v", len(decoded.Frames), total, stats)
+	}
+}
+
+func TestStatRecover(t *testing.T) {
+	data := readFixture(t, "walk-cycle.gif")
+	ends := frameEnds(data)
+	truncated := data[:ends[1]+(ends[2]-ends[1])/2]
+
+	if _, err := Stat(truncated, DefaultOptions()); err == nil {
+		t.Fatalf("expected error without recover")
+	}
+	opts := DefaultOptions()
+	opts.Recover = true
+	stats, err := Stat(truncated, opts)
+	if err != nil {
+		t.Fatalf("recover stat failed: %v", err)
+	}
+	if stats.Frames != 2 || len(stats.Warnings) != 1 || !strings.Contains(stats.Warnings[0], "after frame 2") {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	if _, err := Stat([]byte("nope"), opts); err == nil {
+		t.Fatalf("expected error for garbage")
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/alecthomas/kong"
+	"github.com/steipete/gifgrep/internal/clipboard"
 	"github.com/steipete/gifgrep/internal/download"
 	"github.com/steipete/gifgrep/internal/model"
 	"github.com/steipete/gifgrep/internal/reveal"
@@ -57,8 +58,9 @@ type SearchCmd struct {
 	JSON     bool   `help:"Emit JSON array of results."`
 	Number   bool   `help:"Prefix lines with 1-based index." short:"n"`
 	Download bool   `help:"Download results to ~/Downloads."`
+	Copy     bool   `help:"Copy the first result's URL to the clipboard."`
 	Format   string `help:"Output format." enum:"auto,plain,tsv,md,url,comment,json" default:"auto"`
-	Thumbs   string `help:"Inline thumbnails (Kitty protocol / iTerm2 images; TTY only)." enum:"auto,always,never" default:"auto"`
+	Thumbs   string `help:"Inline thumbnails (Kitty protocol / iTerm2 images / sixel, else text unless GIFGREP_INLINE=none; always forces them; TTY only)." enum:"auto,always,never" default:"auto"`
 
 	Query []string `arg:"" name:"query" help:"Search query."`
 }
@@ -77,6 +79,7 @@ func (c *SearchCmd) Run(ctx *kong.Context, cli *CLI) error {
 	opts.Format = c.Format
 	opts.Thumbs = c.Thumbs
 	opts.Download = c.Download
+	opts.Copy = c.Copy
 	return runSearch(ctx.Stdout, ctx.Stderr, opts, query)
 }
 
@@ -102,14 +105,14 @@ type StillCmd struct {
 	Output string        `help:"Output path or '-' for stdout." name:"output" short:"o" default:"still.png"`
 }
 
-func (c *StillCmd) Run(_ *kong.Context, cli *CLI) error {
+func (c *StillCmd) Run(ctx *kong.Context, cli *CLI) error {
 	opts := cli.Globals.toOptions()
 	opts.GifInput = c.GIF
 	opts.StillSet = true
 	opts.StillAt = time.Duration(c.At)
 	opts.OutPath = c.Output
 	opts.StillsCount = 0
-	if err := runExtract(opts); err != nil {
+	if err := runExtract(ctx.Stderr, opts); err != nil {
 		return err
 	}
 	if opts.Reveal {
@@ -129,7 +132,7 @@ type SheetCmd struct {
 	Output  string `help:"Output path or '-' for stdout." name:"output" short:"o" default:"sheet.png"`
 }
 
-func (c *SheetCmd) Run(_ *kong.Context, cli *CLI) error {
+func (c *SheetCmd) Run(ctx *kong.Context, cli *CLI) error {
 	opts := cli.Globals.toOptions()
 	opts.GifInput = c.GIF
 	opts.StillSet = false
@@ -137,7 +140,7 @@ func (c *SheetCmd) Run(_ *kong.Context, cli *CLI) error {
 	opts.StillsCols = c.Cols
 	opts.StillsPadding = c.Padding
 	opts.OutPath = c.Output
-	if err := runExtract(opts); err != nil {
+	if err := runExtract(ctx.Stderr, opts); err != nil {
 		return err
 	}
 	if opts.Reveal {
@@ -163,6 +166,9 @@ func runSearch(stdout io.Writer, stderr io.Writer, opts model.Options, query str
 	if err := downloadSearchResults(results, opts, stderr); err != nil {
 		return err
 	}
+	if err := copySearchResult(results, opts, stderr); err != nil {
+		return err
+	}
 
 	format := resolveOutputFormat(opts, stdout)
 	out := bufio.NewWriter(stdout)
@@ -212,6 +218,43 @@ func downloadSearchResults(results []model.Result, opts model.Options, stderr io
 	return nil
 }
 
+var (
+	copyClipboard   = clipboard.Copy
+	detectClipboard = termcaps.DetectClipboard
+)
+
+// copySearchResult puts the first result's URL on the clipboard. When
+// stderr is a terminal that supports it, an OSC 52 write asks the terminal
+// (which also works over SSH); otherwise the platform tool copies it.
+func copySearchResult(results []model.Result, opts model.Options, stderr io.Writer) error {
+	if !opts.Copy {
+		return nil
+	}
+	url := ""
+	for _, res := range results {
+		if res.URL != "" {
+			url = res.URL
+			break
+		}
+	}
+	if url == "" {
+		return errors.New("no result to copy")
+	}
+	var err error
+	if isTerminalWriter(stderr) && detectClipboard(os.Getenv) {
+		_, err = io.WriteString(stderr, clipboard.OSC52(url))
+	} else {
+		err = copyClipboard(url)
+	}
+	if err != nil {
+		return fmt.Errorf("copy: %w", err)
+	}
+	if opts.Verbose > 0 && !opts.Quiet {
+		_, _ = fmt.Fprintf(stderr, "copied %s\n", url)
+	}
+	return nil
+}
+
 func termColumns(w io.Writer, thumbs termcaps.InlineProtocol) int {
 	if thumbs == termcaps.InlineNone {
 		return 0
@@ -2,6 +2,7 @@ package app
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"os"
 	"strings"
@@ -43,6 +44,49 @@ func TestRunSearchJSON(t *testing.T) {
 	})
 }
 
+func TestRunSearchCopy(t *testing.T) {
+	prevCopy, prevTerm, prevDetect := copyClipboard, isTerminalWriter, detectClipboard
+	t.Cleanup(func() { copyClipboard, isTerminalWriter, detectClipboard = prevCopy, prevTerm, prevDetect })
+	var copied string
+	copyClipboard = func(text string) error {
+		copied = text
+		return nil
+	}
+
+	gifData := testutil.MakeTestGIF()
+	testutil.WithTransport(t, &testutil.FakeTransport{GIFData: gifData}, func() {
+		var stdout, stderr bytes.Buffer
+		err := runSearch(&stdout, &stderr, model.Options{Copy: true, Limit: 1, Source: "tenor"}, "cats")
+		if err != nil {
+			t.Fatalf("runSearch failed: %v", err)
+		}
+		if copied == "" || !strings.Contains(stdout.String(), copied) {
+			t.Fatalf("expected first URL copied, got %q", copied)
+		}
+
+		// A terminal with OSC 52 gets the URL first; the tool isn't run.
+		copied = ""
+		isTerminalWriter = func(io.Writer) bool { return true }
+		detectClipboard = func(func(string) string) bool { return true }
+		stdout.Reset()
+		err = runSearch(&stdout, &stderr, model.Options{Copy: true, Limit: 1, Source: "tenor"}, "cats")
+		if err != nil {
+			t.Fatalf("runSearch failed: %v", err)
+		}
+		if !strings.HasPrefix(stderr.String(), "\x1b]52;c;") || copied != "" {
+			t.Fatalf("expected OSC 52 on stderr only, got %q (tool copied %q)", stderr.String(), copied)
+		}
+
+		copyClipboard = func(string) error { return errors.New("no clipboard tool found") }
+
+		detectClipboard = func(func(string) string) bool { return false }
+		err = runSearch(&stdout, &stderr, model.Options{Copy: true, Limit: 1, Source: "tenor"}, "cats")
+		if err == nil || !strings.Contains(err.Error(), "no clipboard tool") {
+			t.Fatalf("expected copy error, got %v", err)
+		}
+	})
+}
+
 func TestHelpOutput(t *testing.T) {
 	oldStdout := os.Stdout
 	r, w, _ := os.Pipe()
@@ -15,7 +15,7 @@ import (
 	"github.com/steipete/gifgrep/internal/stills"
 )
 
-func runExtract(opts model.Options) error {
+func runExtract(stderr io.Writer, opts model.Options) error {
 	if opts.GifInput == "" {
 		return errors.New("missing GIF input")
 	}
@@ -41,10 +41,16 @@ func runExtract(opts model.Options) error {
 	}
 	decodeOpts := gifdecode.DefaultOptions()
 	decodeOpts.MaxFrames = 0
+	decodeOpts.Recover = true
 	decoded, err := gifdecode.Decode(data, decodeOpts)
 	if err != nil {
 		return err
 	}
+	if !opts.Quiet {
+		for _, warning := range decoded.Warnings {
+			_, _ = fmt.Fprintf(stderr, "warning: %s\n", warning)
+		}
+	}
 
 	var output []byte
 	if opts.StillSet {
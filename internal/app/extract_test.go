@@ -3,8 +3,10 @@ package app
 import (
 	"bytes"
 	"image/png"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -64,7 +66,7 @@ func TestRunExtractContactSheet(t *testing.T) {
 		StillsPadding: 1,
 		OutPath:       outPath,
 	}
-	if err := runExtract(opts); err != nil {
+	if err := runExtract(io.Discard, opts); err != nil {
 		t.Fatalf("runExtract failed: %v", err)
 	}
 	out, err := os.ReadFile(outPath)
@@ -94,7 +96,7 @@ func TestRunExtractStill(t *testing.T) {
 		StillAt:  60 * time.Millisecond,
 		OutPath:  outPath,
 	}
-	if err := runExtract(opts); err != nil {
+	if err := runExtract(io.Discard, opts); err != nil {
 		t.Fatalf("runExtract failed: %v", err)
 	}
 	out, err := os.ReadFile(outPath)
@@ -105,3 +107,39 @@ func TestRunExtractStill(t *testing.T) {
 		t.Fatalf("expected png output")
 	}
 }
+
+func TestRunExtractWarnsOnTruncatedGIF(t *testing.T) {
+	data := testutil.MakeTestGIF()
+	// Drop the trailer, the block terminator and the last LZW byte.
+	data = data[:len(data)-3]
+	inPath := filepath.Join(t.TempDir(), "in.gif")
+	if err := os.WriteFile(inPath, data, 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+	outPath := filepath.Join(t.TempDir(), "still.png")
+
+	opts := model.Options{
+		GifInput: inPath,
+		StillSet: true,
+		OutPath:  outPath,
+	}
+	var stderr bytes.Buffer
+	if err := runExtract(&stderr, opts); err != nil {
+		t.Fatalf("runExtract failed: %v", err)
+	}
+	if !strings.Contains(stderr.String(), "warning: gif truncated or corrupt after frame 1") {
+		t.Fatalf("expected warning, got %q", stderr.String())
+	}
+	if _, err := os.Stat(outPath); err != nil {
+		t.Fatalf("expected output: %v", err)
+	}
+
+	stderr.Reset()
+	opts.Quiet = true
+	if err := runExtract(&stderr, opts); err != nil {
+		t.Fatalf("runExtract failed: %v", err)
+	}
+	if stderr.Len() != 0 {
+		t.Fatalf("expected no warning when quiet, got %q", stderr.String())
+	}
+}
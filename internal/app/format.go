@@ -9,9 +9,11 @@ import (
 	"strings"
 
 	"github.com/steipete/gifgrep/gifdecode"
+	"github.com/steipete/gifgrep/internal/blocks"
 	"github.com/steipete/gifgrep/internal/iterm"
 	"github.com/steipete/gifgrep/internal/kitty"
 	"github.com/steipete/gifgrep/internal/model"
+	"github.com/steipete/gifgrep/internal/sixel"
 	"github.com/steipete/gifgrep/internal/termcaps"
 	"golang.org/x/term"
 )
@@ -46,13 +48,57 @@ var isTerminalWriter = func(w io.Writer) bool {
 
 var (
 	fetchThumb  = fetchURL
-	decodeThumb = func(data []byte) (*gifdecode.Frames, error) {
-		decodeOpts := gifdecode.DefaultOptions()
-		decodeOpts.MaxFrames = 1
-		return gifdecode.Decode(data, decodeOpts)
+	decodeThumb = func(data []byte, opts gifdecode.Options) (*gifdecode.Frames, error) {
+		return gifdecode.Decode(data, opts)
 	}
-	sendThumbKitty = func(out *bufio.Writer, id uint32, frame gifdecode.Frame, cols, rows int) {
-		kitty.SendFrame(out, id, frame, cols, rows)
+	sendThumbKitty = func(out *bufio.Writer, mode kitty.Mode, id uint32, frame gifdecode.Frame, cols, rows int) {
+		if !mode.Unicode {
+			mode.SendFrame(out, id, frame, cols, rows)
+			return
+		}
+		// Placeholder cells are text: reserve the block so it scrolls into
+		// view, write them from its top-left, then return to its first row.
+		_, _ = fmt.Fprint(out, "\r"+strings.Repeat("\n", rows))
+		_, _ = fmt.Fprintf(out, "\x1b[%dA", rows)
+		mode.SendFrame(out, id, frame, cols, rows)
+		if rows > 1 {
+			_, _ = fmt.Fprintf(out, "\x1b[%dA", rows-1)
+		}
+		_, _ = fmt.Fprint(out, "\r")
+	}
+	sendThumbSixel = func(out *bufio.Writer, frame gifdecode.Frame, rows int) error {
+		img, err := sixel.EncodePNG(frame.PNG, sixel.Options{})
+		if err != nil {
+			return err
+		}
+		// Reserve the block first so a sixel at the bottom of the screen
+		// scrolls the text, not the image, then draw from its top-left.
+		_, _ = fmt.Fprint(out, "\r"+strings.Repeat("\n", rows))
+		_, _ = fmt.Fprintf(out, "\x1b[%dA", rows)
+		_, _ = fmt.Fprint(out, "\x1b7")
+		_, _ = out.Write(img.Data)
+		_, _ = fmt.Fprint(out, "\x1b8")
+		return nil
+	}
+	sendThumbText = func(out *bufio.Writer, frame gifdecode.Frame, cols, rows int, depth termcaps.ColorDepth) error {
+		lines, err := blocks.RenderPNG(frame.PNG, cols, rows, depth)
+		if err != nil {
+			return err
+		}
+		// Draw the block, then return to its first row so the title and URL
+		// can be written beside it.
+		_, _ = fmt.Fprint(out, "\r")
+		for i, line := range lines {
+			if i > 0 {
+				_, _ = fmt.Fprint(out, "\r\n")
+			}
+			_, _ = fmt.Fprint(out, line)
+		}
+		if len(lines) > 1 {
+			_, _ = fmt.Fprintf(out, "\x1b[%dA", len(lines)-1)
+		}
+		_, _ = fmt.Fprint(out, "\r")
+		return nil
 	}
 	sendThumbIterm = func(out *bufio.Writer, data []byte, cols, rows int) {
 		iterm.SendInlineFile(out, iterm.File{
@@ -94,15 +140,21 @@ func thumbsProtocol(opts model.Options, stdout io.Writer, format outputFormat) t
 	if !isTerminalWriter(stdout) {
 		return termcaps.InlineNone
 	}
-	switch resolveThumbsMode(opts) {
-	case thumbsNever:
+	mode := resolveThumbsMode(opts)
+	if mode == thumbsNever {
+		return termcaps.InlineNone
+	}
+	if p := termcaps.DetectInlineRobust(os.Getenv); p != termcaps.InlineNone {
+		return p
+	}
+	// Without a graphics protocol, fall back to text thumbnails rather than
+	// silently printing none. In auto mode GIFGREP_INLINE=none still turns
+	// them off; always forces them.
+	forced := strings.ToLower(strings.TrimSpace(os.Getenv("GIFGREP_INLINE")))
+	if p, ok := termcaps.ParseInline(forced); ok && p == termcaps.InlineNone && mode != thumbsAlways {
 		return termcaps.InlineNone
-	case thumbsAlways:
-		return termcaps.DetectInlineRobust(os.Getenv)
-	case thumbsAuto:
-		return termcaps.DetectInlineRobust(os.Getenv)
 	}
-	return termcaps.DetectInlineRobust(os.Getenv)
+	return termcaps.InlineText
 }
 
 func renderPlain(
@@ -115,6 +167,11 @@ func renderPlain(
 ) {
 	nextID := uint32(1)
 	withThumbs := thumbs != termcaps.InlineNone
+	kittyMode := kitty.DetectMode(os.Getenv)
+	var cell termcaps.CellSize
+	if withThumbs {
+		cell = termcaps.DetectCellSize(int(os.Stdout.Fd()))
+	}
 	for i, res := range results {
 		title := normalizeTitle(res)
 		url := res.URL
@@ -124,7 +181,7 @@ func renderPlain(
 			nPrefix = fmt.Sprintf("%d. ", i+1)
 		}
 
-		if withThumbs && renderThumbBlock(out, thumbs, nextID, res, nPrefix, title, url, useColor, termCols) == nil {
+		if withThumbs && renderThumbBlock(out, thumbs, kittyMode, cell, nextID, res, nPrefix, title, url, useColor, termCols) == nil {
 			nextID++
 			if i < len(results)-1 {
 				if thumbs == termcaps.InlineIterm {
@@ -148,25 +205,25 @@ func renderPlain(
 	}
 }
 
-func renderThumbBlock(out *bufio.Writer, thumbs termcaps.InlineProtocol, id uint32, res model.Result, nPrefix, title, url string, useColor bool, termCols int) error {
+func renderThumbBlock(out *bufio.Writer, thumbs termcaps.InlineProtocol, kittyMode kitty.Mode, cell termcaps.CellSize, id uint32, res model.Result, nPrefix, title, url string, useColor bool, termCols int) error {
 	data, src, err := fetchThumbForResult(res)
 	if err != nil {
 		return err
 	}
-	cols, rows := thumbBlockSize(thumbs, data, res)
+	cols, rows := thumbBlockSize(thumbs, data, res, cell)
 
 	data, err = prepareThumbData(thumbs, data, src, res)
 	if err != nil {
 		return err
 	}
-	if err := sendThumb(out, thumbs, id, data, cols, rows); err != nil {
+	if err := sendThumb(out, thumbs, kittyMode, cell, id, data, cols, rows, thumbColorDepth(useColor, os.Getenv)); err != nil {
 		return err
 	}
 
 	indentCols := thumbIndentCols(thumbs, cols)
 	textWidth := thumbTextWidth(termCols, indentCols)
 	titleLine, urlLines := thumbTextLines(nPrefix, title, url, rows, textWidth)
-	writeThumbTextBlock(out, thumbs, rows, indentCols, titleLine, urlLines, useColor)
+	writeThumbTextBlock(out, thumbsInTextGrid(thumbs, kittyMode), rows, indentCols, titleLine, urlLines, useColor)
 	return nil
 }
 
@@ -263,12 +320,14 @@ func fetchThumbForResult(res model.Result) ([]byte, string, error) {
 	return data, src, nil
 }
 
-func thumbBlockSize(thumbs termcaps.InlineProtocol, data []byte, res model.Result) (int, int) {
+// thumbBlockSize returns the cell block for a thumbnail; the row count keeps
+// the image's aspect ratio for the given cell size.
+func thumbBlockSize(thumbs termcaps.InlineProtocol, data []byte, res model.Result, cell termcaps.CellSize) (int, int) {
 	cols := 16
 	rows := 8
 	if w, h := thumbDims(data, res); w > 0 && h > 0 {
 		if thumbs != termcaps.InlineIterm {
-			rows = clampInt(3, 10, int(float64(cols)*0.5*float64(h)/float64(w)))
+			rows = clampInt(3, 10, int(float64(cols)*cell.Aspect()*float64(h)/float64(w)))
 		}
 	}
 	return cols, rows
@@ -291,7 +350,7 @@ func prepareThumbData(thumbs termcaps.InlineProtocol, data []byte, src string, r
 			return nil, fmt.Errorf("unsupported image")
 		}
 		return data, nil
-	case termcaps.InlineKitty:
+	case termcaps.InlineKitty, termcaps.InlineSixel, termcaps.InlineText:
 		if len(data) == 0 {
 			return nil, fmt.Errorf("empty image")
 		}
@@ -301,7 +360,19 @@ func prepareThumbData(thumbs termcaps.InlineProtocol, data []byte, src string, r
 	}
 }
 
-func sendThumb(out *bufio.Writer, thumbs termcaps.InlineProtocol, id uint32, data []byte, cols, rows int) error {
+// thumbColorDepth picks the palette for text thumbnails. Without color the
+// thumbnail is drawn as ASCII art.
+func thumbColorDepth(useColor bool, getenv func(string) string) termcaps.ColorDepth {
+	if !useColor {
+		return termcaps.ColorNone
+	}
+	if depth := termcaps.DetectColorDepth(getenv); depth != termcaps.ColorNone {
+		return depth
+	}
+	return termcaps.Color256
+}
+
+func sendThumb(out *bufio.Writer, thumbs termcaps.InlineProtocol, kittyMode kitty.Mode, cell termcaps.CellSize, id uint32, data []byte, cols, rows int, depth termcaps.ColorDepth) error {
 	switch thumbs {
 	case termcaps.InlineNone:
 		return fmt.Errorf("inline thumbnails not supported")
@@ -312,21 +383,53 @@ func sendThumb(out *bufio.Writer, thumbs termcaps.InlineProtocol, id uint32, dat
 			_, _ = fmt.Fprintf(out, "\x1b[%dA", rows-1)
 		}
 		return nil
-	case termcaps.InlineKitty:
-		decoded, err := decodeThumb(data)
+	case termcaps.InlineKitty, termcaps.InlineSixel, termcaps.InlineText:
+		decoded, err := decodeThumb(data, thumbDecodeOptions(thumbs, cols, rows, cell))
 		if err != nil {
 			return err
 		}
 		if decoded == nil || len(decoded.Frames) == 0 {
 			return fmt.Errorf("no frames")
 		}
-		sendThumbKitty(out, id, decoded.Frames[0], cols, rows)
+		switch thumbs {
+		case termcaps.InlineSixel:
+			return sendThumbSixel(out, decoded.Frames[0], rows)
+		case termcaps.InlineText:
+			return sendThumbText(out, decoded.Frames[0], cols, rows, depth)
+		case termcaps.InlineNone, termcaps.InlineKitty, termcaps.InlineIterm:
+		}
+		sendThumbKitty(out, kittyMode, id, decoded.Frames[0], cols, rows)
 		return nil
 	default:
 		return fmt.Errorf("inline thumbnails not supported")
 	}
 }
 
+// thumbDecodeOptions decodes the first frame only, sized to the thumb block
+// at the measured cell size when known.
+func thumbDecodeOptions(thumbs termcaps.InlineProtocol, cols, rows int, cell termcaps.CellSize) gifdecode.Options {
+	opts := gifdecode.DefaultOptions()
+	opts.MaxFrames = 1
+	opts.TargetCells = gifdecode.CellBox{Cols: cols, Rows: rows}
+	if cell.Valid() {
+		opts.CellWidth = cell.Width
+		opts.CellHeight = cell.Height
+	}
+	switch thumbs {
+	case termcaps.InlineSixel:
+		if !cell.Valid() {
+			opts.CellWidth = termcaps.DefaultCellWidth
+			opts.CellHeight = termcaps.DefaultCellHeight
+		}
+	case termcaps.InlineText:
+		// Half-blocks need two pixels per row; anything more is averaged away.
+		opts.CellWidth = 2
+		opts.CellHeight = 4
+	case termcaps.InlineNone, termcaps.InlineKitty, termcaps.InlineIterm:
+	}
+	return opts
+}
+
 func thumbIndentCols(thumbs termcaps.InlineProtocol, cols int) int {
 	if thumbs == termcaps.InlineIterm {
 		return cols
@@ -356,7 +459,23 @@ func thumbTextLines(nPrefix, title, url string, rows int, textWidth int) (string
 	return titleLine, urlLines
 }
 
-func writeThumbTextBlock(out *bufio.Writer, thumbs termcaps.InlineProtocol, rows, indentCols int, titleLine string, urlLines []string, useColor bool) {
+// thumbsInTextGrid reports whether the thumbnail occupies text cells and
+// leaves the cursor on its first row, so the text beside it has to be
+// positioned by column instead of padded with spaces.
+func thumbsInTextGrid(thumbs termcaps.InlineProtocol, kittyMode kitty.Mode) bool {
+	switch thumbs {
+	case termcaps.InlineIterm, termcaps.InlineSixel, termcaps.InlineText:
+		return true
+	case termcaps.InlineKitty:
+		return kittyMode.Unicode
+	case termcaps.InlineNone:
+		return false
+	default:
+		return false
+	}
+}
+
+func writeThumbTextBlock(out *bufio.Writer, inGrid bool, rows, indentCols int, titleLine string, urlLines []string, useColor bool) {
 	for r := 0; r < rows; r++ {
 		line := ""
 		switch r {
@@ -374,7 +493,7 @@ func writeThumbTextBlock(out *bufio.Writer, thumbs termcaps.InlineProtocol, rows
 			}
 		}
 
-		if thumbs == termcaps.InlineIterm {
+		if inGrid {
 			col := indentCols + 1
 			if col < 1 {
 				col = 1
@@ -3,14 +3,20 @@ package app
 import (
 	"bufio"
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"image"
+	"image/png"
 	"io"
+	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"testing"
 
 	"github.com/steipete/gifgrep/gifdecode"
+	"github.com/steipete/gifgrep/internal/kitty"
 	"github.com/steipete/gifgrep/internal/model"
 	"github.com/steipete/gifgrep/internal/termcaps"
 )
@@ -48,6 +54,40 @@ func TestResolveOutputFormatAutoNonTTY(t *testing.T) {
 	}
 }
 
+func TestThumbsProtocolFallsBackToText(t *testing.T) {
+	prev := isTerminalWriter
+	isTerminalWriter = func(_ io.Writer) bool { return true }
+	t.Cleanup(func() { isTerminalWriter = prev })
+	// A capability document without a graphics protocol skips the probes.
+	data, err := json.Marshal(termcaps.Caps{Version: termcaps.CapsVersion, Inline: termcaps.InlineNone.String()})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "caps.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	t.Setenv("GIFGREP_CAPS", path)
+	t.Setenv("GIFGREP_INLINE", "")
+
+	for mode, want := range map[string]termcaps.InlineProtocol{
+		"auto":   termcaps.InlineText,
+		"always": termcaps.InlineText,
+		"never":  termcaps.InlineNone,
+	} {
+		if got := thumbsProtocol(model.Options{Thumbs: mode}, &bytes.Buffer{}, formatPlain); got != want {
+			t.Fatalf("--thumbs %s: got %v want %v", mode, got, want)
+		}
+	}
+	t.Setenv("GIFGREP_INLINE", "none")
+	if got := thumbsProtocol(model.Options{}, &bytes.Buffer{}, formatPlain); got != termcaps.InlineNone {
+		t.Fatalf("expected GIFGREP_INLINE=none to turn thumbnails off, got %v", got)
+	}
+	if got := thumbsProtocol(model.Options{Thumbs: "always"}, &bytes.Buffer{}, formatPlain); got != termcaps.InlineText {
+		t.Fatalf("expected --thumbs always to force text thumbnails, got %v", got)
+	}
+}
+
 func TestRenderPlainNoThumbs(t *testing.T) {
 	var buf bytes.Buffer
 	out := bufio.NewWriter(&buf)
@@ -77,12 +117,15 @@ func TestRenderPlainThumbsIncludesBlockGap(t *testing.T) {
 	})
 
 	fetchThumb = func(_ string) ([]byte, error) { return []byte("gif"), nil }
-	decodeThumb = func(_ []byte) (*gifdecode.Frames, error) {
+	decodeThumb = func(_ []byte, _ gifdecode.Options) (*gifdecode.Frames, error) {
 		return &gifdecode.Frames{Frames: []gifdecode.Frame{{PNG: []byte{1}}}}, nil
 	}
-	sendThumbKitty = func(out *bufio.Writer, id uint32, _ gifdecode.Frame, _, _ int) {
+	sendThumbKitty = func(out *bufio.Writer, _ kitty.Mode, id uint32, _ gifdecode.Frame, _, _ int) {
 		_, _ = fmt.Fprintf(out, "<IMG%d>", id)
 	}
+	t.Setenv("TMUX", "")
+	t.Setenv("STY", "")
+	t.Setenv("GIFGREP_KITTY_PLACEHOLDERS", "")
 
 	var buf bytes.Buffer
 	out := bufio.NewWriter(&buf)
@@ -105,6 +148,40 @@ func TestRenderPlainThumbsIncludesBlockGap(t *testing.T) {
 	}
 }
 
+func TestRenderPlainThumbsKittyPlaceholdersInTmux(t *testing.T) {
+	prevFetch := fetchThumb
+	prevDecode := decodeThumb
+	t.Cleanup(func() {
+		fetchThumb = prevFetch
+		decodeThumb = prevDecode
+	})
+	t.Setenv("TMUX", "/tmp/tmux-1000/default,1,0")
+	t.Setenv("GIFGREP_KITTY_PLACEHOLDERS", "")
+
+	fetchThumb = func(_ string) ([]byte, error) { return []byte("gif"), nil }
+	decodeThumb = func(_ []byte, _ gifdecode.Options) (*gifdecode.Frames, error) {
+		return &gifdecode.Frames{Frames: []gifdecode.Frame{{PNG: []byte{1}}}}, nil
+	}
+
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+	renderPlain(out, model.Options{}, false, termcaps.InlineKitty, []model.Result{
+		{Title: "A", URL: "https://example.test/a.gif"},
+	}, 80)
+	_ = out.Flush()
+
+	text := buf.String()
+	if !strings.HasPrefix(text, "\r\n\n\n\n\n\n\n\n\x1b[8A\x1bPtmux;\x1b\x1b_G") {
+		t.Fatalf("expected reserved block and tmux passthrough: %q", text)
+	}
+	if !strings.Contains(text, ",U=1;") || strings.Count(text, "\U0010EEEE") != 16*8 {
+		t.Fatalf("expected a 16x8 placeholder block: %q", text)
+	}
+	if !strings.Contains(text, "\x1b[39m\x1b[7A\r\x1b[19GA\x1b[K\n") {
+		t.Fatalf("expected title next to the placeholders: %q", text)
+	}
+}
+
 func TestRenderPlainThumbsItermUsesRawGIF(t *testing.T) {
 	prevFetch := fetchThumb
 	prevDecode := decodeThumb
@@ -117,7 +194,7 @@ func TestRenderPlainThumbsItermUsesRawGIF(t *testing.T) {
 
 	gifData := []byte("GIF89a\x01\x00\x01\x00")
 	fetchThumb = func(_ string) ([]byte, error) { return gifData, nil }
-	decodeThumb = func(_ []byte) (*gifdecode.Frames, error) {
+	decodeThumb = func(_ []byte, _ gifdecode.Options) (*gifdecode.Frames, error) {
 		t.Fatalf("decodeThumb should not be called for iTerm")
 		return nil, errors.New("unexpected call")
 	}
@@ -182,3 +259,134 @@ func TestRenderPlainThumbsItermWrapsURLToTerminalWidth(t *testing.T) {
 		t.Fatalf("unexpected second url line: %q", got)
 	}
 }
+
+func TestRenderPlainThumbsSixel(t *testing.T) {
+	prevFetch := fetchThumb
+	prevDecode := decodeThumb
+	prevSend := sendThumbSixel
+	t.Cleanup(func() {
+		fetchThumb = prevFetch
+		decodeThumb = prevDecode
+		sendThumbSixel = prevSend
+	})
+
+	fetchThumb = func(_ string) ([]byte, error) { return []byte("gif"), nil }
+	decodeThumb = func(_ []byte, opts gifdecode.Options) (*gifdecode.Frames, error) {
+		if opts.CellWidth != termcaps.DefaultCellWidth || opts.TargetCells.Cols != 16 {
+			t.Fatalf("unexpected sixel decode options: %+v", opts)
+		}
+		return &gifdecode.Frames{Frames: []gifdecode.Frame{{PNG: []byte{1}}}}, nil
+	}
+	sendThumbSixel = func(out *bufio.Writer, _ gifdecode.Frame, _ int) error {
+		_, _ = fmt.Fprint(out, "<SIXEL>")
+		return nil
+	}
+
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+	renderPlain(out, model.Options{}, false, termcaps.InlineSixel, []model.Result{
+		{Title: "A", URL: "https://example.test/a.gif"},
+	}, 80)
+	_ = out.Flush()
+
+	text := buf.String()
+	if !strings.Contains(text, "<SIXEL>\x1b[19GA\x1b[K\n") {
+		t.Fatalf("expected title next to the sixel: %q", text)
+	}
+}
+
+func TestRenderPlainThumbsText(t *testing.T) {
+	prevFetch := fetchThumb
+	prevDecode := decodeThumb
+	t.Cleanup(func() {
+		fetchThumb = prevFetch
+		decodeThumb = prevDecode
+	})
+
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	var pngBuf bytes.Buffer
+	if err := png.Encode(&pngBuf, img); err != nil {
+		t.Fatalf("png encode failed: %v", err)
+	}
+	fetchThumb = func(_ string) ([]byte, error) { return []byte("gif"), nil }
+	decodeThumb = func(_ []byte, opts gifdecode.Options) (*gifdecode.Frames, error) {
+		if opts.CellWidth != 2 || opts.CellHeight != 4 {
+			t.Fatalf("unexpected text decode options: %+v", opts)
+		}
+		return &gifdecode.Frames{Frames: []gifdecode.Frame{{PNG: pngBuf.Bytes()}}}, nil
+	}
+
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+	renderPlain(out, model.Options{}, false, termcaps.InlineText, []model.Result{
+		{Title: "A", URL: "https://example.test/a.gif"},
+	}, 80)
+	_ = out.Flush()
+
+	text := buf.String()
+	if !strings.HasPrefix(text, "\r"+strings.Repeat("@", 16)+"\x1b[0m\r\n") {
+		t.Fatalf("expected ascii thumbnail without color: %q", text)
+	}
+	if !strings.Contains(text, "\x1b[7A\r\x1b[19GA\x1b[K\n") {
+		t.Fatalf("expected title next to the thumbnail: %q", text)
+	}
+}
+
+func TestThumbColorDepth(t *testing.T) {
+	env := func(vals map[string]string) func(string) string {
+		return func(k string) string { return vals[k] }
+	}
+	if got := thumbColorDepth(false, env(map[string]string{"COLORTERM": "truecolor"})); got != termcaps.ColorNone {
+		t.Fatalf("expected no color, got %s", got)
+	}
+	if got := thumbColorDepth(true, env(map[string]string{"COLORTERM": "truecolor"})); got != termcaps.ColorTrue {
+		t.Fatalf("expected truecolor, got %s", got)
+	}
+	if got := thumbColorDepth(true, env(map[string]string{"TERM": "dumb"})); got != termcaps.Color256 {
+		t.Fatalf("expected 256 colors when color is forced, got %s", got)
+	}
+}
+
+func TestSendThumbSixelReservesBlock(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	var pngBuf bytes.Buffer
+	if err := png.Encode(&pngBuf, img); err != nil {
+		t.Fatalf("png encode failed: %v", err)
+	}
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+	if err := sendThumbSixel(out, gifdecode.Frame{PNG: pngBuf.Bytes()}, 3); err != nil {
+		t.Fatalf("sendThumbSixel failed: %v", err)
+	}
+	_ = out.Flush()
+	text := buf.String()
+	if !strings.HasPrefix(text, "\r\n\n\n\x1b[3A\x1b7\x1bP") || !strings.HasSuffix(text, "\x1b\\\x1b8") {
+		t.Fatalf("unexpected sixel thumb output: %q", text)
+	}
+	if err := sendThumbSixel(out, gifdecode.Frame{PNG: []byte("nope")}, 3); err == nil {
+		t.Fatalf("expected error for bad frame")
+	}
+}
+
+func TestThumbBlockSizeUsesCellAspect(t *testing.T) {
+	res := model.Result{Width: 160, Height: 120}
+	if cols, rows := thumbBlockSize(termcaps.InlineKitty, nil, res, termcaps.CellSize{}); cols != 16 || rows != 6 {
+		t.Fatalf("expected 16x6 with default cells, got %dx%d", cols, rows)
+	}
+	// 8x24 px cells are three times taller than wide.
+	cell := termcaps.CellSize{Width: 8, Height: 24}
+	if cols, rows := thumbBlockSize(termcaps.InlineKitty, nil, res, cell); cols != 16 || rows != 4 {
+		t.Fatalf("expected 16x4 with measured cells, got %dx%d", cols, rows)
+	}
+	opts := thumbDecodeOptions(termcaps.InlineSixel, 16, 4, cell)
+	if opts.CellWidth != 8 || opts.CellHeight != 24 {
+		t.Fatalf("expected sixel thumbs decoded at the measured cell size, got %dx%d", opts.CellWidth, opts.CellHeight)
+	}
+	opts = thumbDecodeOptions(termcaps.InlineSixel, 16, 4, termcaps.CellSize{})
+	if opts.CellWidth != termcaps.DefaultCellWidth || opts.CellHeight != termcaps.DefaultCellHeight {
+		t.Fatalf("expected default sixel cell size, got %dx%d", opts.CellWidth, opts.CellHeight)
+	}
+}
@@ -48,7 +48,7 @@ func TestRunArgs(t *testing.T) {
 		t.Setenv("GIFGREP_INLINE", "kitty")
 		tui.SetDefaultEnvForTest(func() tui.Env {
 			return tui.Env{
-				In:         bytes.NewReader([]byte("q")),
+				In:         bytes.NewReader([]byte("\x03")), // ctrl+c
 				Out:        io.Discard,
 				FD:         1,
 				IsTerminal: func(int) bool { return true },
@@ -0,0 +1,222 @@
+// Package blocks renders images as terminal text: colored Unicode half-blocks
+// when the terminal has color, ASCII art when it does not.
+package blocks
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"strings"
+
+	"github.com/steipete/gifgrep/internal/termcaps"
+)
+
+const (
+	upperHalf = "▀"
+	lowerHalf = "▄"
+	reset     = "\x1b[0m"
+)
+
+// asciiRamp runs from dark to bright.
+const asciiRamp = " .:-=+*#%@"
+
+// alphaCutoff is the coverage below which a pixel counts as transparent.
+const alphaCutoff = 0x80
+
+type pixel struct {
+	r, g, b, a uint8
+}
+
+// RenderPNG decodes a PNG frame (as produced by gifdecode) and renders it.
+func RenderPNG(data []byte, cols, rows int, depth termcaps.ColorDepth) ([]string, error) {
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return Render(img, cols, rows, depth), nil
+}
+
+// Render draws img into a cols x rows cell box and returns one string per
+// row. Every line is exactly cols cells wide and ends with an SGR reset, so
+// lines can be written next to other content without bleeding color.
+func Render(img image.Image, cols, rows int, depth termcaps.ColorDepth) []string {
+	if cols <= 0 || rows <= 0 {
+		return nil
+	}
+	if depth == termcaps.ColorNone {
+		return renderASCII(sample(img, cols, rows), cols, rows)
+	}
+	// Each cell holds two vertically stacked pixels.
+	px := sample(img, cols, rows*2)
+	lines := make([]string, rows)
+	var sb strings.Builder
+	for y := 0; y < rows; y++ {
+		sb.Reset()
+		var fg, bg string
+		for x := 0; x < cols; x++ {
+			top := px[(2*y)*cols+x]
+			bottom := px[(2*y+1)*cols+x]
+			topOn := top.a >= alphaCutoff
+			bottomOn := bottom.a >= alphaCutoff
+			var wantFG, wantBG, glyph string
+			switch {
+			case topOn && bottomOn:
+				wantFG, wantBG, glyph = colorCode(top, depth, true), colorCode(bottom, depth, false), upperHalf
+			case topOn:
+				wantFG, wantBG, glyph = colorCode(top, depth, true), "49", upperHalf
+			case bottomOn:
+				wantFG, wantBG, glyph = colorCode(bottom, depth, true), "49", lowerHalf
+			default:
+				wantFG, wantBG, glyph = fg, "49", " "
+				if wantFG == "" {
+					wantFG = "39"
+				}
+			}
+			if wantFG != fg || wantBG != bg {
+				sb.WriteString("\x1b[")
+				sb.WriteString(wantFG)
+				sb.WriteByte(';')
+				sb.WriteString(wantBG)
+				sb.WriteByte('m')
+				fg, bg = wantFG, wantBG
+			}
+			sb.WriteString(glyph)
+		}
+		sb.WriteString(reset)
+		lines[y] = sb.String()
+	}
+	return lines
+}
+
+func renderASCII(px []pixel, cols, rows int) []string {
+	lines := make([]string, rows)
+	buf := make([]byte, cols)
+	for y := 0; y < rows; y++ {
+		for x := 0; x < cols; x++ {
+			p := px[y*cols+x]
+			if p.a < alphaCutoff {
+				buf[x] = ' '
+				continue
+			}
+			idx := luminance(p) * (len(asciiRamp) - 1) / 255
+			buf[x] = asciiRamp[idx]
+		}
+		lines[y] = string(buf) + reset
+	}
+	return lines
+}
+
+// sample box-averages img down (or nearest-neighbour up) to w x h pixels.
+// Color channels are averaged premultiplied, so transparent pixels don't
+// darken their neighbours.
+func sample(img image.Image, w, h int) []pixel {
+	b := img.Bounds()
+	out := make([]pixel, w*h)
+	if b.Empty() {
+		return out
+	}
+	srcW, srcH := b.Dx(), b.Dy()
+	for y := 0; y < h; y++ {
+		y0 := b.Min.Y + y*srcH/h
+		y1 := b.Min.Y + (y+1)*srcH/h
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < w; x++ {
+			x0 := b.Min.X + x*srcW/w
+			x1 := b.Min.X + (x+1)*srcW/w
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r += uint64(cr)
+					g += uint64(cg)
+					bl += uint64(cb)
+					a += uint64(ca)
+					n++
+				}
+			}
+			if a == 0 {
+				continue
+			}
+			// Un-premultiply: r/a gives the straight color in 0-1.
+			out[y*w+x] = pixel{
+				r: uint8(r * 255 / a),
+				g: uint8(g * 255 / a),
+				b: uint8(bl * 255 / a),
+				a: uint8(a / n >> 8),
+			}
+		}
+	}
+	return out
+}
+
+func colorCode(p pixel, depth termcaps.ColorDepth, foreground bool) string {
+	base := 38
+	if !foreground {
+		base = 48
+	}
+	if depth == termcaps.ColorTrue {
+		return fmt.Sprintf("%d;2;%d;%d;%d", base, p.r, p.g, p.b)
+	}
+	return fmt.Sprintf("%d;5;%d", base, ansi256(p))
+}
+
+// ansi256 maps a color to the closest entry in the xterm 6x6x6 cube or the
+// 24-step grey ramp.
+func ansi256(p pixel) int {
+	ri, gi, bi := cubeIndex(p.r), cubeIndex(p.g), cubeIndex(p.b)
+	cube := 16 + 36*ri + 6*gi + bi
+	cubeDist := dist(p, cubeLevel(ri), cubeLevel(gi), cubeLevel(bi))
+
+	avg := (int(p.r) + int(p.g) + int(p.b)) / 3
+	greyIdx := 23
+	if avg < 238 {
+		greyIdx = maxInt(0, (avg-3)/10)
+	}
+	grey := uint8(8 + 10*greyIdx)
+	if dist(p, grey, grey, grey) < cubeDist {
+		return 232 + greyIdx
+	}
+	return cube
+}
+
+func cubeIndex(v uint8) int {
+	if v < 48 {
+		return 0
+	}
+	if v < 115 {
+		return 1
+	}
+	return (int(v) - 35) / 40
+}
+
+func cubeLevel(i int) uint8 {
+	if i == 0 {
+		return 0
+	}
+	return uint8(55 + 40*i)
+}
+
+func dist(p pixel, r, g, b uint8) int {
+	dr := int(p.r) - int(r)
+	dg := int(p.g) - int(g)
+	db := int(p.b) - int(b)
+	return dr*dr + dg*dg + db*db
+}
+
+// luminance returns perceived brightness (Rec. 601) in 0-255.
+func luminance(p pixel) int {
+	return (299*int(p.r) + 587*int(p.g) + 114*int(p.b)) / 1000
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,129 @@
+package blocks
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/mattn/go-runewidth"
+
+	"github.com/steipete/gifgrep/internal/termcaps"
+)
+
+func visibleWidth(s string) int {
+	var out strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == 0x1b {
+			for i < len(s) && s[i] != 'm' {
+				i++
+			}
+			continue
+		}
+		out.WriteByte(s[i])
+	}
+	return runewidth.StringWidth(out.String())
+}
+
+func TestRenderTrueColorHalfBlocks(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			c := color.RGBA{R: 0xff, A: 0xff}
+			if y >= 2 {
+				c = color.RGBA{B: 0xff, A: 0xff}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	lines := Render(img, 4, 1, termcaps.ColorTrue)
+	if len(lines) != 1 {
+		t.Fatalf("expected 1 line, got %d", len(lines))
+	}
+	line := lines[0]
+	if !strings.Contains(line, "38;2;255;0;0;48;2;0;0;255m") {
+		t.Fatalf("expected red over blue: %q", line)
+	}
+	if strings.Count(line, upperHalf) != 4 || strings.Count(line, "\x1b[38") != 1 {
+		t.Fatalf("expected one color run of four half-blocks: %q", line)
+	}
+	if !strings.HasSuffix(line, reset) {
+		t.Fatalf("expected trailing reset: %q", line)
+	}
+}
+
+func TestRender256AndTransparency(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 4))
+	img.SetRGBA(0, 2, color.RGBA{G: 0xff, A: 0xff})
+	img.SetRGBA(0, 3, color.RGBA{G: 0xff, A: 0xff})
+	lines := Render(img, 2, 2, termcaps.Color256)
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+	for i, line := range lines {
+		if w := visibleWidth(line); w != 2 {
+			t.Fatalf("line %d width=%d: %q", i, w, line)
+		}
+	}
+	if strings.ContainsAny(lines[0], upperHalf+lowerHalf) {
+		t.Fatalf("expected transparent first row: %q", lines[0])
+	}
+	if !strings.Contains(lines[1], "38;5;46;48;5;46m"+upperHalf) {
+		t.Fatalf("expected green cell in 256 colors: %q", lines[1])
+	}
+}
+
+func TestRenderASCII(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 3, 1))
+	img.SetRGBA(0, 0, color.RGBA{A: 0xff})
+	img.SetRGBA(1, 0, color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff})
+	lines := Render(img, 3, 1, termcaps.ColorNone)
+	if len(lines) != 1 || lines[0] != " @ "+reset {
+		t.Fatalf("unexpected ascii output: %q", lines)
+	}
+}
+
+func TestRenderPNG(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+	lines, err := RenderPNG(buf.Bytes(), 5, 3, termcaps.ColorTrue)
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d", len(lines))
+	}
+	for i, line := range lines {
+		if w := visibleWidth(line); w != 5 {
+			t.Fatalf("line %d width=%d: %q", i, w, line)
+		}
+	}
+	if _, err := RenderPNG([]byte("nope"), 1, 1, termcaps.ColorTrue); err == nil {
+		t.Fatalf("expected error for invalid png")
+	}
+}
+
+func TestAnsi256(t *testing.T) {
+	cases := []struct {
+		p    pixel
+		want int
+	}{
+		{pixel{r: 0, g: 0, b: 0}, 16},
+		{pixel{r: 255, g: 255, b: 255}, 231},
+		{pixel{r: 255, g: 0, b: 0}, 196},
+		{pixel{r: 128, g: 128, b: 128}, 244},
+	}
+	for _, tc := range cases {
+		if got := ansi256(tc.p); got != tc.want {
+			t.Fatalf("ansi256(%+v)=%d want %d", tc.p, got, tc.want)
+		}
+	}
+}
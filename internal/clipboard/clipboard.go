@@ -0,0 +1,60 @@
+package clipboard
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+var (
+	execCommand = exec.Command
+	lookPath    = exec.LookPath
+	getenv      = os.Getenv
+)
+
+// Copy puts text on the system clipboard with the platform's clipboard
+// tool (pbcopy, clip.exe, wl-copy, xclip or xsel).
+func Copy(text string) error {
+	cmd, args, err := commandForCopy(runtime.GOOS)
+	if err != nil {
+		return err
+	}
+	c := execCommand(cmd, args...)
+	c.Stdin = strings.NewReader(text)
+	// Stdout and Stderr stay nil (/dev/null): with a pipe, Run would wait
+	// for the background selection owner xclip and wl-copy fork, which
+	// keeps it open until another app takes the selection.
+	return c.Run()
+}
+
+// OSC52 returns the escape sequence that asks the terminal to put text on
+// the clipboard. It works over SSH, where the native tools can't reach the
+// local clipboard.
+func OSC52(text string) string {
+	return "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\x07"
+}
+
+func commandForCopy(goos string) (string, []string, error) {
+	switch goos {
+	case "darwin":
+		return "pbcopy", nil, nil
+	case "windows":
+		return "clip.exe", nil, nil
+	default:
+		if strings.TrimSpace(getenv("WAYLAND_DISPLAY")) != "" {
+			if _, err := lookPath("wl-copy"); err == nil {
+				return "wl-copy", nil, nil
+			}
+		}
+		if _, err := lookPath("xclip"); err == nil {
+			return "xclip", []string{"-selection", "clipboard"}, nil
+		}
+		if _, err := lookPath("xsel"); err == nil {
+			return "xsel", []string{"--clipboard", "--input"}, nil
+		}
+		return "", nil, errors.New("no clipboard tool found (need wl-copy, xclip or xsel)")
+	}
+}
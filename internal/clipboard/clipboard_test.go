@@ -0,0 +1,102 @@
+package clipboard
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func stubTools(t *testing.T, env map[string]string, tools ...string) {
+	t.Helper()
+	prevLook, prevEnv := lookPath, getenv
+	lookPath = func(name string) (string, error) {
+		for _, tool := range tools {
+			if tool == name {
+				return "/usr/bin/" + name, nil
+			}
+		}
+		return "", errors.New("nope")
+	}
+	getenv = func(k string) string { return env[k] }
+	t.Cleanup(func() { lookPath, getenv = prevLook, prevEnv })
+}
+
+func TestCommandForCopyPlatforms(t *testing.T) {
+	if cmd, _, err := commandForCopy("darwin"); err != nil || cmd != "pbcopy" {
+		t.Fatalf("unexpected darwin cmd: %q %v", cmd, err)
+	}
+	if cmd, _, err := commandForCopy("windows"); err != nil || cmd != "clip.exe" {
+		t.Fatalf("unexpected windows cmd: %q %v", cmd, err)
+	}
+}
+
+func TestCommandForCopyLinuxPrefersWayland(t *testing.T) {
+	stubTools(t, map[string]string{"WAYLAND_DISPLAY": "wayland-0"}, "wl-copy", "xclip")
+	cmd, _, err := commandForCopy("linux")
+	if err != nil || cmd != "wl-copy" {
+		t.Fatalf("unexpected cmd: %q %v", cmd, err)
+	}
+}
+
+func TestCommandForCopyLinuxFallsBackToX11(t *testing.T) {
+	stubTools(t, map[string]string{}, "wl-copy", "xclip")
+	cmd, args, err := commandForCopy("linux")
+	if err != nil || cmd != "xclip" || len(args) != 2 || args[1] != "clipboard" {
+		t.Fatalf("unexpected cmd: %q %#v %v", cmd, args, err)
+	}
+
+	stubTools(t, map[string]string{}, "xsel")
+	if cmd, _, err := commandForCopy("linux"); err != nil || cmd != "xsel" {
+		t.Fatalf("unexpected cmd: %q %v", cmd, err)
+	}
+
+	stubTools(t, map[string]string{})
+	if _, _, err := commandForCopy("linux"); err == nil {
+		t.Fatalf("expected error without clipboard tools")
+	}
+}
+
+func TestCopyWritesStdin(t *testing.T) {
+	stubTools(t, map[string]string{}, "xclip")
+	out := filepath.Join(t.TempDir(), "clip.txt")
+	prev := execCommand
+	execCommand = func(string, ...string) *exec.Cmd {
+		return exec.Command("sh", "-c", `cat > "$0"`, out)
+	}
+	t.Cleanup(func() { execCommand = prev })
+
+	if err := Copy("https://example.test/a.gif"); err != nil {
+		t.Fatalf("copy: %v", err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil || string(data) != "https://example.test/a.gif" {
+		t.Fatalf("unexpected clipboard %q (%v)", data, err)
+	}
+}
+
+func TestCopyDoesNotWaitForForkedOwner(t *testing.T) {
+	stubTools(t, map[string]string{}, "xclip")
+	prev := execCommand
+	// Like xclip, leave a background process behind that owns the selection.
+	execCommand = func(string, ...string) *exec.Cmd {
+		return exec.Command("sh", "-c", "cat >/dev/null; sleep 5 &")
+	}
+	t.Cleanup(func() { execCommand = prev })
+
+	start := time.Now()
+	if err := Copy("https://example.test/a.gif"); err != nil {
+		t.Fatalf("copy: %v", err)
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Fatalf("expected Copy to return without waiting for the background process, took %v", d)
+	}
+}
+
+func TestOSC52(t *testing.T) {
+	if got := OSC52("hi"); got != "\x1b]52;c;aGk=\x07" {
+		t.Fatalf("unexpected sequence %q", got)
+	}
+}
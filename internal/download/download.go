@@ -25,12 +25,13 @@ func ToDownloads(item model.Result) (string, error) {
 		return "", err
 	}
 	filename := filenameForResult(item)
-	finalPath, err := uniqueFilePath(dir, filename)
+	finalPath, err := reserveFilePath(dir, filename)
 	if err != nil {
 		return "", err
 	}
 	client := &http.Client{Timeout: 20 * time.Second}
 	if err := downloadGIFToFile(client, item.URL, finalPath); err != nil {
+		_ = os.Remove(finalPath)
 		return "", err
 	}
 	return finalPath, nil
@@ -44,6 +45,29 @@ func DefaultDir() (string, error) {
 	return filepath.Join(home, "Downloads"), nil
 }
 
+// FilenameForResult returns the file name ToDownloads uses for item with
+// ext (e.g. "-sheet.png") in place of ".gif".
+func FilenameForResult(item model.Result, ext string) string {
+	return strings.TrimSuffix(filenameForResult(item), ".gif") + ext
+}
+
+// SaveFile writes data to dir/filename, or to a numbered variant when that
+// name is taken, and returns the path.
+func SaveFile(dir, filename string, data []byte) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	p, err := reserveFilePath(dir, filename)
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(p, data, 0o644); err != nil {
+		_ = os.Remove(p)
+		return "", err
+	}
+	return p, nil
+}
+
 func filenameForResult(item model.Result) string {
 	name := strings.TrimSpace(item.Title)
 	if name == "" {
@@ -143,6 +167,27 @@ func uniqueFilePath(dir, filename string) (string, error) {
 	return "", err
 }
 
+// reserveFilePath picks a free name like uniqueFilePath and creates an empty
+// file there, so concurrent downloads of same-named GIFs can't pick the same
+// path. The download is renamed over it.
+func reserveFilePath(dir, filename string) (string, error) {
+	for i := 0; i < 10; i++ {
+		p, err := uniqueFilePath(dir, filename)
+		if err != nil {
+			return "", err
+		}
+		f, err := os.OpenFile(p, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		return p, f.Close()
+	}
+	return "", errors.New("could not pick filename")
+}
+
 func downloadGIFToFile(client *http.Client, gifURL, dest string) error {
 	if client == nil {
 		client = http.DefaultClient
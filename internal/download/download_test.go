@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/steipete/gifgrep/internal/model"
@@ -64,6 +65,60 @@ func TestUniqueFilePath(t *testing.T) {
 	}
 }
 
+func TestReserveFilePathConcurrent(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	paths := make(chan string, 8)
+	var wg sync.WaitGroup
+	for i := 0; i < cap(paths); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p, err := reserveFilePath(dir, "x.gif")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			paths <- p
+		}()
+	}
+	wg.Wait()
+	close(paths)
+	seen := map[string]bool{}
+	for p := range paths {
+		if seen[p] {
+			t.Fatalf("path picked twice: %q", p)
+		}
+		seen[p] = true
+	}
+	if len(seen) != 8 {
+		t.Fatalf("expected 8 paths, got %d", len(seen))
+	}
+}
+
+func TestSaveFile(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "out")
+	name := FilenameForResult(model.Result{Title: "Happy Cat"}, "-sheet.png")
+	if name != "Happy_Cat-sheet.png" {
+		t.Fatalf("unexpected name %q", name)
+	}
+	first, err := SaveFile(dir, name, []byte("one"))
+	if err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	second, err := SaveFile(dir, name, []byte("two"))
+	if err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if filepath.Base(first) != name || filepath.Base(second) != "Happy_Cat-sheet-1.png" {
+		t.Fatalf("unexpected paths %q %q", first, second)
+	}
+	if data, _ := os.ReadFile(second); string(data) != "two" {
+		t.Fatalf("unexpected content %q", data)
+	}
+}
+
 func TestDownloadGIFToFile(t *testing.T) {
 	t.Parallel()
 
@@ -10,6 +10,21 @@ import (
 	"github.com/steipete/gifgrep/gifdecode"
 )
 
+// Mode selects how graphics commands reach the terminal and how images are
+// shown. The zero value places images directly at the cursor.
+type Mode struct {
+	// Unicode creates virtual placements (U=1) and shows them by writing
+	// U+10EEEE placeholder cells, so the image moves with the text grid and
+	// survives multiplexer redraws.
+	Unicode bool
+	// Passthrough wraps every graphics command so a multiplexer forwards it
+	// to the outer terminal.
+	Passthrough Passthrough
+	// Medium is how image data is transmitted; files and shared memory skip
+	// the base64 escape traffic when the terminal runs on this machine.
+	Medium Medium
+}
+
 type kittyData struct {
 	Action      string
 	ID          uint32
@@ -19,14 +34,20 @@ type kittyData struct {
 	PlacementID int
 	Delay       time.Duration
 	NoCursor    bool
+	Virtual     bool
+	// Frame deltas: the data is drawn at X,Y over frame BaseFrame (1-based),
+	// replacing its pixels.
+	X         int
+	Y         int
+	BaseFrame int
 }
 
-func SendAnimation(out *bufio.Writer, id uint32, frames []gifdecode.Frame, cols, rows int) {
+func (m Mode) SendAnimation(out *bufio.Writer, id uint32, frames []gifdecode.Frame, cols, rows int) {
 	if len(frames) == 0 {
 		return
 	}
 	base := frames[0]
-	sendKittyData(out, kittyData{
+	m.sendData(out, kittyData{
 		Action:      "T",
 		ID:          id,
 		Data:        base.PNG,
@@ -34,22 +55,40 @@ func SendAnimation(out *bufio.Writer, id uint32, frames []gifdecode.Frame, cols,
 		Rows:        rows,
 		PlacementID: 1,
 		NoCursor:    true,
+		Virtual:     m.Unicode,
 	})
 	for i := 1; i < len(frames); i++ {
 		frame := frames[i]
-		sendKittyData(out, kittyData{
+		if d := frame.Delta; d != nil {
+			// Only the changed rectangle, on top of the previous frame
+			// (frame numbers are 1-based, so that is frame i).
+			m.sendData(out, kittyData{
+				Action:    "f",
+				ID:        id,
+				Data:      d.PNG,
+				Delay:     frame.Delay,
+				X:         d.Bounds.Min.X,
+				Y:         d.Bounds.Min.Y,
+				BaseFrame: i,
+			})
+			continue
+		}
+		m.sendData(out, kittyData{
 			Action: "f",
 			ID:     id,
 			Data:   frame.PNG,
 			Delay:  frame.Delay,
 		})
 	}
-	sendKittyAnimDelay(out, id, delayMS(base.Delay))
-	sendKittyAnimStart(out, id)
+	m.sendAnimDelay(out, id, delayMS(base.Delay))
+	m.sendAnimStart(out, id)
+	if m.Unicode {
+		writePlaceholders(out, id, cols, rows)
+	}
 }
 
-func SendFrame(out *bufio.Writer, id uint32, frame gifdecode.Frame, cols, rows int) {
-	sendKittyData(out, kittyData{
+func (m Mode) SendFrame(out *bufio.Writer, id uint32, frame gifdecode.Frame, cols, rows int) {
+	m.sendData(out, kittyData{
 		Action:      "T",
 		ID:          id,
 		Data:        frame.PNG,
@@ -57,10 +96,24 @@ func SendFrame(out *bufio.Writer, id uint32, frame gifdecode.Frame, cols, rows i
 		Rows:        rows,
 		PlacementID: 1,
 		NoCursor:    true,
+		Virtual:     m.Unicode,
 	})
+	if m.Unicode {
+		writePlaceholders(out, id, cols, rows)
+	}
 }
 
-func sendKittyData(out *bufio.Writer, data kittyData) {
+func (m Mode) sendData(out *bufio.Writer, data kittyData) {
+	if m.Medium != MediumDirect {
+		if payload, err := m.Medium.stage(data.Data); err == nil {
+			params := data.params(0)
+			params = append(params, fmt.Sprintf("t=%s", m.Medium.key()), fmt.Sprintf("S=%d", len(data.Data)))
+			m.command(out, fmt.Sprintf("\x1b_G%s;%s\x1b\\", strings.Join(params, ","), payload))
+			return
+		}
+		// Staging failed (disk full, no /dev/shm): send the bytes inline.
+	}
+
 	encoded := base64.StdEncoding.EncodeToString(data.Data)
 	const chunkSize = 4096
 	first := true
@@ -75,73 +128,115 @@ func sendKittyData(out *bufio.Writer, data kittyData) {
 			more = 1
 		}
 		if first {
-			params := []string{
-				fmt.Sprintf("a=%s", data.Action),
-				"f=100",
-				fmt.Sprintf("i=%d", data.ID),
-				fmt.Sprintf("m=%d", more),
-				"q=2",
-			}
-			if data.Cols > 0 {
-				params = append(params, fmt.Sprintf("c=%d", data.Cols))
-			}
-			if data.Rows > 0 {
-				params = append(params, fmt.Sprintf("r=%d", data.Rows))
-			}
-			if data.PlacementID > 0 {
-				params = append(params, fmt.Sprintf("p=%d", data.PlacementID))
-			}
-			if data.NoCursor {
-				params = append(params, "C=1")
-			}
-			if data.Action == "f" && data.Delay > 0 {
-				params = append(params, fmt.Sprintf("z=%d", delayMS(data.Delay)))
-			}
-			_, _ = fmt.Fprintf(out, "\x1b_G%s;", strings.Join(params, ","))
+			m.command(out, fmt.Sprintf("\x1b_G%s;%s\x1b\\", strings.Join(data.params(more), ","), chunk))
 			first = false
 		} else {
 			if data.Action == "f" {
-				_, _ = fmt.Fprintf(out, "\x1b_Ga=f,m=%d;", more)
+				m.command(out, fmt.Sprintf("\x1b_Ga=f,m=%d;%s\x1b\\", more, chunk))
 			} else {
-				_, _ = fmt.Fprintf(out, "\x1b_Gm=%d;", more)
+				m.command(out, fmt.Sprintf("\x1b_Gm=%d;%s\x1b\\", more, chunk))
 			}
 		}
-		_, _ = fmt.Fprint(out, chunk)
-		_, _ = fmt.Fprint(out, "\x1b\\")
 	}
 }
 
-func sendKittyAnimDelay(out *bufio.Writer, id uint32, delayMS int) {
+// params returns the keys of the first (or only) command for data.
+func (data kittyData) params(more int) []string {
+	params := []string{
+		fmt.Sprintf("a=%s", data.Action),
+		"f=100",
+		fmt.Sprintf("i=%d", data.ID),
+		fmt.Sprintf("m=%d", more),
+		"q=2",
+	}
+	if data.Cols > 0 {
+		params = append(params, fmt.Sprintf("c=%d", data.Cols))
+	}
+	if data.Rows > 0 {
+		params = append(params, fmt.Sprintf("r=%d", data.Rows))
+	}
+	if data.PlacementID > 0 {
+		params = append(params, fmt.Sprintf("p=%d", data.PlacementID))
+	}
+	if data.NoCursor {
+		params = append(params, "C=1")
+	}
+	if data.Virtual {
+		params = append(params, "U=1")
+	}
+	if data.Action == "f" && data.Delay > 0 {
+		params = append(params, fmt.Sprintf("z=%d", delayMS(data.Delay)))
+	}
+	if data.Action == "f" && data.BaseFrame > 0 {
+		params = append(params, fmt.Sprintf("x=%d", data.X), fmt.Sprintf("y=%d", data.Y), fmt.Sprintf("c=%d", data.BaseFrame), "X=1")
+	}
+	return params
+}
+
+func (m Mode) sendAnimDelay(out *bufio.Writer, id uint32, delayMS int) {
 	if delayMS <= 0 {
 		return
 	}
-	_, _ = fmt.Fprintf(out, "\x1b_Ga=a,i=%d,r=1,z=%d,q=2\x1b\\", id, delayMS)
+	m.command(out, fmt.Sprintf("\x1b_Ga=a,i=%d,r=1,z=%d,q=2\x1b\\", id, delayMS))
 }
 
-func sendKittyAnimStart(out *bufio.Writer, id uint32) {
-	_, _ = fmt.Fprintf(out, "\x1b_Ga=a,i=%d,s=3,v=1,q=2\x1b\\", id)
+func (m Mode) sendAnimStart(out *bufio.Writer, id uint32) {
+	m.command(out, fmt.Sprintf("\x1b_Ga=a,i=%d,s=3,v=1,q=2\x1b\\", id))
+}
+
+// SetPlaying stops (s=1) or resumes looping (s=3) an uploaded animation.
+func (m Mode) SetPlaying(out *bufio.Writer, id uint32, playing bool) {
+	if id == 0 {
+		return
+	}
+	state := 1
+	if playing {
+		state = 3
+	}
+	m.command(out, fmt.Sprintf("\x1b_Ga=a,i=%d,s=%d,q=2\x1b\\", id, state))
 }
 
-func PlaceImage(out *bufio.Writer, id uint32, cols, rows int) {
+// PlaceImage shows an uploaded image in a cols x rows box at the cursor. In
+// Unicode mode it re-creates the virtual placement and rewrites the
+// placeholder cells, which any redraw of the area will have erased.
+func (m Mode) PlaceImage(out *bufio.Writer, id uint32, cols, rows int) {
 	if id == 0 {
 		return
 	}
-	_, _ = fmt.Fprintf(out, "\x1b_Ga=p,i=%d,p=1,c=%d,r=%d,C=1,q=2\x1b\\", id, cols, rows)
+	if m.Unicode {
+		m.command(out, fmt.Sprintf("\x1b_Ga=p,U=1,i=%d,p=1,c=%d,r=%d,q=2\x1b\\", id, cols, rows))
+		writePlaceholders(out, id, cols, rows)
+		return
+	}
+	m.command(out, fmt.Sprintf("\x1b_Ga=p,i=%d,p=1,c=%d,r=%d,C=1,q=2\x1b\\", id, cols, rows))
 }
 
-func DeleteImage(out *bufio.Writer, id uint32) {
+func (m Mode) DeleteImage(out *bufio.Writer, id uint32) {
 	if id == 0 {
 		return
 	}
-	_, _ = fmt.Fprintf(out, "\x1b_Ga=d,d=I,i=%d,q=2\x1b\\", id)
+	m.command(out, fmt.Sprintf("\x1b_Ga=d,d=I,i=%d,q=2\x1b\\", id))
 }
 
+// DeleteAll removes every image placement.
+func (m Mode) DeleteAll(out *bufio.Writer) {
+	m.command(out, "\x1b_Ga=d\x1b\\")
+}
+
+func (m Mode) command(out *bufio.Writer, seq string) {
+	_, _ = out.WriteString(m.Passthrough.wrap(seq))
+}
+
+// maxFrameDelay is the longest GIF delay (0xffff centiseconds); merged
+// duplicate frames can legitimately hold for several seconds.
+const maxFrameDelay = 0xffff * 10 * time.Millisecond
+
 func clampDelay(delay time.Duration) time.Duration {
 	if delay < 10*time.Millisecond {
 		return 10 * time.Millisecond
 	}
-	if delay > time.Second {
-		return time.Second
+	if delay > maxFrameDelay {
+		return maxFrameDelay
 	}
 	return delay
 }
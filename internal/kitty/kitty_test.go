@@ -3,6 +3,7 @@ package kitty
 import (
 	"bufio"
 	"bytes"
+	"image"
 	"strings"
 	"testing"
 	"time"
@@ -13,7 +14,8 @@ import (
 func TestKittySequences(t *testing.T) {
 	var buf bytes.Buffer
 	out := bufio.NewWriter(&buf)
-	sendKittyData(out, kittyData{
+	var m Mode
+	m.sendData(out, kittyData{
 		Action:      "T",
 		ID:          7,
 		Data:        []byte{1, 2, 3},
@@ -22,10 +24,10 @@ func TestKittySequences(t *testing.T) {
 		PlacementID: 1,
 		NoCursor:    true,
 	})
-	sendKittyAnimDelay(out, 7, 80)
-	sendKittyAnimStart(out, 7)
-	PlaceImage(out, 7, 2, 3)
-	DeleteImage(out, 7)
+	m.sendAnimDelay(out, 7, 80)
+	m.sendAnimStart(out, 7)
+	m.PlaceImage(out, 7, 2, 3)
+	m.DeleteImage(out, 7)
 	_ = out.Flush()
 
 	s := buf.String()
@@ -37,7 +39,7 @@ func TestKittySequences(t *testing.T) {
 	}
 
 	buf.Reset()
-	SendAnimation(out, 2, []gifdecode.Frame{
+	m.SendAnimation(out, 2, []gifdecode.Frame{
 		{PNG: []byte{1, 2, 3}, Delay: 80 * time.Millisecond},
 		{PNG: []byte{4, 5, 6}, Delay: 90 * time.Millisecond},
 	}, 5, 4)
@@ -47,9 +49,18 @@ func TestKittySequences(t *testing.T) {
 	}
 
 	buf.Reset()
-	sendKittyAnimDelay(out, 7, 0)
-	PlaceImage(out, 0, 2, 3)
-	DeleteImage(out, 0)
+	m.SetPlaying(out, 7, false)
+	m.SetPlaying(out, 7, true)
+	_ = out.Flush()
+	if !strings.Contains(buf.String(), "a=a,i=7,s=1") || !strings.Contains(buf.String(), "a=a,i=7,s=3") {
+		t.Fatalf("expected stop and loop commands, got %q", buf.String())
+	}
+
+	buf.Reset()
+	m.sendAnimDelay(out, 7, 0)
+	m.PlaceImage(out, 0, 2, 3)
+	m.DeleteImage(out, 0)
+	m.SetPlaying(out, 0, true)
 	_ = out.Flush()
 	if buf.Len() != 0 {
 		t.Fatalf("expected no output for no-op calls")
@@ -60,9 +71,117 @@ func TestKittySequences(t *testing.T) {
 	for i := range large {
 		large[i] = byte(i % 251)
 	}
-	sendKittyData(out, kittyData{Action: "f", ID: 9, Data: large})
+	m.sendData(out, kittyData{Action: "f", ID: 9, Data: large})
 	_ = out.Flush()
 	if !strings.Contains(buf.String(), "a=f") {
 		t.Fatalf("expected chunked frame data")
 	}
 }
+
+func TestDelayMSAllowsMergedFrames(t *testing.T) {
+	if got := delayMS(time.Millisecond); got != 10 {
+		t.Fatalf("expected 10ms floor, got %d", got)
+	}
+	if got := delayMS(3 * time.Second); got != 3000 {
+		t.Fatalf("expected long delay to pass through, got %d", got)
+	}
+	if got := delayMS(time.Hour); got != 655350 {
+		t.Fatalf("expected GIF max delay cap, got %d", got)
+	}
+}
+
+func TestUnicodePlaceholders(t *testing.T) {
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+	Mode{Unicode: true}.SendFrame(out, 0x010203, gifdecode.Frame{PNG: []byte{1}}, 2, 2)
+	_ = out.Flush()
+	s := buf.String()
+	if !strings.Contains(s, ",U=1;") {
+		t.Fatalf("expected virtual placement: %q", s)
+	}
+	want := "\x1b[38;2;1;2;3m" +
+		"\U0010EEEE\u0305\u0305\U0010EEEE\u0305\u030D" +
+		"\x1b[39m\x1b[2D\x1b[B\x1b[38;2;1;2;3m" +
+		"\U0010EEEE\u030D\u0305\U0010EEEE\u030D\u030D\x1b[39m"
+	if !strings.HasSuffix(s, want) {
+		t.Fatalf("unexpected placeholders: %q", s)
+	}
+
+	buf.Reset()
+	Mode{Unicode: true}.PlaceImage(out, 0x67000001, 1, 1)
+	_ = out.Flush()
+	s = buf.String()
+	if !strings.HasPrefix(s, "\x1b_Ga=p,U=1,i=1728053249,") {
+		t.Fatalf("expected virtual placement: %q", s)
+	}
+	if !strings.Contains(s, "\x1b[38;2;0;0;1m\U0010EEEE\u0305\u0305"+string(diacritics[0x67])) {
+		t.Fatalf("expected id high byte diacritic: %q", s)
+	}
+	if len(diacritics) != 297 || diacritics[len(diacritics)-1] != 0x1D244 {
+		t.Fatalf("unexpected diacritics table")
+	}
+}
+
+func TestPassthrough(t *testing.T) {
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+	Mode{Passthrough: PassthroughTmux}.DeleteImage(out, 3)
+	_ = out.Flush()
+	if got := buf.String(); got != "\x1bPtmux;\x1b\x1b_Ga=d,d=I,i=3,q=2\x1b\x1b\\\x1b\\" {
+		t.Fatalf("unexpected tmux passthrough: %q", got)
+	}
+
+	buf.Reset()
+	Mode{Passthrough: PassthroughScreen}.DeleteAll(out)
+	_ = out.Flush()
+	if got := buf.String(); got != "\x1bP\x1b\x1b\\\x1bP_Ga=d\x1b\x1b\\\x1bP\\\x1b\\" {
+		t.Fatalf("unexpected screen passthrough: %q", got)
+	}
+
+	long := PassthroughScreen.wrap("\x1b_G" + strings.Repeat("A", 2000) + "\x1b\\")
+	if n := strings.Count(long, "\x1bP"); n != 5 {
+		t.Fatalf("expected long command split into 5 pieces, got %d", n)
+	}
+}
+
+func TestDetectMode(t *testing.T) {
+	env := func(vals map[string]string) func(string) string {
+		return func(k string) string { return vals[k] }
+	}
+	if m := DetectMode(env(nil)); m.Unicode || m.Passthrough != PassthroughNone {
+		t.Fatalf("expected direct placement, got %+v", m)
+	}
+	if m := DetectMode(env(map[string]string{"TMUX": "/tmp/tmux-1/default,1,0"})); !m.Unicode || m.Passthrough != PassthroughTmux {
+		t.Fatalf("expected tmux placeholders, got %+v", m)
+	}
+	if m := DetectMode(env(map[string]string{"STY": "1.pts-0"})); !m.Unicode || m.Passthrough != PassthroughScreen {
+		t.Fatalf("expected screen placeholders, got %+v", m)
+	}
+	if m := DetectMode(env(map[string]string{"GIFGREP_KITTY_PLACEHOLDERS": "1"})); !m.Unicode || m.Passthrough != PassthroughNone {
+		t.Fatalf("expected forced placeholders, got %+v", m)
+	}
+	if m := DetectMode(env(map[string]string{"TMUX": "x", "GIFGREP_KITTY_PLACEHOLDERS": "0"})); m.Unicode {
+		t.Fatalf("expected placeholders off, got %+v", m)
+	}
+}
+
+func TestSendAnimationDeltas(t *testing.T) {
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+	Mode{}.SendAnimation(out, 3, []gifdecode.Frame{
+		{PNG: []byte{1, 2, 3}, Delay: 80 * time.Millisecond},
+		{PNG: []byte{4, 5, 6}, Delay: 80 * time.Millisecond, Delta: &gifdecode.Delta{
+			PNG:    []byte{7},
+			Bounds: image.Rect(5, 6, 9, 8),
+		}},
+		{PNG: []byte{8, 9, 10}, Delay: 90 * time.Millisecond},
+	}, 5, 4)
+	_ = out.Flush()
+	s := buf.String()
+	if !strings.Contains(s, "\x1b_Ga=f,f=100,i=3,m=0,q=2,z=80,x=5,y=6,c=1,X=1;Bw==\x1b\\") {
+		t.Fatalf("expected delta frame over frame 1: %q", s)
+	}
+	if !strings.Contains(s, "\x1b_Ga=f,f=100,i=3,m=0,q=2,z=90;CAkK\x1b\\") {
+		t.Fatalf("expected full frame without delta: %q", s)
+	}
+}
@@ -0,0 +1,145 @@
+package kitty
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// Medium is how image data reaches the terminal (the t= key).
+type Medium int
+
+const (
+	// MediumDirect sends base64 data inside the escape codes (t=d). It works
+	// everywhere, including over SSH.
+	MediumDirect Medium = iota
+	// MediumFile writes a temp file the terminal reads and deletes (t=t).
+	MediumFile
+	// MediumSharedMemory writes a POSIX shared memory object the terminal
+	// reads and unlinks (t=s). Linux only.
+	MediumSharedMemory
+)
+
+// tempMarker is the name fragment Kitty requires before it deletes a temp
+// file after reading it.
+const tempMarker = "tty-graphics-protocol"
+
+var (
+	errSharedMemoryUnsupported = errors.New("shared memory transmission needs /dev/shm")
+
+	shmDir = "/dev/shm"
+	goos   = runtime.GOOS
+)
+
+func (m Medium) String() string {
+	switch m {
+	case MediumDirect:
+		return "direct"
+	case MediumFile:
+		return "file"
+	case MediumSharedMemory:
+		return "shm"
+	default:
+		return "direct"
+	}
+}
+
+// ParseMedium maps a GIFGREP_KITTY_MEDIUM value to a Medium.
+func ParseMedium(s string) (Medium, bool) {
+	switch s {
+	case "direct", "d":
+		return MediumDirect, true
+	case "file", "t":
+		return MediumFile, true
+	case "shm", "s":
+		return MediumSharedMemory, true
+	default:
+		return MediumDirect, false
+	}
+}
+
+func (m Medium) key() string {
+	switch m {
+	case MediumFile:
+		return "t"
+	case MediumSharedMemory:
+		return "s"
+	case MediumDirect:
+		return "d"
+	default:
+		return "d"
+	}
+}
+
+// stage writes data where the terminal can read it and returns the base64
+// payload naming it. The terminal removes the file or object once read.
+func (m Medium) stage(data []byte) (string, error) {
+	switch m {
+	case MediumFile:
+		f, err := os.CreateTemp("", "gifgrep-"+tempMarker+"-*.png")
+		if err != nil {
+			return "", err
+		}
+		if _, err := f.Write(data); err != nil {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+			return "", err
+		}
+		if err := f.Close(); err != nil {
+			_ = os.Remove(f.Name())
+			return "", err
+		}
+		return base64.StdEncoding.EncodeToString([]byte(f.Name())), nil
+	case MediumSharedMemory:
+		if goos != "linux" {
+			return "", errSharedMemoryUnsupported
+		}
+		var id [8]byte
+		if _, err := rand.Read(id[:]); err != nil {
+			return "", err
+		}
+		name := "gifgrep-" + tempMarker + "-" + hex.EncodeToString(id[:])
+		// On Linux, shm_open("/name") is the file /dev/shm/name.
+		if err := os.WriteFile(filepath.Join(shmDir, name), data, 0o600); err != nil {
+			return "", err
+		}
+		return base64.StdEncoding.EncodeToString([]byte("/" + name)), nil
+	case MediumDirect:
+		return base64.StdEncoding.EncodeToString(data), nil
+	default:
+		return base64.StdEncoding.EncodeToString(data), nil
+	}
+}
+
+// unstage removes a staged file or object the terminal did not consume.
+func (m Medium) unstage(payload string) {
+	raw, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return
+	}
+	switch m {
+	case MediumFile:
+		_ = os.Remove(string(raw))
+	case MediumSharedMemory:
+		_ = os.Remove(filepath.Join(shmDir, filepath.Base(string(raw))))
+	case MediumDirect:
+	}
+}
+
+// Query builds a graphics query asking the terminal to load a 1x1 RGB pixel
+// through medium, ready to write (wrapped for the multiplexer). The terminal
+// answers `ESC _G i=<id> ; OK ESC \` only if it could read the data, which
+// fails when it runs on another machine. Call cleanup once the answer is in.
+func (m Mode) Query(medium Medium, id uint32) (query string, cleanup func(), err error) {
+	payload, err := medium.stage([]byte{0, 0, 0})
+	if err != nil {
+		return "", func() {}, err
+	}
+	seq := fmt.Sprintf("\x1b_Gi=%d,s=1,v=1,a=q,t=%s,f=24,S=3;%s\x1b\\", id, medium.key(), payload)
+	return m.Passthrough.wrap(seq), func() { medium.unstage(payload) }, nil
+}
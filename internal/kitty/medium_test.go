@@ -0,0 +1,86 @@
+package kitty
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestSendFrameViaTempFile(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+	Mode{Medium: MediumFile}.sendData(out, kittyData{Action: "T", ID: 4, Data: []byte("png-bytes")})
+	_ = out.Flush()
+
+	m := regexp.MustCompile(`^\x1b_G(a=T,f=100,i=4,m=0,q=2,t=t,S=9);([A-Za-z0-9+/=]+)\x1b\\$`).FindStringSubmatch(buf.String())
+	if m == nil {
+		t.Fatalf("unexpected file transmission: %q", buf.String())
+	}
+	path, err := base64.StdEncoding.DecodeString(m[2])
+	if err != nil {
+		t.Fatalf("decode path failed: %v", err)
+	}
+	if !strings.Contains(string(path), tempMarker) {
+		t.Fatalf("temp file must be named for the terminal to delete it: %s", path)
+	}
+	data, err := os.ReadFile(string(path))
+	if err != nil || string(data) != "png-bytes" {
+		t.Fatalf("unexpected temp file contents: %q, %v", data, err)
+	}
+}
+
+func TestSharedMemoryStaging(t *testing.T) {
+	prevDir, prevOS := shmDir, goos
+	t.Cleanup(func() { shmDir, goos = prevDir, prevOS })
+	shmDir = t.TempDir()
+
+	goos = "darwin"
+	if _, err := MediumSharedMemory.stage([]byte{1}); err == nil {
+		t.Fatalf("expected shared memory to be unsupported off linux")
+	}
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+	Mode{Medium: MediumSharedMemory}.sendData(out, kittyData{Action: "T", ID: 4, Data: []byte{1, 2, 3}})
+	_ = out.Flush()
+	if strings.Contains(buf.String(), "t=s") || !strings.Contains(buf.String(), ";AQID\x1b\\") {
+		t.Fatalf("expected direct fallback, got %q", buf.String())
+	}
+
+	goos = "linux"
+	query, cleanup, err := Mode{Passthrough: PassthroughTmux}.Query(MediumSharedMemory, 32)
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if !strings.HasPrefix(query, "\x1bPtmux;\x1b\x1b_Gi=32,s=1,v=1,a=q,t=s,f=24,S=3;") {
+		t.Fatalf("unexpected query: %q", query)
+	}
+	entries, _ := os.ReadDir(shmDir)
+	if len(entries) != 1 {
+		t.Fatalf("expected one shm object, got %d", len(entries))
+	}
+	data, err := os.ReadFile(filepath.Join(shmDir, entries[0].Name()))
+	if err != nil || !bytes.Equal(data, []byte{0, 0, 0}) {
+		t.Fatalf("unexpected shm contents: %v, %v", data, err)
+	}
+	cleanup()
+	if entries, _ := os.ReadDir(shmDir); len(entries) != 0 {
+		t.Fatalf("expected cleanup to unlink the object")
+	}
+}
+
+func TestParseMedium(t *testing.T) {
+	for in, want := range map[string]Medium{"direct": MediumDirect, "file": MediumFile, "shm": MediumSharedMemory, "s": MediumSharedMemory} {
+		if got, ok := ParseMedium(in); !ok || got != want {
+			t.Fatalf("ParseMedium(%q)=%s,%v", in, got, ok)
+		}
+	}
+	if _, ok := ParseMedium("bogus"); ok {
+		t.Fatalf("expected unknown medium")
+	}
+}
@@ -0,0 +1,91 @@
+package kitty
+
+import (
+	"os"
+	"strings"
+)
+
+type Passthrough int
+
+const (
+	PassthroughNone Passthrough = iota
+	// PassthroughTmux wraps commands in tmux's DCS passthrough. tmux 3.3+
+	// also needs `set -g allow-passthrough on`.
+	PassthroughTmux
+	// PassthroughScreen wraps commands in GNU screen's DCS passthrough.
+	PassthroughScreen
+)
+
+// screenChunk stays below GNU screen's string buffer (768 bytes).
+const screenChunk = 760
+
+func (p Passthrough) String() string {
+	switch p {
+	case PassthroughNone:
+		return "none"
+	case PassthroughTmux:
+		return "tmux"
+	case PassthroughScreen:
+		return "screen"
+	default:
+		return "none"
+	}
+}
+
+// wrap returns seq ready to write through the multiplexer.
+func (p Passthrough) wrap(seq string) string {
+	switch p {
+	case PassthroughTmux:
+		// Every ESC inside the payload is doubled.
+		return "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+	case PassthroughScreen:
+		// screen ends the DCS at the first ESC \ and drops long strings, so
+		// split after every ESC and into short pieces; the outer terminal
+		// sees the pieces joined back together.
+		var sb strings.Builder
+		for len(seq) > 0 {
+			n := len(seq)
+			if n > screenChunk {
+				n = screenChunk
+			}
+			if i := strings.IndexByte(seq[:n], 0x1b); i >= 0 {
+				n = i + 1
+			}
+			sb.WriteString("\x1bP")
+			sb.WriteString(seq[:n])
+			sb.WriteString("\x1b\\")
+			seq = seq[n:]
+		}
+		return sb.String()
+	case PassthroughNone:
+		return seq
+	default:
+		return seq
+	}
+}
+
+// DetectMode picks the placement mode for the current session: inside tmux
+// or screen, commands are passed through and images are shown with Unicode
+// placeholders, since direct placements don't follow the multiplexer's
+// panes and redraws. GIFGREP_KITTY_PLACEHOLDERS=1 (or 0) overrides the
+// placeholder choice.
+func DetectMode(getenv func(string) string) Mode {
+	if getenv == nil {
+		getenv = os.Getenv
+	}
+	var m Mode
+	switch {
+	case strings.TrimSpace(getenv("TMUX")) != "":
+		m.Passthrough = PassthroughTmux
+	case strings.TrimSpace(getenv("STY")) != "":
+		m.Passthrough = PassthroughScreen
+	}
+	m.Unicode = m.Passthrough != PassthroughNone
+	switch strings.ToLower(strings.TrimSpace(getenv("GIFGREP_KITTY_PLACEHOLDERS"))) {
+	case "1", "true", "yes", "on":
+		m.Unicode = true
+	case "0", "false", "no", "off":
+		m.Unicode = false
+	}
+	return m
+}
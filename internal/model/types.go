@@ -24,6 +24,7 @@ type Options struct {
 	Quiet    bool
 	Reveal   bool
 	Download bool
+	Copy     bool
 	Format   string
 	Thumbs   string
 
@@ -0,0 +1,154 @@
+package sixel
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+
+	"github.com/steipete/gifgrep/gifdecode"
+)
+
+const defaultColors = 256
+
+type Options struct {
+	// Colors caps the palette size (2-256).
+	Colors int
+	Dither bool
+}
+
+// Image is an encoded sixel sequence, ready to write at the cursor.
+type Image struct {
+	Data   []byte
+	Width  int
+	Height int
+	// Transparent is set when some pixels are left undrawn, so whatever was
+	// on screen before shows through.
+	Transparent bool
+}
+
+// EncodePNG decodes a PNG frame (as produced by gifdecode) and encodes it.
+func EncodePNG(data []byte, opts Options) (*Image, error) {
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return Encode(img, opts), nil
+}
+
+// Encode quantizes img and writes it as a DCS sixel sequence. Pixels are
+// emitted in six-row bands, one pass per color present in the band, with
+// run-length encoding for repeated columns.
+func Encode(img image.Image, opts Options) *Image {
+	if opts.Colors <= 0 {
+		opts.Colors = defaultColors
+	}
+	pal := gifdecode.Quantize(img, opts.Colors, opts.Dither)
+	b := pal.Bounds()
+	width, height := b.Dx(), b.Dy()
+
+	transparentIdx := -1
+	for i, c := range pal.Palette {
+		if _, _, _, a := c.RGBA(); a == 0 {
+			transparentIdx = i
+		}
+	}
+
+	var buf bytes.Buffer
+	// P2=1: pixels without a sixel bit keep their current on-screen color.
+	buf.WriteString("\x1bP0;1;0q")
+	fmt.Fprintf(&buf, "\"1;1;%d;%d", width, height)
+	for i, c := range pal.Palette {
+		if i == transparentIdx {
+			continue
+		}
+		r, g, bl, _ := c.RGBA()
+		fmt.Fprintf(&buf, "#%d;2;%d;%d;%d", i, percent(r), percent(g), percent(bl))
+	}
+
+	bits := make([]byte, width)
+	used := make([]bool, len(pal.Palette))
+	for top := 0; top < height; top += 6 {
+		bandRows := minInt(6, height-top)
+		for i := range used {
+			used[i] = false
+		}
+		for y := 0; y < bandRows; y++ {
+			row := pal.Pix[pal.PixOffset(b.Min.X, b.Min.Y+top+y):]
+			for x := 0; x < width; x++ {
+				used[row[x]] = true
+			}
+		}
+
+		first := true
+		for idx, ok := range used {
+			if !ok || idx == transparentIdx {
+				continue
+			}
+			for x := range bits {
+				bits[x] = 0
+			}
+			for y := 0; y < bandRows; y++ {
+				row := pal.Pix[pal.PixOffset(b.Min.X, b.Min.Y+top+y):]
+				for x := 0; x < width; x++ {
+					if int(row[x]) == idx {
+						bits[x] |= 1 << y
+					}
+				}
+			}
+			if !first {
+				buf.WriteByte('$')
+			}
+			first = false
+			fmt.Fprintf(&buf, "#%d", idx)
+			writeRuns(&buf, bits)
+		}
+		if top+6 < height {
+			buf.WriteByte('-')
+		}
+	}
+	buf.WriteString("\x1b\\")
+
+	return &Image{
+		Data:        buf.Bytes(),
+		Width:       width,
+		Height:      height,
+		Transparent: transparentIdx >= 0,
+	}
+}
+
+// writeRuns writes one color's sixel characters for a band, trimming empty
+// trailing columns and run-length encoding repeats.
+func writeRuns(buf *bytes.Buffer, bits []byte) {
+	end := len(bits)
+	for end > 0 && bits[end-1] == 0 {
+		end--
+	}
+	for x := 0; x < end; {
+		run := 1
+		for x+run < end && bits[x+run] == bits[x] {
+			run++
+		}
+		ch := byte(0x3f + bits[x])
+		if run > 3 {
+			fmt.Fprintf(buf, "!%d%c", run, ch)
+		} else {
+			for i := 0; i < run; i++ {
+				buf.WriteByte(ch)
+			}
+		}
+		x += run
+	}
+}
+
+// percent converts a 16-bit color channel to the 0-100 range sixel uses.
+func percent(v uint32) int {
+	return int((v*100 + 0x7fff) / 0xffff)
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,163 @@
+package sixel
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestEncodeRoundTrip(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 9, 8))
+	red := color.RGBA{R: 0xff, A: 0xff}
+	blue := color.RGBA{B: 0xff, A: 0xff}
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 9; x++ {
+			c := red
+			if y == 7 || x == 4 {
+				c = blue
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	out := Encode(img, Options{Colors: 16})
+	s := string(out.Data)
+	if !strings.HasPrefix(s, "\x1bP0;1;0q\"1;1;9;8") || !strings.HasSuffix(s, "\x1b\\") {
+		t.Fatalf("unexpected framing: %q", s)
+	}
+	if out.Width != 9 || out.Height != 8 || out.Transparent {
+		t.Fatalf("unexpected image info: %+v", out)
+	}
+	if !strings.Contains(s, ";2;100;0;0") || !strings.Contains(s, ";2;0;0;100") {
+		t.Fatalf("expected red and blue palette entries: %q", s)
+	}
+	if strings.Count(s, "-") != 1 {
+		t.Fatalf("expected two bands: %q", s)
+	}
+	if !strings.Contains(s, "!4") {
+		t.Fatalf("expected run-length encoding: %q", s)
+	}
+
+	got := decodeForTest(t, out.Data, 9, 8)
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 9; x++ {
+			want := img.RGBAAt(x, y)
+			if got[y][x] != want {
+				t.Fatalf("pixel %d,%d: got %v, want %v", x, y, got[y][x], want)
+			}
+		}
+	}
+}
+
+func TestEncodeTransparency(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	img.SetRGBA(0, 0, color.RGBA{G: 0xff, A: 0xff})
+	out := Encode(img, Options{})
+	if !out.Transparent {
+		t.Fatalf("expected transparent image")
+	}
+	got := decodeForTest(t, out.Data, 3, 2)
+	if got[0][0] != (color.RGBA{G: 0xff, A: 0xff}) {
+		t.Fatalf("expected green pixel, got %v", got[0][0])
+	}
+	if got[1][2] != (color.RGBA{}) {
+		t.Fatalf("expected undrawn pixel, got %v", got[1][2])
+	}
+}
+
+func TestEncodePNG(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("png encode failed: %v", err)
+	}
+	out, err := EncodePNG(buf.Bytes(), Options{})
+	if err != nil {
+		t.Fatalf("EncodePNG failed: %v", err)
+	}
+	if out.Width != 2 || out.Height != 2 {
+		t.Fatalf("unexpected size %dx%d", out.Width, out.Height)
+	}
+	if _, err := EncodePNG([]byte("nope"), Options{}); err == nil {
+		t.Fatalf("expected error for bad png")
+	}
+}
+
+// decodeForTest paints a sixel sequence onto a w x h grid; undrawn pixels
+// stay zero.
+func decodeForTest(t *testing.T, data []byte, w, h int) [][]color.RGBA {
+	t.Helper()
+	grid := make([][]color.RGBA, h)
+	for i := range grid {
+		grid[i] = make([]color.RGBA, w)
+	}
+	s := string(data)
+	start := strings.IndexByte(s, 'q')
+	s = strings.TrimSuffix(s[start+1:], "\x1b\\")
+	if strings.HasPrefix(s, "\"") {
+		i := strings.IndexByte(s, '#')
+		s = s[i:]
+	}
+
+	pal := map[int]color.RGBA{}
+	cur := 0
+	x, band := 0, 0
+	readNum := func() int {
+		n := 0
+		for len(s) > 0 && s[0] >= '0' && s[0] <= '9' {
+			n = n*10 + int(s[0]-'0')
+			s = s[1:]
+		}
+		return n
+	}
+	paint := func(ch byte, count int) {
+		bits := ch - 0x3f
+		for i := 0; i < count; i++ {
+			for y := 0; y < 6; y++ {
+				if bits&(1<<y) != 0 && band*6+y < h && x < w {
+					grid[band*6+y][x] = pal[cur]
+				}
+			}
+			x++
+		}
+	}
+	for len(s) > 0 {
+		switch c := s[0]; {
+		case c == '#':
+			s = s[1:]
+			idx := readNum()
+			if strings.HasPrefix(s, ";2;") {
+				s = s[3:]
+				parts := make([]int, 3)
+				for i := range parts {
+					parts[i] = readNum()
+					s = strings.TrimPrefix(s, ";")
+				}
+				scale := func(v int) uint8 { return uint8((v*255 + 50) / 100) }
+				pal[idx] = color.RGBA{R: scale(parts[0]), G: scale(parts[1]), B: scale(parts[2]), A: 0xff}
+			}
+			cur = idx
+		case c == '!':
+			s = s[1:]
+			n := readNum()
+			paint(s[0], n)
+			s = s[1:]
+		case c == '$':
+			x = 0
+			s = s[1:]
+		case c == '-':
+			x = 0
+			band++
+			s = s[1:]
+		case c >= 0x3f && c <= 0x7e:
+			paint(c, 1)
+			s = s[1:]
+		default:
+			t.Fatalf("unexpected byte %q at %s", c, strconv.Quote(s))
+		}
+	}
+	return grid
+}
@@ -5,6 +5,7 @@ import (
 	"image"
 	"image/color"
 	"image/draw"
+	"image/gif"
 	"image/png"
 	"testing"
 	"time"
@@ -85,6 +86,57 @@ func TestFrameIndexAtBounds(t *testing.T) {
 	}
 }
 
+func TestFrameIndexAtMergedDuplicates(t *testing.T) {
+	pal := color.Palette{color.Black, color.White}
+	g := &gif.GIF{
+		Delay:  []int{10, 10, 10, 20, 5},
+		Config: image.Config{Width: 2, Height: 2, ColorModel: pal},
+	}
+	for _, lit := range []int{0, 0, 0, 1, 1} {
+		frame := image.NewPaletted(image.Rect(0, 0, 2, 2), pal)
+		frame.SetColorIndex(lit, lit, 1)
+		g.Image = append(g.Image, frame)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		t.Fatalf("gif encode failed: %v", err)
+	}
+
+	plain, err := gifdecode.Decode(buf.Bytes(), gifdecode.DefaultOptions())
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	opts := gifdecode.DefaultOptions()
+	opts.MergeDuplicates = true
+	merged, err := gifdecode.Decode(buf.Bytes(), opts)
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if len(merged.Frames) != 2 {
+		t.Fatalf("expected 2 merged frames, got %d", len(merged.Frames))
+	}
+
+	for at := time.Duration(0); at < 600*time.Millisecond; at += 25 * time.Millisecond {
+		want, _, err := FrameAtPNG(plain, at)
+		if err != nil {
+			t.Fatalf("FrameAtPNG failed: %v", err)
+		}
+		got, _, err := FrameAtPNG(merged, at)
+		if err != nil {
+			t.Fatalf("FrameAtPNG failed: %v", err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("frame at %v differs after merging", at)
+		}
+	}
+	if idx, _ := FrameIndexAt(merged.Frames, 299*time.Millisecond); idx != 0 {
+		t.Fatalf("expected merged frame 0 at 299ms, got %d", idx)
+	}
+	if idx, _ := FrameIndexAt(merged.Frames, 300*time.Millisecond); idx != 1 {
+		t.Fatalf("expected frame 1 at 300ms, got %d", idx)
+	}
+}
+
 func TestFrameIndexAtErrors(t *testing.T) {
 	if _, err := FrameIndexAt(nil, 0); err == nil {
 		t.Fatalf("expected error on empty frames")
@@ -0,0 +1,235 @@
+package termcaps
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/steipete/gifgrep/internal/kitty"
+)
+
+// CapsVersion is the version of the Caps document. Documents with another
+// version are ignored when loaded.
+const CapsVersion = 1
+
+// Caps is a terminal capability report. cmd/termcaps-check writes it;
+// pointing GIFGREP_CAPS at the file makes gifgrep use it instead of probing
+// the terminal at startup.
+type Caps struct {
+	Version int `json:"version"`
+	// Inline is the protocol gifgrep would use (InlineProtocol.String).
+	Inline        string `json:"inline"`
+	KittyGraphics bool   `json:"kitty_graphics"`
+	// KittyPlaceholdersConfigured reports whether gifgrep places Kitty
+	// images with Unicode placeholders (inside tmux/screen, or
+	// GIFGREP_KITTY_PLACEHOLDERS). Terminals don't answer queries about
+	// placeholder support, so this is configuration, not a probe result.
+	KittyPlaceholdersConfigured bool      `json:"kitty_placeholders_configured"`
+	KittyMedium                 string    `json:"kitty_medium,omitempty"`
+	Sixel                       bool      `json:"sixel"`
+	Color                       string    `json:"color"`
+	TrueColor                   bool      `json:"truecolor"`
+	Cell                        *CellSize `json:"cell,omitempty"`
+	// SyncOutput is DEC private mode 2026 (synchronized output).
+	SyncOutput bool `json:"sync_output"`
+	// KittyKeyboard is the Kitty keyboard protocol (CSI > flags u).
+	KittyKeyboard bool `json:"kitty_keyboard"`
+	// Clipboard is OSC 52 clipboard writes.
+	Clipboard bool `json:"osc52_clipboard"`
+	// Passthrough is the multiplexer graphics go through (none|tmux|screen);
+	// TmuxPassthrough reports whether tmux's allow-passthrough is on.
+	Passthrough     string            `json:"passthrough"`
+	TmuxPassthrough bool              `json:"tmux_passthrough"`
+	Env             map[string]string `json:"env,omitempty"`
+}
+
+// capsEnvKeys are recorded in Caps.Env to show what detection saw.
+var capsEnvKeys = []string{"TERM", "TERM_PROGRAM", "COLORTERM", "ITERM_SESSION_ID", "KITTY_WINDOW_ID", "TMUX", "STY"}
+
+// LoadCaps reads a Caps document written by termcaps-check.
+func LoadCaps(path string) (Caps, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Caps{}, err
+	}
+	var caps Caps
+	if err := json.Unmarshal(data, &caps); err != nil {
+		return Caps{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if caps.Version != CapsVersion {
+		return Caps{}, fmt.Errorf("%s: unsupported caps version %d (want %d)", path, caps.Version, CapsVersion)
+	}
+	return caps, nil
+}
+
+// loadedCaps caches the GIFGREP_CAPS document by path, so the Detect
+// functions read and parse it once.
+var loadedCaps struct {
+	sync.Mutex
+	path string
+	caps Caps
+	ok   bool
+}
+
+// capsFromEnv loads the document named by GIFGREP_CAPS; a missing or bad
+// document means probing as usual.
+func capsFromEnv(getenv func(string) string) (Caps, bool) {
+	if getenv == nil {
+		getenv = os.Getenv
+	}
+	path := strings.TrimSpace(getenv("GIFGREP_CAPS"))
+	if path == "" {
+		return Caps{}, false
+	}
+	loadedCaps.Lock()
+	defer loadedCaps.Unlock()
+	if loadedCaps.path != path {
+		caps, err := LoadCaps(path)
+		loadedCaps.path, loadedCaps.caps, loadedCaps.ok = path, caps, err == nil
+	}
+	return loadedCaps.caps, loadedCaps.ok
+}
+
+// ProbeCaps probes the terminal on fd for everything in Caps. Unlike the
+// Detect functions it ignores GIFGREP_CAPS; explicit overrides such as
+// GIFGREP_INLINE still apply.
+func ProbeCaps(getenv func(string) string, fd int) Caps {
+	if getenv == nil {
+		getenv = os.Getenv
+	}
+	var da1 []int
+	da1Done := false
+	mode := kitty.DetectMode(getenv)
+	return probeCaps(getenv, capsProbes{
+		kitty: func() kittyProbeResult {
+			return withRawTTY(kittyProbeUnknown, func(tty *os.File) kittyProbeResult {
+				return probeKittyGraphics(tty, 150*time.Millisecond)
+			})
+		},
+		da1: func() []int {
+			if !da1Done {
+				da1Done = true
+				da1 = withRawTTY(nil, func(tty *os.File) []int {
+					return probeDA1(tty, 150*time.Millisecond)
+				})
+			}
+			return da1
+		},
+		medium: func(medium kitty.Medium) bool {
+			query, cleanup, err := mode.Query(medium, kittyMediumProbeID)
+			if err != nil {
+				return false
+			}
+			defer cleanup()
+			return withRawTTY(false, func(tty *os.File) bool {
+				return probeKittyMedium(tty, query, 150*time.Millisecond)
+			})
+		},
+		cell: func() CellSize {
+			return measureCellSize(fd)
+		},
+		syncOutput: func() bool {
+			return withRawTTY(false, func(tty *os.File) bool {
+				return probeSyncOutput(tty, 150*time.Millisecond)
+			})
+		},
+		kittyKeyboard: func() bool {
+			return withRawTTY(false, func(tty *os.File) bool {
+				return probeKittyKeyboard(tty, 150*time.Millisecond)
+			})
+		},
+		tmuxPassthrough: tmuxAllowsPassthrough,
+	})
+}
+
+type capsProbes struct {
+	kitty           func() kittyProbeResult
+	da1             func() []int
+	medium          func(kitty.Medium) bool
+	cell            func() CellSize
+	syncOutput      func() bool
+	kittyKeyboard   func() bool
+	tmuxPassthrough func() bool
+}
+
+func probeCaps(getenv func(string) string, p capsProbes) Caps {
+	caps := Caps{Version: CapsVersion, Env: map[string]string{}}
+	for _, key := range capsEnvKeys {
+		if v := getenv(key); v != "" {
+			caps.Env[key] = v
+		}
+	}
+
+	kittyResult := kittyProbeUnknown
+	kittyProbed := false
+	probeKitty := func() kittyProbeResult {
+		if !kittyProbed {
+			kittyProbed = true
+			kittyResult = p.kitty()
+		}
+		return kittyResult
+	}
+	probeSixel := func() bool { return hasAttribute(p.da1(), 4) }
+
+	inline := detectInlineRobust(getenv, probeKitty, probeSixel)
+	caps.Inline = inline.String()
+	caps.KittyGraphics = inline == InlineKitty || probeKitty() == kittyProbeSupported
+	caps.Sixel = inline == InlineSixel || probeSixel()
+
+	mode := kitty.DetectMode(getenv)
+	caps.KittyPlaceholdersConfigured = mode.Unicode
+	caps.Passthrough = mode.Passthrough.String()
+	if mode.Passthrough == kitty.PassthroughTmux {
+		caps.TmuxPassthrough = p.tmuxPassthrough()
+	}
+	if caps.KittyGraphics {
+		caps.KittyMedium = detectKittyMedium(getenv, mode, p.medium).String()
+	}
+
+	depth := DetectColorDepth(getenv)
+	caps.Color = depth.String()
+	caps.TrueColor = depth == ColorTrue
+
+	if cell := p.cell(); cell.Valid() {
+		caps.Cell = &cell
+	}
+	caps.SyncOutput = p.syncOutput()
+	caps.KittyKeyboard = p.kittyKeyboard()
+	caps.Clipboard = hasAttribute(p.da1(), 52) || knownClipboardTerminal(getenv)
+	return caps
+}
+
+// knownClipboardTerminal covers terminals that accept OSC 52 writes but
+// don't advertise it in DA1 (only xterm lists attribute 52).
+func knownClipboardTerminal(getenv func(string) string) bool {
+	termProgram := strings.ToLower(getenv("TERM_PROGRAM"))
+	termEnv := strings.ToLower(getenv("TERM"))
+	for _, name := range []string{"iterm", "wezterm", "ghostty", "kitty", "alacritty", "foot", "contour"} {
+		if strings.Contains(termProgram, name) || strings.Contains(termEnv, name) {
+			return true
+		}
+	}
+	return strings.TrimSpace(getenv("KITTY_WINDOW_ID")) != "" || strings.TrimSpace(getenv("ITERM_SESSION_ID")) != ""
+}
+
+// tmuxAllowsPassthrough reports whether tmux forwards DCS passthrough
+// (allow-passthrough on or all; tmux before 3.3 has no such option and
+// always forwards).
+func tmuxAllowsPassthrough() bool {
+	out, err := exec.Command("tmux", "show-options", "-gv", "allow-passthrough").Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		return errors.As(err, &exitErr) && strings.Contains(string(exitErr.Stderr), "invalid option")
+	}
+	switch strings.TrimSpace(string(out)) {
+	case "on", "all":
+		return true
+	default:
+		return false
+	}
+}
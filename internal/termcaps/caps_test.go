@@ -0,0 +1,165 @@
+package termcaps
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/steipete/gifgrep/internal/kitty"
+)
+
+func fakeCapsProbes() capsProbes {
+	return capsProbes{
+		kitty:           func() kittyProbeResult { return kittyProbeSupported },
+		da1:             func() []int { return []int{62, 4, 22, 52} },
+		medium:          func(m kitty.Medium) bool { return m == kitty.MediumFile },
+		cell:            func() CellSize { return CellSize{Width: 9, Height: 20} },
+		syncOutput:      func() bool { return true },
+		kittyKeyboard:   func() bool { return true },
+		tmuxPassthrough: func() bool { return true },
+	}
+}
+
+func TestProbeCaps(t *testing.T) {
+	env := map[string]string{
+		"TERM":      "xterm-kitty",
+		"COLORTERM": "truecolor",
+		"TMUX":      "/tmp/tmux-1000/default,1,0",
+	}
+	caps := probeCaps(func(k string) string { return env[k] }, fakeCapsProbes())
+
+	if caps.Version != CapsVersion || caps.Inline != "kitty" || !caps.KittyGraphics || !caps.Sixel {
+		t.Fatalf("unexpected graphics caps: %+v", caps)
+	}
+	// tmux: placeholders and passthrough; the medium is direct through a multiplexer.
+	if !caps.KittyPlaceholdersConfigured || caps.Passthrough != "tmux" || !caps.TmuxPassthrough || caps.KittyMedium != "direct" {
+		t.Fatalf("unexpected kitty caps: %+v", caps)
+	}
+	if caps.Color != "truecolor" || !caps.TrueColor {
+		t.Fatalf("unexpected color caps: %+v", caps)
+	}
+	if caps.Cell == nil || *caps.Cell != (CellSize{Width: 9, Height: 20}) {
+		t.Fatalf("unexpected cell: %+v", caps.Cell)
+	}
+	if !caps.SyncOutput || !caps.Clipboard || !caps.KittyKeyboard {
+		t.Fatalf("expected sync output, clipboard and kitty keyboard: %+v", caps)
+	}
+	if caps.Env["TERM"] != "xterm-kitty" {
+		t.Fatalf("expected env snapshot, got %v", caps.Env)
+	}
+}
+
+func TestProbeCapsPlainTerminal(t *testing.T) {
+	probes := capsProbes{
+		kitty:           func() kittyProbeResult { return kittyProbeNotSupported },
+		da1:             func() []int { return []int{62, 22} },
+		medium:          func(kitty.Medium) bool { t.Fatalf("unexpected medium probe"); return false },
+		cell:            func() CellSize { return CellSize{} },
+		syncOutput:      func() bool { return false },
+		kittyKeyboard:   func() bool { return false },
+		tmuxPassthrough: func() bool { t.Fatalf("unexpected tmux probe"); return false },
+	}
+	env := map[string]string{"TERM": "xterm-256color"}
+	caps := probeCaps(func(k string) string { return env[k] }, probes)
+	if caps.Inline != "none" || caps.KittyGraphics || caps.Sixel || caps.KittyMedium != "" {
+		t.Fatalf("unexpected graphics caps: %+v", caps)
+	}
+	if caps.Cell != nil || caps.SyncOutput || caps.Clipboard || caps.KittyKeyboard || caps.Passthrough != "none" || caps.Color != "256" {
+		t.Fatalf("unexpected caps: %+v", caps)
+	}
+}
+
+func writeCaps(t *testing.T, caps Caps) string {
+	t.Helper()
+	data, err := json.Marshal(caps)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "caps.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	return path
+}
+
+// replyTTY stands in for the tty of a probe: it delivers each reply in a
+// separate write, as a terminal over a slow link might. The probes' queries
+// go to the read end and are dropped.
+func replyTTY(t *testing.T, replies ...string) *os.File {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("pipe: %v", err)
+	}
+	t.Cleanup(func() { _ = r.Close() })
+	go func() {
+		defer func() { _ = w.Close() }()
+		for _, reply := range replies {
+			_, _ = w.WriteString(reply)
+			time.Sleep(20 * time.Millisecond)
+		}
+	}()
+	return r
+}
+
+// assertDrained fails when the probe left bytes on tty for the next one.
+func assertDrained(t *testing.T, tty *os.File) {
+	t.Helper()
+	time.Sleep(30 * time.Millisecond)
+	_ = tty.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	var buf [64]byte
+	if n, _ := tty.Read(buf[:]); n > 0 {
+		t.Fatalf("expected the replies read, %q left over", buf[:n])
+	}
+}
+
+func TestLoadCaps(t *testing.T) {
+	want := probeCaps(func(string) string { return "" }, fakeCapsProbes())
+	got, err := LoadCaps(writeCaps(t, want))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if got.Inline != want.Inline || got.KittyMedium != want.KittyMedium || *got.Cell != *want.Cell || got.SyncOutput != want.SyncOutput {
+		t.Fatalf("round trip mismatch: %+v vs %+v", got, want)
+	}
+
+	want.Version = CapsVersion + 1
+	if _, err := LoadCaps(writeCaps(t, want)); err == nil {
+		t.Fatalf("expected version mismatch error")
+	}
+	if _, err := LoadCaps(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Fatalf("expected error for missing file")
+	}
+}
+
+func TestDetectUsesCapsDocument(t *testing.T) {
+	path := writeCaps(t, Caps{Version: CapsVersion, Inline: "sixel", KittyMedium: "file"})
+	env := map[string]string{"GIFGREP_CAPS": path}
+	getenv := func(k string) string { return env[k] }
+
+	if got := DetectInlineRobust(getenv); got != InlineSixel {
+		t.Fatalf("expected sixel from caps, got %v", got)
+	}
+	if got := DetectKittyMedium(getenv, kitty.Mode{}); got != kitty.MediumFile {
+		t.Fatalf("expected file medium from caps, got %v", got)
+	}
+
+	// The document is read once per path.
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if caps, ok := capsFromEnv(getenv); !ok || caps.Inline != "sixel" {
+		t.Fatalf("expected the cached document, got %+v %v", caps, ok)
+	}
+
+	env["GIFGREP_INLINE"] = "text"
+	env["GIFGREP_KITTY_MEDIUM"] = "direct"
+	if got := DetectInlineRobust(getenv); got != InlineText {
+		t.Fatalf("expected GIFGREP_INLINE to win over caps, got %v", got)
+	}
+	if got := DetectKittyMedium(getenv, kitty.Mode{}); got != kitty.MediumDirect {
+		t.Fatalf("expected GIFGREP_KITTY_MEDIUM to win over caps, got %v", got)
+	}
+}
@@ -0,0 +1,172 @@
+package termcaps
+
+import (
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// Fallback cell size in pixels for terminals that draw images 1:1 (sixel)
+// when the real size is unknown. Kept on the small side so images sized from
+// it stay inside their cell box.
+const (
+	DefaultCellWidth  = 8
+	DefaultCellHeight = 16
+)
+
+// CellSize is the size of one terminal cell in pixels.
+type CellSize struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// Valid reports whether the size was measured and looks plausible.
+func (c CellSize) Valid() bool {
+	return c.Width >= 2 && c.Height >= 4 && c.Width <= 256 && c.Height <= 512
+}
+
+// Aspect returns width/height of a cell, or that of the default cell when
+// the size is unknown.
+func (c CellSize) Aspect() float64 {
+	if !c.Valid() {
+		return float64(DefaultCellWidth) / float64(DefaultCellHeight)
+	}
+	return float64(c.Width) / float64(c.Height)
+}
+
+// winsize mirrors TIOCGWINSZ; XPixel/YPixel are 0 when the terminal
+// doesn't fill them in.
+type winsize struct {
+	Cols, Rows     int
+	XPixel, YPixel int
+}
+
+var (
+	cellSizeOnce   sync.Once
+	cellSizeCached CellSize
+)
+
+// DetectCellSize measures the cell size of the terminal on fd. It asks the
+// kernel first (TIOCGWINSZ pixel fields), then the terminal itself
+// (CSI 16t for the cell, CSI 14t for the text area), unless a GIFGREP_CAPS
+// document already has it. The first result is cached for the rest of the
+// process; a zero CellSize means unknown.
+func DetectCellSize(fd int) CellSize {
+	cellSizeOnce.Do(func() {
+		if caps, ok := capsFromEnv(os.Getenv); ok && caps.Cell != nil && caps.Cell.Valid() {
+			cellSizeCached = *caps.Cell
+			return
+		}
+		if !term.IsTerminal(fd) {
+			return
+		}
+		cellSizeCached = measureCellSize(fd)
+	})
+	return cellSizeCached
+}
+
+func measureCellSize(fd int) CellSize {
+	ws, _ := windowSize(fd)
+	return detectCellSize(ws, func() []byte {
+		return withRawTTY(nil, func(tty *os.File) []byte {
+			return probeCellSize(tty, 150*time.Millisecond)
+		})
+	})
+}
+
+func detectCellSize(ws winsize, probe func() []byte) CellSize {
+	if ws.Cols > 0 && ws.Rows > 0 && ws.XPixel > 0 && ws.YPixel > 0 {
+		if c := (CellSize{Width: ws.XPixel / ws.Cols, Height: ws.YPixel / ws.Rows}); c.Valid() {
+			return c
+		}
+	}
+	if probe == nil {
+		return CellSize{}
+	}
+	return parseCellSizeReply(probe(), ws.Cols, ws.Rows)
+}
+
+// probeCellSize sends CSI 16t and CSI 14t followed by DA1, and returns what
+// the terminal answered up to the DA1 reply.
+func probeCellSize(tty *os.File, timeout time.Duration) []byte {
+	if tty == nil {
+		return nil
+	}
+	_, _ = tty.Write([]byte("\x1b[16t\x1b[14t\x1b[c"))
+
+	deadline := time.Now().Add(timeout)
+	_ = tty.SetReadDeadline(deadline)
+
+	var buf [256]byte
+	acc := make([]byte, 0, 256)
+	for time.Now().Before(deadline) {
+		n, err := tty.Read(buf[:])
+		if n > 0 {
+			acc = append(acc, buf[:n]...)
+			if hasDA1Response(acc) {
+				break
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+	return acc
+}
+
+// parseCellSizeReply reads ESC [ 6 ; h ; w t (cell size) or, failing that,
+// ESC [ 4 ; h ; w t (text area size, divided by cols x rows).
+func parseCellSizeReply(b []byte, cols, rows int) CellSize {
+	var area CellSize
+	for i := 0; i+1 < len(b); i++ {
+		if b[i] != 0x1b || b[i+1] != '[' {
+			continue
+		}
+		params, ok := windowOpParams(b[i+2:])
+		if !ok || len(params) != 3 {
+			continue
+		}
+		switch params[0] {
+		case 6:
+			if c := (CellSize{Width: params[2], Height: params[1]}); c.Valid() {
+				return c
+			}
+		case 4:
+			if cols > 0 && rows > 0 {
+				area = CellSize{Width: params[2] / cols, Height: params[1] / rows}
+			}
+		}
+	}
+	if area.Valid() {
+		return area
+	}
+	return CellSize{}
+}
+
+// windowOpParams parses "n;n;n t" at the start of b.
+func windowOpParams(b []byte) ([]int, bool) {
+	var params []int
+	start := 0
+	for j := 0; j < len(b) && j < 32; j++ {
+		ch := b[j]
+		if ch >= '0' && ch <= '9' {
+			continue
+		}
+		if ch != ';' && ch != 't' {
+			return nil, false
+		}
+		n, err := strconv.Atoi(string(b[start:j]))
+		if err != nil {
+			return nil, false
+		}
+		params = append(params, n)
+		if ch == 't' {
+			return params, true
+		}
+		start = j + 1
+	}
+	return nil, false
+}
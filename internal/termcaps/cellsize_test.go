@@ -0,0 +1,47 @@
+package termcaps
+
+import "testing"
+
+func TestDetectCellSizeFromWinsize(t *testing.T) {
+	probed := false
+	got := detectCellSize(winsize{Cols: 80, Rows: 24, XPixel: 800, YPixel: 528}, func() []byte {
+		probed = true
+		return nil
+	})
+	if got != (CellSize{Width: 10, Height: 22}) {
+		t.Fatalf("unexpected cell size: %+v", got)
+	}
+	if probed {
+		t.Fatalf("expected no terminal probe when the kernel knows the pixel size")
+	}
+}
+
+func TestDetectCellSizeFromReplies(t *testing.T) {
+	cases := []struct {
+		name  string
+		reply string
+		want  CellSize
+	}{
+		{"cell", "\x1b[6;20;9t\x1b[4;480;720t\x1b[?62;4c", CellSize{Width: 9, Height: 20}},
+		{"area only", "\x1b[4;480;720t\x1b[?62c", CellSize{Width: 9, Height: 20}},
+		{"da1 only", "\x1b[?62;22c", CellSize{}},
+		{"garbage", "\x1b[6;0;0t\x1b[6;x;9t", CellSize{}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := detectCellSize(winsize{Cols: 80, Rows: 24}, func() []byte { return []byte(tc.reply) })
+			if got != tc.want {
+				t.Fatalf("got %+v want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCellSizeAspect(t *testing.T) {
+	if got := (CellSize{}).Aspect(); got != 0.5 {
+		t.Fatalf("expected default aspect 0.5, got %v", got)
+	}
+	if got := (CellSize{Width: 10, Height: 25}).Aspect(); got != 0.4 {
+		t.Fatalf("expected 0.4, got %v", got)
+	}
+}
@@ -0,0 +1,34 @@
+package termcaps
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+// DetectClipboard reports whether the terminal accepts OSC 52 clipboard
+// writes. GIFGREP_OSC52=1|0 overrides; otherwise a GIFGREP_CAPS document,
+// a known terminal, or DA1 attribute 52 decides.
+func DetectClipboard(getenv func(string) string) bool {
+	if getenv == nil {
+		getenv = os.Getenv
+	}
+	return detectClipboard(getenv, func() bool {
+		return withRawTTY(false, func(tty *os.File) bool {
+			return hasAttribute(probeDA1(tty, 150*time.Millisecond), 52)
+		})
+	})
+}
+
+func detectClipboard(getenv func(string) string, probe func() bool) bool {
+	switch strings.ToLower(strings.TrimSpace(getenv("GIFGREP_OSC52"))) {
+	case "1", "true", "yes", "on":
+		return true
+	case "0", "false", "no", "off":
+		return false
+	}
+	if caps, ok := capsFromEnv(getenv); ok {
+		return caps.Clipboard
+	}
+	return knownClipboardTerminal(getenv) || probe()
+}
@@ -0,0 +1,29 @@
+package termcaps
+
+import "testing"
+
+func TestDetectClipboard(t *testing.T) {
+	env := map[string]string{}
+	getenv := func(k string) string { return env[k] }
+	probed := 0
+	probe := func() bool { probed++; return false }
+
+	if detectClipboard(getenv, probe) || probed != 1 {
+		t.Fatalf("expected probe result")
+	}
+
+	env["TERM_PROGRAM"] = "WezTerm"
+	if !detectClipboard(getenv, probe) || probed != 1 {
+		t.Fatalf("expected known terminal without probing")
+	}
+
+	env["GIFGREP_CAPS"] = writeCaps(t, Caps{Version: CapsVersion, Clipboard: false})
+	if detectClipboard(getenv, probe) {
+		t.Fatalf("expected caps document to decide")
+	}
+
+	env["GIFGREP_OSC52"] = "1"
+	if !detectClipboard(getenv, probe) || probed != 1 {
+		t.Fatalf("expected env override")
+	}
+}
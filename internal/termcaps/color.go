@@ -0,0 +1,50 @@
+package termcaps
+
+import (
+	"os"
+	"strings"
+)
+
+type ColorDepth int
+
+const (
+	ColorNone ColorDepth = iota
+	Color256
+	ColorTrue
+)
+
+func (d ColorDepth) String() string {
+	switch d {
+	case ColorNone:
+		return "none"
+	case Color256:
+		return "256"
+	case ColorTrue:
+		return "truecolor"
+	default:
+		return "none"
+	}
+}
+
+// DetectColorDepth guesses the terminal's color support from the
+// environment: COLORTERM for 24-bit color, TERM for everything else.
+func DetectColorDepth(getenv func(string) string) ColorDepth {
+	if getenv == nil {
+		getenv = os.Getenv
+	}
+	colorTerm := strings.ToLower(strings.TrimSpace(getenv("COLORTERM")))
+	if colorTerm == "truecolor" || colorTerm == "24bit" {
+		return ColorTrue
+	}
+	termEnv := strings.ToLower(strings.TrimSpace(getenv("TERM")))
+	switch {
+	case termEnv == "" || termEnv == "dumb":
+		return ColorNone
+	case strings.Contains(termEnv, "direct") || strings.Contains(termEnv, "truecolor"):
+		return ColorTrue
+	case strings.Contains(termEnv, "kitty") || strings.Contains(termEnv, "ghostty") || strings.Contains(termEnv, "foot"):
+		return ColorTrue
+	default:
+		return Color256
+	}
+}
@@ -0,0 +1,23 @@
+package termcaps
+
+import "testing"
+
+func TestDetectColorDepth(t *testing.T) {
+	cases := []struct {
+		env  map[string]string
+		want ColorDepth
+	}{
+		{map[string]string{"COLORTERM": "truecolor", "TERM": "xterm"}, ColorTrue},
+		{map[string]string{"COLORTERM": "24bit"}, ColorTrue},
+		{map[string]string{"TERM": "xterm-256color"}, Color256},
+		{map[string]string{"TERM": "xterm-direct"}, ColorTrue},
+		{map[string]string{"TERM": "dumb"}, ColorNone},
+		{map[string]string{}, ColorNone},
+	}
+	for _, tc := range cases {
+		got := DetectColorDepth(func(k string) string { return tc.env[k] })
+		if got != tc.want {
+			t.Fatalf("env=%v: got %s want %s", tc.env, got, tc.want)
+		}
+	}
+}
@@ -12,6 +12,10 @@ const (
 	InlineNone InlineProtocol = iota
 	InlineKitty
 	InlineIterm
+	InlineSixel
+	// InlineText draws images with colored half-block characters (or ASCII
+	// art), which works in any terminal.
+	InlineText
 )
 
 func (p InlineProtocol) String() string {
@@ -22,26 +26,44 @@ func (p InlineProtocol) String() string {
 		return "kitty"
 	case InlineIterm:
 		return "iterm"
+	case InlineSixel:
+		return "sixel"
+	case InlineText:
+		return "text"
 	default:
 		return "none"
 	}
 }
 
+// ParseInline maps a GIFGREP_INLINE value (or InlineProtocol.String) to a
+// protocol; unknown values give InlineNone and false.
+func ParseInline(s string) (InlineProtocol, bool) {
+	switch s {
+	case "kitty":
+		return InlineKitty, true
+	case "iterm", "iterm2", "wezterm":
+		return InlineIterm, true
+	case "sixel":
+		return InlineSixel, true
+	case "text", "blocks", "ascii":
+		return InlineText, true
+	case "none", "off", "false", "0":
+		return InlineNone, true
+	default:
+		return InlineNone, false
+	}
+}
+
 func DetectInline(getenv func(string) string) InlineProtocol {
 	if getenv == nil {
 		getenv = os.Getenv
 	}
 
-	switch strings.ToLower(strings.TrimSpace(getenv("GIFGREP_INLINE"))) {
-	case "kitty":
-		return InlineKitty
-	case "iterm", "iterm2":
-		return InlineIterm
-	case "none", "off", "false", "0":
-		return InlineNone
+	switch forced := strings.ToLower(strings.TrimSpace(getenv("GIFGREP_INLINE"))); forced {
 	case "", "auto":
 	default:
-		return InlineNone
+		p, _ := ParseInline(forced)
+		return p
 	}
 
 	if strings.TrimSpace(getenv("KITTY_WINDOW_ID")) != "" {
@@ -52,6 +74,11 @@ func DetectInline(getenv func(string) string) InlineProtocol {
 	if strings.Contains(termProgram, "ghostty") {
 		return InlineKitty
 	}
+	// WezTerm speaks Kitty graphics, sixel and OSC 1337; its OSC 1337
+	// support is the most complete of the three.
+	if IsWezTerm(getenv) {
+		return InlineIterm
+	}
 	if strings.Contains(termProgram, "iterm") || strings.TrimSpace(getenv("ITERM_SESSION_ID")) != "" {
 		return InlineIterm
 	}
@@ -63,10 +90,24 @@ func DetectInline(getenv func(string) string) InlineProtocol {
 	if strings.Contains(termEnv, "xterm-kitty") || strings.Contains(termEnv, "ghostty") {
 		return InlineKitty
 	}
+	if strings.HasPrefix(termEnv, "foot") || strings.Contains(termEnv, "mlterm") {
+		return InlineSixel
+	}
 
 	return InlineNone
 }
 
+// IsWezTerm reports whether we run inside WezTerm.
+func IsWezTerm(getenv func(string) string) bool {
+	if getenv == nil {
+		getenv = os.Getenv
+	}
+	if strings.Contains(strings.ToLower(getenv("TERM_PROGRAM")), "wezterm") {
+		return true
+	}
+	return strings.TrimSpace(getenv("WEZTERM_PANE")) != "" || strings.TrimSpace(getenv("WEZTERM_EXECUTABLE")) != ""
+}
+
 type kittyProbeResult int
 
 const (
@@ -75,23 +116,46 @@ const (
 	kittyProbeNotSupported
 )
 
+// DetectInlineRobust is DetectInline plus terminal probes for Kitty
+// graphics and sixel. A GIFGREP_CAPS document replaces the probes unless
+// GIFGREP_INLINE forces a protocol.
 func DetectInlineRobust(getenv func(string) string) InlineProtocol {
-	return detectInlineRobust(getenv, func() kittyProbeResult {
-		tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
-		if err != nil {
-			return kittyProbeUnknown
+	if getenv == nil {
+		getenv = os.Getenv
+	}
+	if caps, ok := capsFromEnv(getenv); ok && !inlineForced(getenv) {
+		if p, ok := ParseInline(caps.Inline); ok {
+			return p
 		}
-		defer func() { _ = tty.Close() }()
-		return probeKittyGraphics(tty, 150*time.Millisecond)
+	}
+	return detectInlineRobust(getenv, func() kittyProbeResult {
+		return withRawTTY(kittyProbeUnknown, func(tty *os.File) kittyProbeResult {
+			return probeKittyGraphics(tty, 150*time.Millisecond)
+		})
+	}, func() bool {
+		return withRawTTY(false, func(tty *os.File) bool {
+			return probeSixel(tty, 150*time.Millisecond)
+		})
 	})
 }
 
-func detectInlineRobust(getenv func(string) string, probeKitty func() kittyProbeResult) InlineProtocol {
+func detectInlineRobust(getenv func(string) string, probeKitty func() kittyProbeResult, probeSixel func() bool) InlineProtocol {
 	if getenv == nil {
 		getenv = os.Getenv
 	}
 	p := DetectInline(getenv)
-	if p != InlineKitty {
+	switch p {
+	case InlineKitty:
+	case InlineNone:
+		// Sixel terminals rarely identify themselves through env vars, so ask
+		// the terminal unless the user forced a protocol.
+		if inlineForced(getenv) {
+			return InlineNone
+		}
+		return sixelOr(probeSixel, InlineNone)
+	case InlineIterm, InlineSixel, InlineText:
+		return p
+	default:
 		return p
 	}
 
@@ -103,15 +167,26 @@ func detectInlineRobust(getenv func(string) string, probeKitty func() kittyProbe
 	if strings.TrimSpace(getenv("KITTY_WINDOW_ID")) != "" {
 		return InlineKitty
 	}
-
 	switch probeKitty() {
 	case kittyProbeSupported:
 		return InlineKitty
 	case kittyProbeNotSupported:
-		return InlineNone
+		return sixelOr(probeSixel, InlineNone)
 	case kittyProbeUnknown:
 		return InlineKitty
 	default:
 		return InlineKitty
 	}
 }
+
+func inlineForced(getenv func(string) string) bool {
+	v := strings.ToLower(strings.TrimSpace(getenv("GIFGREP_INLINE")))
+	return v != "" && v != "auto"
+}
+
+func sixelOr(probeSixel func() bool, fallback InlineProtocol) InlineProtocol {
+	if probeSixel != nil && probeSixel() {
+		return InlineSixel
+	}
+	return fallback
+}
@@ -55,7 +55,7 @@ func TestDetectInlineRobustProbeNegative(t *testing.T) {
 			return ""
 		}
 	}
-	got := detectInlineRobust(getenv, func() kittyProbeResult { return kittyProbeNotSupported })
+	got := detectInlineRobust(getenv, func() kittyProbeResult { return kittyProbeNotSupported }, func() bool { return false })
 	if got != InlineNone {
 		t.Fatalf("expected none, got %v", got)
 	}
@@ -70,8 +70,119 @@ func TestDetectInlineRobustProbeUnknownKeepsKitty(t *testing.T) {
 			return ""
 		}
 	}
-	got := detectInlineRobust(getenv, func() kittyProbeResult { return kittyProbeUnknown })
+	got := detectInlineRobust(getenv, func() kittyProbeResult { return kittyProbeUnknown }, nil)
 	if got != InlineKitty {
 		t.Fatalf("expected kitty, got %v", got)
 	}
 }
+
+func TestDetectInlineSixelEnv(t *testing.T) {
+	for _, termEnv := range []string{"foot", "foot-extra", "mlterm"} {
+		getenv := func(k string) string {
+			if k == "TERM" {
+				return termEnv
+			}
+			return ""
+		}
+		if got := DetectInline(getenv); got != InlineSixel {
+			t.Fatalf("TERM=%s: expected sixel, got %v", termEnv, got)
+		}
+	}
+	override := func(k string) string {
+		if k == "GIFGREP_INLINE" {
+			return "sixel"
+		}
+		return ""
+	}
+	if got := DetectInline(override); got != InlineSixel {
+		t.Fatalf("expected sixel override, got %v", got)
+	}
+}
+
+func TestDetectInlineRobustSixelProbe(t *testing.T) {
+	xterm := func(k string) string {
+		if k == "TERM" {
+			return "xterm-256color"
+		}
+		return ""
+	}
+	noKitty := func() kittyProbeResult {
+		t.Fatalf("kitty probe should not run")
+		return kittyProbeUnknown
+	}
+	if got := detectInlineRobust(xterm, noKitty, func() bool { return true }); got != InlineSixel {
+		t.Fatalf("expected sixel from DA1 probe, got %v", got)
+	}
+	if got := detectInlineRobust(xterm, noKitty, func() bool { return false }); got != InlineNone {
+		t.Fatalf("expected none, got %v", got)
+	}
+
+	forcedNone := func(k string) string {
+		if k == "GIFGREP_INLINE" {
+			return "none"
+		}
+		return ""
+	}
+	got := detectInlineRobust(forcedNone, noKitty, func() bool {
+		t.Fatalf("sixel probe should not run when forced")
+		return true
+	})
+	if got != InlineNone {
+		t.Fatalf("expected forced none, got %v", got)
+	}
+
+	kittyTerm := func(k string) string {
+		if k == "TERM" {
+			return "xterm-kitty"
+		}
+		return ""
+	}
+	got = detectInlineRobust(kittyTerm, func() kittyProbeResult { return kittyProbeNotSupported }, func() bool { return true })
+	if got != InlineSixel {
+		t.Fatalf("expected sixel fallback after failed kitty probe, got %v", got)
+	}
+}
+
+func TestDA1Attributes(t *testing.T) {
+	cases := []struct {
+		in    string
+		attrs []int
+		ok    bool
+	}{
+		{in: "\x1b[?62;4;22c", attrs: []int{62, 4, 22}, ok: true},
+		{in: "junk\x1b[?1;2c", attrs: []int{1, 2}, ok: true},
+		{in: "\x1b[?65;1;9c", attrs: []int{65, 1, 9}, ok: true},
+		{in: "\x1b[?62;4", ok: false},
+		{in: "\x1b[62;4c", ok: false},
+	}
+	for _, tc := range cases {
+		attrs, ok := da1Attributes([]byte(tc.in))
+		if ok != tc.ok {
+			t.Fatalf("%q: expected ok=%v", tc.in, tc.ok)
+		}
+		if len(attrs) != len(tc.attrs) {
+			t.Fatalf("%q: got %v, want %v", tc.in, attrs, tc.attrs)
+		}
+		for i := range attrs {
+			if attrs[i] != tc.attrs[i] {
+				t.Fatalf("%q: got %v, want %v", tc.in, attrs, tc.attrs)
+			}
+		}
+	}
+}
+
+func TestDetectInlineWezTerm(t *testing.T) {
+	for _, env := range []map[string]string{
+		{"TERM_PROGRAM": "WezTerm", "TERM": "xterm-256color"},
+		{"WEZTERM_PANE": "0", "TERM": "wezterm"},
+		{"GIFGREP_INLINE": "wezterm"},
+	} {
+		getenv := func(k string) string { return env[k] }
+		if got := DetectInline(getenv); got != InlineIterm {
+			t.Fatalf("env=%v: expected iterm protocol, got %v", env, got)
+		}
+	}
+	if IsWezTerm(func(k string) string { return map[string]string{"TERM_PROGRAM": "iTerm.app"}[k] }) {
+		t.Fatalf("iTerm2 is not WezTerm")
+	}
+}
@@ -0,0 +1,85 @@
+package termcaps
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"time"
+)
+
+// DetectKittyKeyboard reports whether the terminal speaks the Kitty keyboard
+// protocol (progressive enhancement, CSI > flags u), which tells Esc apart
+// from the start of an escape sequence and reports modifiers unambiguously.
+// GIFGREP_KITTY_KEYBOARD=1|0 overrides; otherwise a GIFGREP_CAPS document
+// or a CSI ? u query decides.
+func DetectKittyKeyboard(getenv func(string) string) bool {
+	if getenv == nil {
+		getenv = os.Getenv
+	}
+	return detectKittyKeyboard(getenv, func() bool {
+		return withRawTTY(false, func(tty *os.File) bool {
+			return probeKittyKeyboard(tty, 150*time.Millisecond)
+		})
+	})
+}
+
+func detectKittyKeyboard(getenv func(string) string, probe func() bool) bool {
+	switch strings.ToLower(strings.TrimSpace(getenv("GIFGREP_KITTY_KEYBOARD"))) {
+	case "1", "true", "yes", "on":
+		return true
+	case "0", "false", "no", "off":
+		return false
+	}
+	if caps, ok := capsFromEnv(getenv); ok {
+		return caps.KittyKeyboard
+	}
+	return probe()
+}
+
+// probeKittyKeyboard asks for the current keyboard flags (CSI ? u) followed
+// by DA1. Terminals with the protocol answer ESC [ ? flags u first; the
+// rest only answer DA1. It reads through the DA1 reply, so the next probe
+// doesn't find it.
+func probeKittyKeyboard(tty *os.File, timeout time.Duration) bool {
+	if tty == nil {
+		return false
+	}
+	_, _ = tty.Write([]byte("\x1b[?u\x1b[c"))
+
+	deadline := time.Now().Add(timeout)
+	_ = tty.SetReadDeadline(deadline)
+
+	var buf [256]byte
+	acc := make([]byte, 0, 256)
+	for time.Now().Before(deadline) {
+		n, err := tty.Read(buf[:])
+		if n > 0 {
+			acc = append(acc, buf[:n]...)
+			if hasDA1Response(acc) {
+				break
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+	return hasKeyboardFlagsReply(acc)
+}
+
+// hasKeyboardFlagsReply finds ESC [ ? digits u in b.
+func hasKeyboardFlagsReply(b []byte) bool {
+	for {
+		i := bytes.Index(b, []byte("\x1b[?"))
+		if i < 0 {
+			return false
+		}
+		b = b[i+3:]
+		j := 0
+		for j < len(b) && b[j] >= '0' && b[j] <= '9' {
+			j++
+		}
+		if j > 0 && j < len(b) && b[j] == 'u' {
+			return true
+		}
+	}
+}
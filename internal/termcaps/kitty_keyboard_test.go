@@ -0,0 +1,50 @@
+package termcaps
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDetectKittyKeyboard(t *testing.T) {
+	env := map[string]string{}
+	getenv := func(k string) string { return env[k] }
+	probed := 0
+	probe := func() bool { probed++; return true }
+
+	if !detectKittyKeyboard(getenv, probe) || probed != 1 {
+		t.Fatalf("expected probe result")
+	}
+
+	env["GIFGREP_CAPS"] = writeCaps(t, Caps{Version: CapsVersion})
+	if detectKittyKeyboard(getenv, probe) || probed != 1 {
+		t.Fatalf("expected caps document to replace the probe")
+	}
+
+	env["GIFGREP_KITTY_KEYBOARD"] = "1"
+	if !detectKittyKeyboard(getenv, probe) || probed != 1 {
+		t.Fatalf("expected env override")
+	}
+}
+
+func TestHasKeyboardFlagsReply(t *testing.T) {
+	if !hasKeyboardFlagsReply([]byte("\x1b[?0u\x1b[?62;22c")) {
+		t.Fatalf("expected flags reply")
+	}
+	if hasKeyboardFlagsReply([]byte("\x1b[?62;22c")) {
+		t.Fatalf("DA1 alone is not a flags reply")
+	}
+	if hasKeyboardFlagsReply([]byte("\x1b[?u")) {
+		t.Fatalf("the query echoed back is not a reply")
+	}
+}
+
+func TestProbeKittyKeyboardReadsThroughDA1(t *testing.T) {
+	tty := replyTTY(t, "\x1b[?0u", "\x1b[?62;4c")
+	if !probeKittyKeyboard(tty, time.Second) {
+		t.Fatalf("expected the flags reply to mean support")
+	}
+	assertDrained(t, tty)
+	if probeKittyKeyboard(replyTTY(t, "\x1b[?62c"), time.Second) {
+		t.Fatalf("expected DA1 alone to mean no support")
+	}
+}
@@ -0,0 +1,108 @@
+package termcaps
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/steipete/gifgrep/internal/kitty"
+)
+
+// kittyMediumProbeID is echoed back in the terminal's reply to the query.
+const kittyMediumProbeID = 32
+
+// DetectKittyMedium picks how Kitty image data is transmitted. Shared memory
+// and temp files only work when the terminal runs on this machine, so each
+// is tried with a query the terminal answers OK only if it could read the
+// data; over SSH, inside a multiplexer, or without an answer, data is sent
+// directly. GIFGREP_KITTY_MEDIUM=direct|file|shm overrides the choice, and
+// a GIFGREP_CAPS document replaces the probes.
+func DetectKittyMedium(getenv func(string) string, mode kitty.Mode) kitty.Medium {
+	if getenv == nil {
+		getenv = os.Getenv
+	}
+	if caps, ok := capsFromEnv(getenv); ok && strings.TrimSpace(getenv("GIFGREP_KITTY_MEDIUM")) == "" {
+		if medium, ok := kitty.ParseMedium(caps.KittyMedium); ok {
+			return medium
+		}
+	}
+	return detectKittyMedium(getenv, mode, func(medium kitty.Medium) bool {
+		query, cleanup, err := mode.Query(medium, kittyMediumProbeID)
+		if err != nil {
+			return false
+		}
+		defer cleanup()
+		return withRawTTY(false, func(tty *os.File) bool {
+			return probeKittyMedium(tty, query, 150*time.Millisecond)
+		})
+	})
+}
+
+func detectKittyMedium(getenv func(string) string, mode kitty.Mode, probe func(kitty.Medium) bool) kitty.Medium {
+	if getenv == nil {
+		getenv = os.Getenv
+	}
+	if forced := strings.ToLower(strings.TrimSpace(getenv("GIFGREP_KITTY_MEDIUM"))); forced != "" {
+		if medium, ok := kitty.ParseMedium(forced); ok {
+			return medium
+		}
+	}
+	if isRemoteSession(getenv) || mode.Passthrough != kitty.PassthroughNone {
+		return kitty.MediumDirect
+	}
+	for _, medium := range []kitty.Medium{kitty.MediumSharedMemory, kitty.MediumFile} {
+		if probe(medium) {
+			return medium
+		}
+	}
+	return kitty.MediumDirect
+}
+
+// isRemoteSession reports whether we run over SSH, where the terminal
+// can't see our files.
+func isRemoteSession(getenv func(string) string) bool {
+	for _, key := range []string{"SSH_CONNECTION", "SSH_CLIENT", "SSH_TTY"} {
+		if strings.TrimSpace(getenv(key)) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// probeKittyMedium sends query followed by DA1 and waits for the graphics
+// reply. A DA1 reply without an OK first means the medium failed. It reads
+// through the DA1 reply either way, so the next probe doesn't find it.
+func probeKittyMedium(tty *os.File, query string, timeout time.Duration) bool {
+	if tty == nil {
+		return false
+	}
+	_, _ = tty.Write([]byte(query + "\x1b[c"))
+
+	deadline := time.Now().Add(timeout)
+	_ = tty.SetReadDeadline(deadline)
+
+	var buf [1024]byte
+	acc := make([]byte, 0, 2048)
+	for time.Now().Before(deadline) {
+		n, err := tty.Read(buf[:])
+		if n > 0 {
+			acc = append(acc, buf[:n]...)
+			if hasDA1Response(acc) {
+				break
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+	prefix := []byte(fmt.Sprintf("\x1b_Gi=%d;", kittyMediumProbeID))
+	if i := bytes.Index(acc, prefix); i >= 0 {
+		reply := acc[i+len(prefix):]
+		if end := bytes.Index(reply, []byte("\x1b\\")); end >= 0 {
+			return string(reply[:end]) == "OK"
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package termcaps
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/steipete/gifgrep/internal/kitty"
+)
+
+func TestDetectKittyMedium(t *testing.T) {
+	env := func(vals map[string]string) func(string) string {
+		return func(k string) string { return vals[k] }
+	}
+	var probed []kitty.Medium
+	probe := func(ok kitty.Medium) func(kitty.Medium) bool {
+		return func(m kitty.Medium) bool {
+			probed = append(probed, m)
+			return m == ok
+		}
+	}
+
+	if got := detectKittyMedium(env(nil), kitty.Mode{}, probe(kitty.MediumSharedMemory)); got != kitty.MediumSharedMemory {
+		t.Fatalf("expected shm, got %s", got)
+	}
+	probed = nil
+	if got := detectKittyMedium(env(nil), kitty.Mode{}, probe(kitty.MediumFile)); got != kitty.MediumFile || len(probed) != 2 {
+		t.Fatalf("expected file after shm failed, got %s (probed %v)", got, probed)
+	}
+	if got := detectKittyMedium(env(nil), kitty.Mode{}, probe(-1)); got != kitty.MediumDirect {
+		t.Fatalf("expected direct when nothing answers, got %s", got)
+	}
+
+	probed = nil
+	if got := detectKittyMedium(env(map[string]string{"SSH_CONNECTION": "1.2.3.4 5 6.7.8.9 22"}), kitty.Mode{}, probe(kitty.MediumFile)); got != kitty.MediumDirect || len(probed) != 0 {
+		t.Fatalf("expected direct without probing over ssh, got %s", got)
+	}
+	if got := detectKittyMedium(env(nil), kitty.Mode{Passthrough: kitty.PassthroughTmux}, probe(kitty.MediumFile)); got != kitty.MediumDirect || len(probed) != 0 {
+		t.Fatalf("expected direct without probing in tmux, got %s", got)
+	}
+	if got := detectKittyMedium(env(map[string]string{"GIFGREP_KITTY_MEDIUM": "file", "SSH_TTY": "/dev/pts/1"}), kitty.Mode{}, probe(-1)); got != kitty.MediumFile {
+		t.Fatalf("expected forced file, got %s", got)
+	}
+}
+
+func TestProbeKittyMediumReadsThroughDA1(t *testing.T) {
+	reply := fmt.Sprintf("\x1b_Gi=%d;OK\x1b\\", kittyMediumProbeID)
+	tty := replyTTY(t, reply, "\x1b[?62;4c")
+	if !probeKittyMedium(tty, "", time.Second) {
+		t.Fatalf("expected OK")
+	}
+	assertDrained(t, tty)
+
+	failed := fmt.Sprintf("\x1b_Gi=%d;EBADF:no such file\x1b\\", kittyMediumProbeID)
+	tty = replyTTY(t, failed, "\x1b[?62;4c")
+	if probeKittyMedium(tty, "", time.Second) {
+		t.Fatalf("expected an error reply to fail")
+	}
+	assertDrained(t, tty)
+	if probeKittyMedium(replyTTY(t, "\x1b[?62c"), "", time.Second) {
+		t.Fatalf("expected DA1 alone to fail")
+	}
+}
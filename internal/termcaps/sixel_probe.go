@@ -0,0 +1,103 @@
+package termcaps
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// probeSixel asks for primary device attributes (DA1). Terminals that can
+// draw sixel graphics list attribute 4 in the reply, e.g. ESC [ ? 62;4;22 c.
+func probeSixel(tty *os.File, timeout time.Duration) bool {
+	return hasAttribute(probeDA1(tty, timeout), 4)
+}
+
+// probeDA1 sends a DA1 query and returns the attributes of the reply, or nil
+// when the terminal doesn't answer in time.
+func probeDA1(tty *os.File, timeout time.Duration) []int {
+	if tty == nil {
+		return nil
+	}
+	_, _ = tty.Write([]byte("\x1b[c"))
+
+	deadline := time.Now().Add(timeout)
+	_ = tty.SetReadDeadline(deadline)
+
+	var buf [256]byte
+	acc := make([]byte, 0, 256)
+	for time.Now().Before(deadline) {
+		n, err := tty.Read(buf[:])
+		if n > 0 {
+			acc = append(acc, buf[:n]...)
+			if attrs, ok := da1Attributes(acc); ok {
+				return attrs
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+	return nil
+}
+
+func hasAttribute(attrs []int, want int) bool {
+	for _, attr := range attrs {
+		if attr == want {
+			return true
+		}
+	}
+	return false
+}
+
+// da1Attributes extracts the parameters of the first DA1 reply in b.
+func da1Attributes(b []byte) ([]int, bool) {
+	for i := 0; i+2 < len(b); i++ {
+		if b[i] != 0x1b || b[i+1] != '[' || b[i+2] != '?' {
+			continue
+		}
+		var attrs []int
+		start := i + 3
+		for j := start; j < len(b) && j-i < 64; j++ {
+			ch := b[j]
+			if ch >= '0' && ch <= '9' {
+				continue
+			}
+			if ch != ';' && ch != 'c' {
+				break
+			}
+			if n, err := strconv.Atoi(string(b[start:j])); err == nil {
+				attrs = append(attrs, n)
+			}
+			if ch == 'c' {
+				return attrs, true
+			}
+			start = j + 1
+		}
+	}
+	return nil, false
+}
+
+// withRawTTY runs probe against /dev/tty in raw mode; terminal replies are
+// not newline-terminated, so a cooked tty would hold them back until the
+// deadline (and echo them). fallback is returned when there is no tty.
+func withRawTTY[T any](fallback T, probe func(*os.File) T) T {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return fallback
+	}
+	defer func() { _ = tty.Close() }()
+	// tty.Fd() would switch the file to blocking mode and break the read
+	// deadlines the probes rely on, so reach the descriptor via SyscallConn.
+	fd := -1
+	if rc, err := tty.SyscallConn(); err == nil {
+		_ = rc.Control(func(raw uintptr) { fd = int(raw) })
+	}
+	if fd >= 0 {
+		if oldState, err := term.MakeRaw(fd); err == nil {
+			defer func() { _ = term.Restore(fd, oldState) }()
+		}
+	}
+	return probe(tty)
+}
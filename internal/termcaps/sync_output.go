@@ -0,0 +1,87 @@
+package termcaps
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DetectSyncOutput reports whether the terminal supports synchronized
+// output (DEC private mode 2026), which holds back drawing until a frame is
+// complete. GIFGREP_SYNC_OUTPUT=1|0 overrides; otherwise a GIFGREP_CAPS
+// document or a DECRQM query decides.
+func DetectSyncOutput(getenv func(string) string) bool {
+	if getenv == nil {
+		getenv = os.Getenv
+	}
+	return detectSyncOutput(getenv, func() bool {
+		return withRawTTY(false, func(tty *os.File) bool {
+			return probeSyncOutput(tty, 150*time.Millisecond)
+		})
+	})
+}
+
+func detectSyncOutput(getenv func(string) string, probe func() bool) bool {
+	switch strings.ToLower(strings.TrimSpace(getenv("GIFGREP_SYNC_OUTPUT"))) {
+	case "1", "true", "yes", "on":
+		return true
+	case "0", "false", "no", "off":
+		return false
+	}
+	if caps, ok := capsFromEnv(getenv); ok {
+		return caps.SyncOutput
+	}
+	return probe()
+}
+
+// probeSyncOutput asks whether DEC private mode 2026 is known (DECRQM).
+// Replies ESC [ ? 2026 ; Ps $ y with Ps 1/2 (set/reset) or 3 (always set)
+// mean synchronized output works; 0 or 4 mean it doesn't. It reads through
+// the DA1 reply, so the next probe doesn't find it.
+func probeSyncOutput(tty *os.File, timeout time.Duration) bool {
+	if tty == nil {
+		return false
+	}
+	_, _ = tty.Write([]byte("\x1b[?2026$p\x1b[c"))
+
+	deadline := time.Now().Add(timeout)
+	_ = tty.SetReadDeadline(deadline)
+
+	var buf [256]byte
+	acc := make([]byte, 0, 256)
+	for time.Now().Before(deadline) {
+		n, err := tty.Read(buf[:])
+		if n > 0 {
+			acc = append(acc, buf[:n]...)
+			if hasDA1Response(acc) {
+				break
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+	ps, ok := decrqmReply(acc, 2026)
+	return ok && (ps == 1 || ps == 2 || ps == 3)
+}
+
+// decrqmReply finds ESC [ ? mode ; Ps $ y in b and returns Ps.
+func decrqmReply(b []byte, mode int) (int, bool) {
+	prefix := []byte("\x1b[?" + strconv.Itoa(mode) + ";")
+	i := bytes.Index(b, prefix)
+	if i < 0 {
+		return 0, false
+	}
+	rest := b[i+len(prefix):]
+	end := bytes.Index(rest, []byte("$y"))
+	if end < 0 {
+		return 0, false
+	}
+	ps, err := strconv.Atoi(string(rest[:end]))
+	if err != nil {
+		return 0, false
+	}
+	return ps, true
+}
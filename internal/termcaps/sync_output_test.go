@@ -0,0 +1,52 @@
+package termcaps
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDetectSyncOutput(t *testing.T) {
+	env := map[string]string{}
+	getenv := func(k string) string { return env[k] }
+	probed := 0
+	probe := func() bool { probed++; return true }
+
+	if !detectSyncOutput(getenv, probe) || probed != 1 {
+		t.Fatalf("expected probe result")
+	}
+
+	env["GIFGREP_CAPS"] = writeCaps(t, Caps{Version: CapsVersion, SyncOutput: false})
+	if detectSyncOutput(getenv, probe) || probed != 1 {
+		t.Fatalf("expected caps document to replace the probe")
+	}
+
+	env["GIFGREP_SYNC_OUTPUT"] = "1"
+	if !detectSyncOutput(getenv, probe) || probed != 1 {
+		t.Fatalf("expected env override")
+	}
+}
+
+func TestDecrqmReply(t *testing.T) {
+	if ps, ok := decrqmReply([]byte("\x1b[?2026;2$y\x1b[?62c"), 2026); !ok || ps != 2 {
+		t.Fatalf("expected Ps=2, got %d %v", ps, ok)
+	}
+	if _, ok := decrqmReply([]byte("\x1b[?62c"), 2026); ok {
+		t.Fatalf("expected no reply")
+	}
+}
+
+func TestProbeSyncOutputReadsThroughDA1(t *testing.T) {
+	tty := replyTTY(t, "\x1b[?2026;2$y", "\x1b[?62;4c")
+	if !probeSyncOutput(tty, time.Second) {
+		t.Fatalf("expected mode 2026 reported")
+	}
+	assertDrained(t, tty)
+	tty = replyTTY(t, "\x1b[?2026;0$y", "\x1b[?62;4c")
+	if probeSyncOutput(tty, time.Second) {
+		t.Fatalf("expected Ps=0 to mean unsupported")
+	}
+	assertDrained(t, tty)
+	if probeSyncOutput(replyTTY(t, "\x1b[?62c"), time.Second) {
+		t.Fatalf("expected DA1 alone to mean unsupported")
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package termcaps
+
+import "golang.org/x/term"
+
+// windowSize has no pixel fields here; the cell size comes from the
+// terminal's CSI 14t/16t replies instead.
+func windowSize(fd int) (winsize, bool) {
+	cols, rows, err := term.GetSize(fd)
+	if err != nil {
+		return winsize{}, false
+	}
+	return winsize{Cols: cols, Rows: rows}, true
+}
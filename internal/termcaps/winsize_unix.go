@@ -0,0 +1,13 @@
+//go:build unix
+
+package termcaps
+
+import "golang.org/x/sys/unix"
+
+func windowSize(fd int) (winsize, bool) {
+	ws, err := unix.IoctlGetWinsize(fd, unix.TIOCGWINSZ)
+	if err != nil {
+		return winsize{}, false
+	}
+	return winsize{Cols: int(ws.Col), Rows: int(ws.Row), XPixel: int(ws.Xpixel), YPixel: int(ws.Ypixel)}, true
+}
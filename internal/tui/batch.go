@@ -0,0 +1,242 @@
+package tui
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/steipete/gifgrep/internal/download"
+	"github.com/steipete/gifgrep/internal/model"
+)
+
+// batchWorkers bounds how many marked GIFs download at once.
+const batchWorkers = 4
+
+var exportDirFn = download.DefaultDir
+
+// batchJob is a running batch download. Workers report on results; the run
+// loop collects them with pollBatch, so state is only touched there.
+type batchJob struct {
+	total, done, failed int
+	results             chan batchResult
+}
+
+type batchResult struct {
+	item model.Result
+	path string
+	err  error
+}
+
+func isMarked(state *appState, item model.Result) bool {
+	return state.marked[resultKey(item)]
+}
+
+// markPrefix is the check mark shown before marked titles.
+func markPrefix(state *appState, item model.Result) string {
+	if !isMarked(state, item) {
+		return ""
+	}
+	return styleIf(state.useColor, "✓ ", "\x1b[32m")
+}
+
+// toggleMark marks or unmarks the selected result and moves on to the next.
+func toggleMark(state *appState) {
+	if state.selected < 0 || state.selected >= len(state.results) {
+		return
+	}
+	key := resultKey(state.results[state.selected])
+	if state.marked[key] {
+		delete(state.marked, key)
+	} else {
+		if state.marked == nil {
+			state.marked = map[string]bool{}
+		}
+		state.marked[key] = true
+	}
+	moveSelection(state, 1)
+	state.renderDirty = true
+}
+
+// markAll marks every listed result, or unmarks them when all are marked.
+// Marks on results a filter hides stay as they are.
+func markAll(state *appState) {
+	all := len(state.results) > 0
+	for _, item := range state.results {
+		all = all && isMarked(state, item)
+	}
+	if state.marked == nil {
+		state.marked = map[string]bool{}
+	}
+	for _, item := range state.results {
+		if all {
+			delete(state.marked, resultKey(item))
+		} else {
+			state.marked[resultKey(item)] = true
+		}
+	}
+	state.renderDirty = true
+}
+
+// markedResults returns the marked results in list order, including those
+// a filter hides.
+func markedResults(state *appState) []model.Result {
+	if len(state.marked) == 0 {
+		return nil
+	}
+	results := state.results
+	if state.allResults != nil {
+		results = state.allResults
+	}
+	items := make([]model.Result, 0, len(state.marked))
+	for _, item := range results {
+		if isMarked(state, item) {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// actionTargets is what a batch action works on: the marked results, or
+// the selected one when nothing is marked.
+func actionTargets(state *appState) []model.Result {
+	if items := markedResults(state); len(items) > 0 {
+		return items
+	}
+	if state.selected < 0 || state.selected >= len(state.results) {
+		return nil
+	}
+	return []model.Result{state.results[state.selected]}
+}
+
+// downloadMarked downloads the marked results concurrently; with nothing
+// marked it downloads the selection as before.
+func downloadMarked(state *appState, out *bufio.Writer) {
+	items := markedResults(state)
+	if len(items) == 0 {
+		downloadSelected(state, out, state.opts.Reveal)
+		return
+	}
+	if state.batch != nil {
+		flashHeader(state, "Downloads already running")
+		state.renderDirty = true
+		return
+	}
+	job := &batchJob{total: len(items), results: make(chan batchResult, len(items))}
+	queue := make(chan model.Result, len(items))
+	for _, item := range items {
+		queue <- item
+	}
+	close(queue)
+	for i := 0; i < minInt(batchWorkers, len(items)); i++ {
+		go func() {
+			for item := range queue {
+				if item.URL == "" {
+					job.results <- batchResult{item: item, err: errors.New("no URL")}
+					continue
+				}
+				path, err := downloadToDownloadsFn(item)
+				job.results <- batchResult{item: item, path: path, err: err}
+			}
+		}()
+	}
+	state.batch = job
+	state.status = fmt.Sprintf("Downloading 0/%d…", job.total)
+	state.renderDirty = true
+}
+
+// pollBatch collects finished downloads and updates the status line.
+func pollBatch(state *appState) {
+	job := state.batch
+	if job == nil {
+		return
+	}
+drain:
+	for {
+		select {
+		case res := <-job.results:
+			job.done++
+			if res.err != nil {
+				job.failed++
+			} else {
+				state.lastSavedPath = res.path
+				trackSavedPath(state, res.item, res.path)
+			}
+			state.renderDirty = true
+		default:
+			break drain
+		}
+	}
+	switch {
+	case job.done < job.total:
+		state.status = fmt.Sprintf("Downloading %d/%d…", job.done, job.total)
+	case job.failed > 0:
+		state.status = fmt.Sprintf("Saved %d of %d (%d failed)", job.total-job.failed, job.total, job.failed)
+		state.batch = nil
+	default:
+		state.status = fmt.Sprintf("Saved %d", job.total)
+		state.batch = nil
+	}
+}
+
+// exportResults writes the marked (or selected) results to a markdown or
+// JSON file in the download directory.
+func exportResults(state *appState, format string) {
+	items := actionTargets(state)
+	if len(items) == 0 {
+		flashHeader(state, "No selection")
+		state.renderDirty = true
+		return
+	}
+	path, err := writeExport(items, state.query, format)
+	if err != nil {
+		flashHeader(state, "Export error: "+err.Error())
+		state.renderDirty = true
+		return
+	}
+	flashHeader(state, fmt.Sprintf("Exported %d to %s", len(items), path))
+	state.renderDirty = true
+}
+
+func writeExport(items []model.Result, query, format string) (string, error) {
+	var data []byte
+	switch format {
+	case "json":
+		encoded, err := json.MarshalIndent(items, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		data = append(encoded, '\n')
+	default:
+		data = []byte(exportMarkdown(items, query))
+	}
+	dir, err := exportDirFn()
+	if err != nil {
+		return "", err
+	}
+	// SaveFile numbers a second export within the same second.
+	return download.SaveFile(dir, fmt.Sprintf("gifgrep-%s.%s", nowFn().Format("20060102-150405"), exportExt(format)), data)
+}
+
+func exportExt(format string) string {
+	if format == "json" {
+		return "json"
+	}
+	return "md"
+}
+
+func exportMarkdown(items []model.Result, query string) string {
+	var b strings.Builder
+	if strings.TrimSpace(query) != "" {
+		fmt.Fprintf(&b, "# gifgrep: %s\n\n", strings.TrimSpace(query))
+	}
+	for _, item := range items {
+		fmt.Fprintf(&b, "%s\n", copyMarkdown.line(item))
+	}
+	return b.String()
+}
+
+func markdownEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`).Replace(s)
+}
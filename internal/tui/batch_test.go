@@ -0,0 +1,246 @@
+package tui
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/steipete/gifgrep/internal/model"
+)
+
+func batchTestState() *appState {
+	return &appState{
+		mode:  modeBrowse,
+		query: "cats",
+		results: []model.Result{
+			{ID: "1", Title: "one", URL: "https://example.test/1.gif"},
+			{ID: "2", Title: "two [hd]", URL: "https://example.test/2.gif"},
+			{ID: "3", Title: "three", URL: "https://example.test/3.gif"},
+		},
+		lastRows: 12,
+		lastCols: 60,
+		cache:    map[string]*gifCacheEntry{},
+	}
+}
+
+func TestMarking(t *testing.T) {
+	state := batchTestState()
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+
+	handleInput(state, inputEvent{kind: keyRune, ch: ' '}, out)
+	if !isMarked(state, state.results[0]) || state.selected != 1 || state.mode != modeBrowse {
+		t.Fatalf("expected first result marked and selection advanced")
+	}
+	handleInput(state, inputEvent{kind: keyRune, ch: ' '}, out)
+	if got := len(markedResults(state)); got != 2 {
+		t.Fatalf("expected 2 marked, got %d", got)
+	}
+
+	render(state, out, state.lastRows, state.lastCols)
+	_ = out.Flush()
+	if !strings.Contains(buf.String(), "2 marked") || !strings.Contains(buf.String(), "✓ one") {
+		t.Fatalf("expected marks in list and status, got %q", buf.String())
+	}
+
+	handleInput(state, inputEvent{kind: keyRune, ch: '*'}, out)
+	if got := len(markedResults(state)); got != 3 {
+		t.Fatalf("expected all marked, got %d", got)
+	}
+	handleInput(state, inputEvent{kind: keyRune, ch: '*'}, out)
+	if got := len(markedResults(state)); got != 0 {
+		t.Fatalf("expected marks cleared, got %d", got)
+	}
+}
+
+func TestBatchDownloadRunsConcurrently(t *testing.T) {
+	orig := downloadToDownloadsFn
+	t.Cleanup(func() { downloadToDownloadsFn = orig })
+
+	var mu sync.Mutex
+	inFlight := 0
+	allStarted := make(chan struct{})
+	downloadToDownloadsFn = func(item model.Result) (string, error) {
+		mu.Lock()
+		inFlight++
+		if inFlight == 3 {
+			close(allStarted)
+		}
+		mu.Unlock()
+		select {
+		case <-allStarted:
+		case <-time.After(2 * time.Second):
+			return "", errors.New("downloads did not overlap")
+		}
+		if item.ID == "2" {
+			return "", errors.New("boom")
+		}
+		return "/tmp/" + item.ID + ".gif", nil
+	}
+
+	state := batchTestState()
+	markAll(state)
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+	handleInput(state, inputEvent{kind: keyRune, ch: 'd'}, out)
+	if state.batch == nil || state.status != "Downloading 0/3…" {
+		t.Fatalf("expected batch started, status %q", state.status)
+	}
+
+	deadline := time.Now().Add(3 * time.Second)
+	for state.batch != nil && time.Now().Before(deadline) {
+		pollBatch(state)
+		time.Sleep(time.Millisecond)
+	}
+	if state.batch != nil {
+		t.Fatalf("batch did not finish")
+	}
+	if state.status != "Saved 2 of 3 (1 failed)" {
+		t.Fatalf("unexpected status %q", state.status)
+	}
+	if state.savedPaths["id:1"] != "/tmp/1.gif" || state.savedPaths["id:3"] != "/tmp/3.gif" {
+		t.Fatalf("expected saved paths tracked, got %v", state.savedPaths)
+	}
+}
+
+func TestCopyURLs(t *testing.T) {
+	state := batchTestState()
+	state.osc52 = true
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+
+	handleInput(state, inputEvent{kind: keyRune, ch: 'c'}, out)
+	_ = out.Flush()
+	want := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte("https://example.test/1.gif")) + "\x07"
+	if buf.String() != want || state.headerFlash != "Copied URL" {
+		t.Fatalf("expected selected URL copied, got %q (%q)", buf.String(), state.headerFlash)
+	}
+
+	buf.Reset()
+	state.marked = map[string]bool{"id:1": true, "id:3": true}
+	handleInput(state, inputEvent{kind: keyRune, ch: 'c'}, out)
+	_ = out.Flush()
+	want = "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte("https://example.test/1.gif\nhttps://example.test/3.gif")) + "\x07"
+	if buf.String() != want || state.headerFlash != "Copied 2 URLs" {
+		t.Fatalf("expected marked URLs copied, got %q (%q)", buf.String(), state.headerFlash)
+	}
+}
+
+func TestMarksUnderFilter(t *testing.T) {
+	state := batchTestState()
+	state.osc52 = true
+	state.marked = map[string]bool{"id:1": true, "id:3": true}
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+
+	handleInput(state, inputEvent{kind: keyRune, ch: 'f', mod: modCtrl}, out)
+	handleInput(state, inputEvent{kind: keyPaste, text: "two"}, out)
+	handleInput(state, inputEvent{kind: keyEnter}, out)
+	if resultIDs(state.results) != "2" {
+		t.Fatalf("expected the filter kept, got %s", resultIDs(state.results))
+	}
+	buf.Reset()
+	handleInput(state, inputEvent{kind: keyRune, ch: 'c'}, out)
+	_ = out.Flush()
+	want := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte("https://example.test/1.gif\nhttps://example.test/3.gif")) + "\x07"
+	if buf.String() != want {
+		t.Fatalf("expected the hidden marked URLs copied, got %q (%q)", buf.String(), state.headerFlash)
+	}
+
+	// Mark-all toggles the listed results and leaves the hidden marks.
+	handleInput(state, inputEvent{kind: keyRune, ch: '*'}, out)
+	if ids := resultIDs(markedResults(state)); ids != "1,2,3" {
+		t.Fatalf("expected the listed result marked too, got %s", ids)
+	}
+	handleInput(state, inputEvent{kind: keyRune, ch: '*'}, out)
+	if ids := resultIDs(markedResults(state)); ids != "1,3" {
+		t.Fatalf("expected only the listed result unmarked, got %s", ids)
+	}
+}
+
+func TestExportResults(t *testing.T) {
+	dir := t.TempDir()
+	origDir, origNow := exportDirFn, nowFn
+	t.Cleanup(func() { exportDirFn, nowFn = origDir, origNow })
+	exportDirFn = func() (string, error) { return dir, nil }
+	nowFn = func() time.Time { return time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	state := batchTestState()
+	state.marked = map[string]bool{"id:1": true, "id:2": true}
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+
+	handleInput(state, inputEvent{kind: keyRune, ch: 'e'}, out)
+	md, err := os.ReadFile(filepath.Join(dir, "gifgrep-20260102-030405.md"))
+	if err != nil {
+		t.Fatalf("read markdown: %v", err)
+	}
+	want := "# gifgrep: cats\n\n![one](https://example.test/1.gif)\n![two \\[hd\\]](https://example.test/2.gif)\n"
+	if string(md) != want {
+		t.Fatalf("unexpected markdown %q", md)
+	}
+	if !strings.HasPrefix(state.headerFlash, "Exported 2 to ") {
+		t.Fatalf("unexpected flash %q", state.headerFlash)
+	}
+
+	handleInput(state, inputEvent{kind: keyRune, ch: 'E'}, out)
+	data, err := os.ReadFile(filepath.Join(dir, "gifgrep-20260102-030405.json"))
+	if err != nil {
+		t.Fatalf("read json: %v", err)
+	}
+	var items []model.Result
+	if err := json.Unmarshal(data, &items); err != nil || len(items) != 2 || items[1].ID != "2" {
+		t.Fatalf("unexpected json %s (%v)", data, err)
+	}
+
+	// A second export in the same second gets its own file.
+	handleInput(state, inputEvent{kind: keyRune, ch: 'e'}, out)
+	if want := "Exported 2 to " + filepath.Join(dir, "gifgrep-20260102-030405-1.md"); state.headerFlash != want {
+		t.Fatalf("expected %q, got %q", want, state.headerFlash)
+	}
+	if again, err := os.ReadFile(filepath.Join(dir, "gifgrep-20260102-030405.md")); err != nil || !bytes.Equal(again, md) {
+		t.Fatalf("expected the first export kept, got %q (%v)", again, err)
+	}
+}
+
+func TestCopyFormatsFallBackToNative(t *testing.T) {
+	orig := copyNativeFn
+	t.Cleanup(func() { copyNativeFn = orig })
+	var copied string
+	copyNativeFn = func(text string) error {
+		copied = text
+		return nil
+	}
+
+	state := batchTestState()
+	state.selected = 1
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+
+	handleInput(state, inputEvent{kind: keyRune, ch: 'm'}, out)
+	if copied != "![two \\[hd\\]](https://example.test/2.gif)" || state.headerFlash != "Copied markdown link" {
+		t.Fatalf("unexpected markdown copy %q (%q)", copied, state.headerFlash)
+	}
+	handleInput(state, inputEvent{kind: keyRune, ch: 'H'}, out)
+	if copied != `<img src="https://example.test/2.gif" alt="two [hd]">` || state.headerFlash != "Copied <img> tag" {
+		t.Fatalf("unexpected html copy %q (%q)", copied, state.headerFlash)
+	}
+	_ = out.Flush()
+	if buf.Len() != 0 {
+		t.Fatalf("expected no OSC 52 without terminal support, got %q", buf.String())
+	}
+
+	copyNativeFn = func(string) error { return errors.New("no clipboard tool found") }
+	handleInput(state, inputEvent{kind: keyRune, ch: 'c'}, out)
+	if state.headerFlash != "Copy error: no clipboard tool found" {
+		t.Fatalf("unexpected flash %q", state.headerFlash)
+	}
+}
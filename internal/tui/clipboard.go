@@ -0,0 +1,89 @@
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/steipete/gifgrep/internal/clipboard"
+	"github.com/steipete/gifgrep/internal/model"
+)
+
+var copyNativeFn = clipboard.Copy
+
+// copyFormat is what a copy key puts on the clipboard for each result.
+type copyFormat int
+
+const (
+	copyURL copyFormat = iota
+	copyMarkdown
+	copyHTML
+)
+
+func (f copyFormat) noun(n int) string {
+	var noun string
+	switch f {
+	case copyURL:
+		noun = "URL"
+	case copyMarkdown:
+		noun = "markdown link"
+	case copyHTML:
+		noun = "<img> tag"
+	}
+	if n == 1 {
+		return noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+func (f copyFormat) line(item model.Result) string {
+	switch f {
+	case copyMarkdown:
+		return fmt.Sprintf("![%s](%s)", markdownEscape(resultLabel(item)), item.URL)
+	case copyHTML:
+		return fmt.Sprintf(`<img src="%s" alt="%s">`, html.EscapeString(item.URL), html.EscapeString(resultLabel(item)))
+	case copyURL:
+	}
+	return item.URL
+}
+
+func resultLabel(item model.Result) string {
+	if item.Title == "" {
+		return item.ID
+	}
+	return item.Title
+}
+
+// copyResults puts the marked (or selected) results on the clipboard in
+// format f, one per line.
+func copyResults(state *appState, out *bufio.Writer, f copyFormat) {
+	targets := actionTargets(state)
+	lines := make([]string, 0, len(targets))
+	for _, item := range targets {
+		if item.URL != "" {
+			lines = append(lines, f.line(item))
+		}
+	}
+	if len(lines) == 0 {
+		flashHeader(state, "No URL")
+		state.renderDirty = true
+		return
+	}
+	if err := copyText(state, out, strings.Join(lines, "\n")); err != nil {
+		flashHeader(state, "Copy error: "+err.Error())
+	} else {
+		flashHeader(state, "Copied "+f.noun(len(lines)))
+	}
+	state.renderDirty = true
+}
+
+// copyText uses OSC 52 when the terminal supports it (it also works over
+// SSH) and the platform's clipboard tool otherwise.
+func copyText(state *appState, out *bufio.Writer, text string) error {
+	if state.osc52 {
+		_, _ = out.WriteString(clipboard.OSC52(text))
+		return nil
+	}
+	return copyNativeFn(text)
+}
@@ -0,0 +1,138 @@
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+
+	"github.com/steipete/gifgrep/gifdecode"
+	"github.com/steipete/gifgrep/internal/search"
+)
+
+// detailLabelWidth is the width of the field names in the detail pane.
+const detailLabelWidth = 8
+
+// toggleDetail shows or hides the detail pane, which takes the place of the
+// list. The grid has no list, so it is left first.
+func toggleDetail(state *appState, out *bufio.Writer) {
+	if state.grid {
+		toggleGrid(state, out)
+		state.detail = true
+	} else {
+		state.detail = !state.detail
+	}
+	state.renderDirty = true
+}
+
+func drawDetail(out *bufio.Writer, state *appState, layout layout) {
+	lines := detailLines(state, layout.listWidth)
+	for i := 0; i < layout.listHeight; i++ {
+		line := ""
+		if i < len(lines) {
+			line = lines[i]
+		}
+		state.screen.writeLineAt(out, layout.contentTop+i, layout.listCol, line, layout.listWidth)
+	}
+}
+
+// detailLines describes the selected result in lines of at most width
+// cells: the result's fields, what the cached preview holds, and where it
+// was saved.
+func detailLines(state *appState, width int) []string {
+	if state.selected < 0 || state.selected >= len(state.results) {
+		return []string{"No result selected"}
+	}
+	item := state.results[state.selected]
+	title := item.Title
+	if title == "" {
+		title = item.ID
+	}
+	lines := []string{styleIf(state.useColor, truncateRunes(title, width), "\x1b[1m"), ""}
+	field := func(name, value string) {
+		if value == "" {
+			return
+		}
+		label := fmt.Sprintf("%-*s", detailLabelWidth, name)
+		for i, part := range wrapRunes(value, maxInt(1, width-detailLabelWidth-1)) {
+			if i > 0 {
+				label = strings.Repeat(" ", detailLabelWidth)
+			}
+			lines = append(lines, styleIf(state.useColor, label, "\x1b[90m")+" "+part)
+		}
+	}
+	field("ID", item.ID)
+	field("Source", search.ResolveSource(state.opts.Source))
+	if item.Width > 0 && item.Height > 0 {
+		field("Size", fmt.Sprintf("%d×%d", item.Width, item.Height))
+	}
+	field("Tags", strings.Join(item.Tags, ", "))
+	field("Preview", previewSummary(state, item.PreviewURL))
+	field("URL", item.URL)
+	if p, ok := savedPathForResult(state, item); ok {
+		field("Saved", p)
+	}
+	return lines
+}
+
+// previewSummary describes the cached preview GIF: its size in bytes and
+// pixels, and the frame count and loop duration of the whole file.
+func previewSummary(state *appState, previewURL string) string {
+	entry, ok := state.cache[previewURL]
+	if !ok || entry == nil {
+		return ""
+	}
+	parts := []string{formatBytes(len(entry.RawGIF))}
+	if entry.Width > 0 && entry.Height > 0 {
+		parts = append(parts, fmt.Sprintf("%d×%d", entry.Width, entry.Height))
+	}
+	stats, err := entryStats(entry)
+	switch {
+	case err != nil:
+		parts = append(parts, "error: "+err.Error())
+	case stats.Frames > 0:
+		parts = append(parts, fmt.Sprintf("%d frames", stats.Frames), fmt.Sprintf("%.2fs", stats.Duration.Seconds()))
+		if len(stats.Warnings) > 0 {
+			parts = append(parts, "truncated")
+		}
+	}
+	return strings.Join(parts, " · ")
+}
+
+// entryStats counts the frames of entry's GIF the first time the detail
+// pane shows it and keeps the result; nothing else needs it, so previews
+// and thumbnails don't pay for the extra decode.
+func entryStats(entry *gifCacheEntry) (*gifdecode.Stats, error) {
+	if entry.Stats == nil && entry.StatsErr == nil {
+		opts := gifdecode.DefaultOptions()
+		opts.Recover = true
+		stats, err := gifdecode.Stat(entry.RawGIF, opts)
+		if err != nil {
+			entry.StatsErr = err
+		} else {
+			entry.Stats = &stats
+		}
+	}
+	return entry.Stats, entry.StatsErr
+}
+
+func formatBytes(n int) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
+
+// wrapRunes splits s into lines of at most width runes.
+func wrapRunes(s string, width int) []string {
+	runes := []rune(s)
+	lines := make([]string, 0, len(runes)/width+1)
+	for len(runes) > width {
+		lines = append(lines, string(runes[:width]))
+		runes = runes[width:]
+	}
+	return append(lines, string(runes))
+}
@@ -16,7 +16,7 @@ func TestRunTUIWithQuit(t *testing.T) {
 	t.Setenv("GIFGREP_INLINE", "kitty")
 	var restored bool
 	env := Env{
-		In:  bytes.NewReader([]byte("q")),
+		In:  bytes.NewReader([]byte("\x03")), // ctrl+c
 		Out: io.Discard,
 		FD:  1,
 		IsTerminal: func(int) bool {
@@ -48,7 +48,7 @@ func TestRunTUIWithSearch(t *testing.T) {
 	gifData := testutil.MakeTestGIF()
 	testutil.WithTransport(t, &testutil.FakeTransport{GIFData: gifData}, func() {
 		env := Env{
-			In:  bytes.NewReader([]byte("q")),
+			In:  bytes.NewReader([]byte("\x03")), // ctrl+c
 			Out: io.Discard,
 			FD:  1,
 			IsTerminal: func(int) bool {
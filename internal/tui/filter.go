@@ -0,0 +1,260 @@
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/steipete/gifgrep/internal/model"
+)
+
+// The filter narrows the current results locally, without a new search.
+// While it is applied, state.allResults holds the full list and
+// state.results the matches, best first.
+
+// startFilter enters filter mode, keeping any filter already applied.
+func startFilter(state *appState) {
+	if len(state.results) == 0 && state.allResults == nil {
+		flashHeader(state, "Nothing to filter")
+		state.renderDirty = true
+		return
+	}
+	if state.allResults == nil {
+		state.allResults = state.results
+	}
+	state.mode = modeFilter
+	filterStatus(state)
+	state.renderDirty = true
+}
+
+func handleFilterInput(state *appState, ev inputEvent, out *bufio.Writer) bool {
+	if act, ok := activeKeymap(state).lookup(ev); ok && act == actFilter {
+		// The filter key again keeps the filter, like Enter.
+		ev = inputEvent{kind: keyEnter}
+	}
+	switch ev.kind {
+	case keyRune, keyPaste, keyLeft, keyRight, keyHome, keyEnd, keyBackspace, keyDelete,
+		keyDeleteWord, keyDeleteLine, keyKillEnd, keyYank:
+		editFilter(state, ev)
+		applyFilter(state, out)
+	case keyEnter:
+		state.mode = modeBrowse
+		if state.filter == "" {
+			clearFilter(state, out)
+		}
+		state.renderDirty = true
+	case keyEsc:
+		state.mode = modeBrowse
+		clearFilter(state, out)
+	case keyUp:
+		moveSelectionBy(state, actUp)
+	case keyDown:
+		moveSelectionBy(state, actDown)
+	case keyPageUp:
+		moveSelectionBy(state, actPageUp)
+	case keyPageDown:
+		moveSelectionBy(state, actPageDown)
+	case keyCtrlC:
+		return true
+	case mouseClick, mouseWheelUp, mouseWheelDown:
+		return handleMouse(state, ev, out)
+	case keyTab, keyUnknown:
+	}
+	return false
+}
+
+// editFilter edits the filter with the search line's editor, which works
+// on state.query: the filter is swapped in for the edit.
+func editFilter(state *appState, ev inputEvent) {
+	state.query, state.filter = state.filter, state.query
+	state.queryTail, state.filterTail = state.filterTail, state.queryTail
+	editQuery(state, ev)
+	state.query, state.filter = state.filter, state.query
+	state.queryTail, state.filterTail = state.filterTail, state.queryTail
+}
+
+// applyFilter shows the matches of the filter.
+func applyFilter(state *appState, out *bufio.Writer) {
+	showResults(state, out, filterResults(state.allResults, state.filter))
+	filterStatus(state)
+}
+
+// clearFilter brings back the full list.
+func clearFilter(state *appState, out *bufio.Writer) {
+	if state.allResults == nil {
+		return
+	}
+	all := state.allResults
+	dropFilter(state)
+	showResults(state, out, all)
+	state.status = fmt.Sprintf("%d results", len(all))
+}
+
+// dropFilter forgets the filter, e.g. when a new search replaces the
+// results.
+func dropFilter(state *appState) {
+	state.allResults = nil
+	state.filter = ""
+	state.filterTail = 0
+}
+
+// showResults replaces the listed results, keeping the selected result
+// selected when it is still there. The grid's tiles are kept by index, so
+// they start over.
+func showResults(state *appState, out *bufio.Writer, results []model.Result) {
+	prev := ""
+	if state.selected >= 0 && state.selected < len(state.results) {
+		prev = resultKey(state.results[state.selected])
+	}
+	state.results = results
+	state.selected, state.scroll = 0, 0
+	for i, item := range results {
+		if resultKey(item) == prev {
+			state.selected = i
+			break
+		}
+	}
+	if state.grid {
+		resetGrid(state, out)
+	}
+	ensureVisible(state)
+	switch {
+	case len(results) == 0:
+		state.currentAnim = nil
+		state.previewDirty = true
+	case prev == "" || resultKey(results[state.selected]) != prev:
+		loadSelectedImage(state)
+	}
+	state.renderDirty = true
+}
+
+func filterStatus(state *appState) {
+	state.status = fmt.Sprintf("Filter: %d of %d results", len(state.results), len(state.allResults))
+}
+
+// filterResults returns the results matching filter, best match first;
+// equal matches keep their order.
+func filterResults(results []model.Result, filter string) []model.Result {
+	if strings.TrimSpace(filter) == "" {
+		return results
+	}
+	type scored struct {
+		item  model.Result
+		score int
+	}
+	matches := make([]scored, 0, len(results))
+	for _, item := range results {
+		if score, ok := matchResult(item, filter); ok {
+			matches = append(matches, scored{item, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+	out := make([]model.Result, len(matches))
+	for i, m := range matches {
+		out[i] = m.item
+	}
+	return out
+}
+
+// matchResult matches every word of filter against the result's label or,
+// failing that, its tags; label matches count double.
+func matchResult(item model.Result, filter string) (int, bool) {
+	label, tags := resultLabel(item), strings.Join(item.Tags, " ")
+	total := 0
+	for _, term := range strings.Fields(filter) {
+		if _, score, ok := fuzzyMatch(label, term); ok {
+			total += 2 * score
+			continue
+		}
+		if _, score, ok := fuzzyMatch(tags, term); ok {
+			total += score
+			continue
+		}
+		return 0, false
+	}
+	return total, true
+}
+
+// filterPositions returns the rune positions of label that the filter's
+// words match, for highlighting.
+func filterPositions(label, filter string) map[int]bool {
+	hits := map[int]bool{}
+	for _, term := range strings.Fields(filter) {
+		if positions, _, ok := fuzzyMatch(label, term); ok {
+			for _, p := range positions {
+				hits[p] = true
+			}
+		}
+	}
+	return hits
+}
+
+// fuzzyMatch finds the runes of pattern in text, in order and ignoring
+// case, and returns their rune positions. Consecutive runes and runes at
+// the start of a word score higher; of the matches starting at each
+// occurrence of the first rune, the best one wins.
+func fuzzyMatch(text, pattern string) ([]int, int, bool) {
+	want := []rune(strings.ToLower(pattern))
+	if len(want) == 0 {
+		return nil, 0, true
+	}
+	runes := []rune(text)
+	var best []int
+	bestScore := 0
+	for start, r := range runes {
+		if unicode.ToLower(r) != want[0] {
+			continue
+		}
+		positions, score, ok := fuzzyMatchFrom(runes, want, start)
+		if !ok {
+			break
+		}
+		if best == nil || score > bestScore {
+			best, bestScore = positions, score
+		}
+	}
+	return best, bestScore, best != nil
+}
+
+// fuzzyMatchFrom matches want greedily in runes from start on.
+func fuzzyMatchFrom(runes, want []rune, start int) ([]int, int, bool) {
+	positions := make([]int, 0, len(want))
+	score, prev := 0, -2
+	for i := start; i < len(runes); i++ {
+		if unicode.ToLower(runes[i]) != want[len(positions)] {
+			continue
+		}
+		score++
+		if i == prev+1 {
+			score += 2
+		}
+		if i == 0 || !(unicode.IsLetter(runes[i-1]) || unicode.IsDigit(runes[i-1])) {
+			score += 3
+		}
+		positions = append(positions, i)
+		prev = i
+		if len(positions) == len(want) {
+			return positions, score, true
+		}
+	}
+	return nil, 0, false
+}
+
+// highlightRunes marks the runes of s at hits; base is the style of the
+// rest of the text, restored after each highlight.
+func highlightRunes(s string, hits map[int]bool, base ...string) string {
+	var b strings.Builder
+	for i, r := range []rune(s) {
+		if !hits[i] {
+			b.WriteRune(r)
+			continue
+		}
+		b.WriteString("\x1b[1m\x1b[33m")
+		b.WriteRune(r)
+		b.WriteString("\x1b[0m")
+		b.WriteString(strings.Join(base, ""))
+	}
+	return b.String()
+}
@@ -0,0 +1,180 @@
+package tui
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/steipete/gifgrep/internal/model"
+)
+
+func filterTestState() *appState {
+	return &appState{
+		mode:  modeBrowse,
+		query: "cats",
+		results: []model.Result{
+			{ID: "1", Title: "Dancing dog"},
+			{ID: "2", Title: "Cat typing", Tags: []string{"keyboard"}},
+			{ID: "3", Title: "Sleepy", Tags: []string{"cat", "nap"}},
+			{ID: "4", Title: "Office party"},
+		},
+		lastRows: 20,
+		lastCols: 60,
+		cache:    map[string]*gifCacheEntry{},
+		screen:   newScreenBuffer(),
+	}
+}
+
+func resultIDs(results []model.Result) string {
+	ids := make([]string, 0, len(results))
+	for _, item := range results {
+		ids = append(ids, item.ID)
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestFuzzyMatch(t *testing.T) {
+	positions, _, ok := fuzzyMatch("Cat typing", "cty")
+	if !ok || len(positions) != 3 || positions[0] != 0 || positions[1] != 2 || positions[2] != 5 {
+		t.Fatalf("unexpected match %v %v", positions, ok)
+	}
+	if _, _, ok := fuzzyMatch("Cat typing", "tac"); ok {
+		t.Fatalf("expected out-of-order runes not to match")
+	}
+	if positions, _, _ := fuzzyMatch("Cat typing", "ty"); positions[0] != 4 {
+		t.Fatalf("expected the match at the word start, got %v", positions)
+	}
+	_, word, _ := fuzzyMatch("Office party", "pa")
+	_, inner, _ := fuzzyMatch("Sleepy apart", "pa")
+	if word <= inner {
+		t.Fatalf("expected a match at a word start to score higher: %d <= %d", word, inner)
+	}
+}
+
+func TestFilterResults(t *testing.T) {
+	results := filterTestState().results
+	cases := []struct {
+		filter, want string
+	}{
+		{"", "1,2,3,4"},
+		{"cat", "2,3,4"},      // title, then tags, then a loose title match
+		{"keyb", "2"},         // tags
+		{"cat nap", "3"},      // every word must match
+		{"DOG", "1"},          // ignoring case
+		{"zzz", ""},           // nothing
+		{"o", "4,1,2"},        // word starts first
+		{"ofc pty", "4"},      // fuzzy
+		{"ty", "2,4"},         // typing's word start beats party
+		{"c", "2,3,1,4"},      // equal scores keep their order
+		{"dancing cat", ""},   // no result has both
+		{"office partyy", ""}, // one rune too many
+	}
+	for _, tc := range cases {
+		if got := resultIDs(filterResults(results, tc.filter)); got != tc.want {
+			t.Fatalf("filter %q: got %q want %q", tc.filter, got, tc.want)
+		}
+	}
+}
+
+func TestFilterMode(t *testing.T) {
+	state := filterTestState()
+	state.selected = 2
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+
+	handleInput(state, inputEvent{kind: keyRune, ch: 'f', mod: modCtrl}, out)
+	if state.mode != modeFilter || state.allResults == nil {
+		t.Fatalf("expected filter mode, got %v", state.mode)
+	}
+	for _, ch := range "cat" {
+		handleInput(state, inputEvent{kind: keyRune, ch: ch}, out)
+	}
+	if state.filter != "cat" || state.query != "cats" || resultIDs(state.results) != "2,3,4" {
+		t.Fatalf("unexpected filter %q query %q results %s", state.filter, state.query, resultIDs(state.results))
+	}
+	if state.selected != 1 || state.status != "Filter: 3 of 4 results" {
+		t.Fatalf("expected the selection kept on result 3, got %d (%q)", state.selected, state.status)
+	}
+	handleInput(state, inputEvent{kind: keyBackspace}, out)
+	handleInput(state, inputEvent{kind: keyLeft}, out)
+	handleInput(state, inputEvent{kind: keyRune, ch: 'o'}, out)
+	if state.filter != "coa" || resultIDs(state.results) != "" || state.currentAnim != nil {
+		t.Fatalf("expected the line editor on the filter, got %q %s", state.filter, resultIDs(state.results))
+	}
+
+	handleInput(state, inputEvent{kind: keyEsc}, out)
+	if state.mode != modeBrowse || state.allResults != nil || state.filter != "" || len(state.results) != 4 {
+		t.Fatalf("expected esc to restore the full list, got %s", resultIDs(state.results))
+	}
+
+	handleInput(state, inputEvent{kind: keyRune, ch: 'f', mod: modCtrl}, out)
+	for _, ch := range "cat" {
+		handleInput(state, inputEvent{kind: keyRune, ch: ch}, out)
+	}
+	handleInput(state, inputEvent{kind: keyDown}, out)
+	handleInput(state, inputEvent{kind: keyEnter}, out)
+	if state.mode != modeBrowse || resultIDs(state.results) != "2,3,4" || state.selected != 1 {
+		t.Fatalf("expected enter to keep the filter, got %s selected %d", resultIDs(state.results), state.selected)
+	}
+	handleInput(state, inputEvent{kind: keyEsc}, out)
+	if state.mode != modeBrowse || len(state.results) != 4 || state.results[state.selected].ID != "3" {
+		t.Fatalf("expected esc in browse mode to drop the kept filter, got %s selected %d", resultIDs(state.results), state.selected)
+	}
+
+	state.results = nil
+	handleInput(state, inputEvent{kind: keyRune, ch: 'f', mod: modCtrl}, out)
+	if state.mode != modeBrowse || state.headerFlash != "Nothing to filter" {
+		t.Fatalf("expected nothing to filter, got %v %q", state.mode, state.headerFlash)
+	}
+}
+
+func TestFilterHighlights(t *testing.T) {
+	state := filterTestState()
+	state.useColor = true
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+
+	handleInput(state, inputEvent{kind: keyRune, ch: 'f', mod: modCtrl}, out)
+	handleInput(state, inputEvent{kind: keyPaste, text: "cty"}, out)
+	buf.Reset()
+	render(state, out, state.lastRows, state.lastCols)
+	_ = out.Flush()
+	hl := func(r string) string { return "\x1b[1m\x1b[33m" + r + "\x1b[0m" }
+	// The selected row keeps its bold between the highlights.
+	want := "\x1b[1m" + hl("C") + "\x1b[1ma" + hl("t") + "\x1b[1m t" + hl("y") + "\x1b[1mping"
+	if !strings.Contains(buf.String(), want) {
+		t.Fatalf("expected highlighted matches, got %q", buf.String())
+	}
+	if !strings.Contains(buf.String(), " Filter ") || !strings.Contains(buf.String(), "cty") {
+		t.Fatalf("expected the filter in the search line, got %q", buf.String())
+	}
+}
+
+func TestFilterHighlightsDisplayedLabel(t *testing.T) {
+	state := filterTestState()
+	state.useColor = true
+	state.results = append(state.results, model.Result{ID: "abc123"})
+	state.marked = map[string]bool{resultKey(state.results[1]): true}
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+
+	// The only match is selected, so it's bold between the highlights.
+	hl := func(r string) string { return "\x1b[1m\x1b[33m" + r + "\x1b[0m\x1b[1m" }
+	for _, tc := range []struct{ filter, want string }{
+		// An untitled row shows its ID, and the filter matched that.
+		{"c12", "> \x1b[0m\x1b[1mab" + hl("c") + hl("1") + hl("2") + "3"},
+		// The mark goes in front of the highlighted title.
+		{"typ", "\x1b[32m✓ \x1b[0m\x1b[1mCat " + hl("t") + hl("y") + hl("p") + "ing"},
+	} {
+		handleInput(state, inputEvent{kind: keyRune, ch: 'f', mod: modCtrl}, out)
+		handleInput(state, inputEvent{kind: keyPaste, text: tc.filter}, out)
+		buf.Reset()
+		render(state, out, state.lastRows, state.lastCols)
+		_ = out.Flush()
+		if !strings.Contains(buf.String(), tc.want) {
+			t.Fatalf("filter %q: expected %q, got %q", tc.filter, tc.want, buf.String())
+		}
+		handleInput(state, inputEvent{kind: keyEsc}, out)
+	}
+}
@@ -0,0 +1,416 @@
+package tui
+
+import (
+	"bufio"
+	"math"
+	"strings"
+
+	"github.com/steipete/gifgrep/gifdecode"
+	"github.com/steipete/gifgrep/internal/iterm"
+	"github.com/steipete/gifgrep/internal/termcaps"
+)
+
+// gridTileCols is the width of one grid tile: the thumbnail box plus a
+// one-column gap.
+const gridTileCols = 22
+
+// gridFetchWorkers bounds how many tiles fetch and decode at once.
+const gridFetchWorkers = 4
+
+// gridLayout tiles the content area: perRow x rows tiles, each a thumbCols x
+// thumbRows thumbnail with its title on the line below.
+type gridLayout struct {
+	perRow, rows         int
+	thumbCols, thumbRows int
+	tileRows             int
+}
+
+// gridTile is a result shown in the grid. Each tile with an image has its
+// own animation and image ID; tiles that scroll off screen are evicted.
+type gridTile struct {
+	anim    *gifAnimation
+	loading bool // fetching or decoding in the background
+	sent    bool // uploaded to the terminal (Kitty)
+	placed  bool // drawn at its current position
+}
+
+// gridTileResult is a tile's thumbnail, fetched and decoded off the run
+// loop. The run loop applies it with pollGridTiles, so state is only
+// touched there.
+type gridTileResult struct {
+	idx    int
+	url    string
+	entry  *gifCacheEntry // the fetched GIF; nil when it was cached
+	frames *gifdecode.Frames
+	err    error
+}
+
+func buildGridLayout(l layout, aspect float64) gridLayout {
+	g := gridLayout{thumbCols: gridTileCols - 1}
+	if !l.hasContent || l.cols <= 0 {
+		return gridLayout{}
+	}
+	// 4:3 thumbnail boxes, measured in pixels.
+	g.thumbRows = maxInt(2, int(math.Round(float64(g.thumbCols)*aspect*3/4)))
+	g.tileRows = g.thumbRows + 2
+	g.perRow = maxInt(1, l.cols/gridTileCols)
+	g.rows = l.contentHeight / g.tileRows
+	if g.rows < 1 {
+		g.rows = 1
+		g.tileRows = l.contentHeight
+		g.thumbRows = maxInt(0, l.contentHeight-1)
+	}
+	if l.cols < gridTileCols {
+		g.thumbCols = l.cols
+	}
+	return g
+}
+
+// tilePos returns the top-left cell of the n-th visible tile.
+func (g gridLayout) tilePos(l layout, n int) (int, int) {
+	return l.contentTop + (n/g.perRow)*g.tileRows, 1 + (n%g.perRow)*gridTileCols
+}
+
+func currentGridLayout(state *appState) gridLayout {
+	return buildGridLayout(buildLayout(state, state.lastRows, state.lastCols), cellAspectRatio(state.cell))
+}
+
+// gridScrollFor returns the first visible tile row that keeps the selection
+// on screen.
+func gridScrollFor(state *appState, g gridLayout) int {
+	if g.perRow <= 0 || g.rows <= 0 {
+		return 0
+	}
+	selRow := state.selected / g.perRow
+	scroll := state.gridScroll
+	if selRow < scroll {
+		scroll = selRow
+	}
+	if selRow >= scroll+g.rows {
+		scroll = selRow - g.rows + 1
+	}
+	return maxInt(0, scroll)
+}
+
+// toggleGrid switches between the list with one preview and the grid.
+func toggleGrid(state *appState, out *bufio.Writer) {
+	state.grid = !state.grid
+	resetGrid(state, out)
+	state.screen.invalidate()
+	// In grid mode there is no single preview; loadSelectedImage drops it
+	// (and brings it back when leaving).
+	loadSelectedImage(state)
+	if state.grid {
+		state.status = "Grid view"
+	} else {
+		state.status = ""
+	}
+	state.renderDirty = true
+}
+
+// resetGrid evicts every tile, e.g. for new results.
+func resetGrid(state *appState, out *bufio.Writer) {
+	evictGridTiles(state, out, 0, 0)
+	state.gridScroll = 0
+	state.gridDirty = true
+}
+
+// evictGridTiles drops the tiles outside results [first, last) and deletes
+// their images from the terminal.
+func evictGridTiles(state *appState, out *bufio.Writer, first, last int) {
+	for idx, tile := range state.gridTiles {
+		if idx >= first && idx < last {
+			continue
+		}
+		if tile.sent && tile.anim != nil && state.inline == termcaps.InlineKitty {
+			state.kitty.DeleteImage(out, tile.anim.ID)
+		}
+		delete(state.gridTiles, idx)
+	}
+}
+
+// gridMove moves the selection in two dimensions; other actions (and
+// Home/End) fall through to the list handling.
+func gridMove(state *appState, act action) bool {
+	g := currentGridLayout(state)
+	if g.perRow <= 0 {
+		return false
+	}
+	sel, last, page := state.selected, len(state.results)-1, g.perRow*g.rows
+	up, down := sel, sel
+	if sel >= g.perRow {
+		up = sel - g.perRow
+	}
+	if sel+g.perRow <= last {
+		down = sel + g.perRow
+	} else if sel/g.perRow < last/g.perRow {
+		// Last row is short: go to its last tile.
+		down = last
+	}
+	pageUp := sel - page
+	if pageUp < 0 {
+		pageUp = sel % g.perRow
+	}
+	targets := map[action]int{
+		actLeft:     sel - 1,
+		actRight:    sel + 1,
+		actUp:       up,
+		actDown:     down,
+		actPageUp:   pageUp,
+		actPageDown: minInt(last, sel+page),
+	}
+	target, ok := targets[act]
+	if !ok {
+		return false
+	}
+	moveSelection(state, target-sel)
+	return true
+}
+
+// handleGridMouse selects a clicked tile; the wheel moves a row of tiles.
+func handleGridMouse(state *appState, l layout, ev inputEvent) {
+	g := buildGridLayout(l, cellAspectRatio(state.cell))
+	if g.perRow <= 0 {
+		return
+	}
+	switch ev.kind {
+	case mouseWheelUp:
+		gridMove(state, actUp)
+	case mouseWheelDown:
+		gridMove(state, actDown)
+	case mouseClick:
+		if ev.row < l.contentTop || ev.row >= l.contentTop+g.rows*g.tileRows || ev.col < 1 {
+			return
+		}
+		c := (ev.col - 1) / gridTileCols
+		if c >= g.perRow {
+			return
+		}
+		idx := (state.gridScroll+(ev.row-l.contentTop)/g.tileRows)*g.perRow + c
+		if idx < len(state.results) {
+			state.mode = modeBrowse
+			moveSelection(state, idx-state.selected)
+			state.renderDirty = true
+		}
+	case keyRune, keyEnter, keyBackspace, keyEsc, keyUp, keyDown, keyCtrlC, keyUnknown,
+		keyLeft, keyRight, keyHome, keyEnd, keyPageUp, keyPageDown, keyDelete, keyTab, keyDeleteWord, keyDeleteLine, keyPaste, keyKillEnd, keyYank:
+	}
+}
+
+// drawGrid draws the visible tiles in place of the list and preview.
+// Images are only (re)drawn when the grid moved or the area was cleared;
+// moving the selection within a page just redraws the titles.
+func drawGrid(out *bufio.Writer, state *appState, l layout) {
+	g := buildGridLayout(l, cellAspectRatio(state.cell))
+	scroll := gridScrollFor(state, g)
+	dirty := state.gridDirty || state.previewDirty || scroll != state.gridScroll || g != state.lastGrid
+	state.gridScroll, state.lastGrid = scroll, g
+	state.gridDirty, state.previewDirty = false, false
+
+	first := scroll * g.perRow
+	last := minInt(len(state.results), first+g.perRow*g.rows)
+	evictGridTiles(state, out, first, last)
+	if dirty {
+		state.screen.invalidate()
+		for i := 0; i < l.contentHeight; i++ {
+			state.screen.writeLineAt(out, l.contentTop+i, 1, "", l.cols)
+		}
+		for _, tile := range state.gridTiles {
+			tile.placed = false
+		}
+	}
+
+	for idx := first; idx < last; idx++ {
+		row, col := g.tilePos(l, idx-first)
+		tile := loadGridTile(state, idx, g)
+		if g.thumbRows > 0 {
+			// The placeholder line is blanked before the thumbnail that
+			// replaces it is drawn.
+			state.screen.writeCellsAt(out, row+g.thumbRows/2, col, gridPlaceholder(state, tile, g), g.thumbCols)
+			if !tile.placed {
+				drawGridTile(state, out, tile, g, row, col)
+			}
+		}
+		item := state.results[idx]
+		// The label the filter matched, so highlights land on it.
+		label := resultLabel(item)
+		prefix := "  "
+		var base []string
+		if idx == state.selected {
+			base = []string{"\x1b[1m", "\x1b[36m"}
+			prefix = styleIf(state.useColor, "> ", base...)
+		}
+		if state.useColor && state.filter != "" {
+			label = highlightRunes(label, filterPositions(label, state.filter), base...)
+		}
+		label = prefix + markPrefix(state, item) + styleIf(state.useColor, label, base...)
+		state.screen.writeCellsAt(out, row+g.thumbRows, col, label, g.thumbCols)
+	}
+}
+
+// gridPlaceholder is shown in the middle of a tile's box while its
+// thumbnail loads.
+func gridPlaceholder(state *appState, tile *gridTile, g gridLayout) string {
+	if !tile.loading {
+		return ""
+	}
+	text := "loading…"
+	if pad := (g.thumbCols - visibleRuneLen(text)) / 2; pad > 0 {
+		text = strings.Repeat(" ", pad) + text
+	}
+	return styleIf(state.useColor, text, "\x1b[90m")
+}
+
+// loadGridTile returns the tile for result idx, starting to fetch and
+// decode its thumbnail on first use. A tile without an image still shows
+// its title.
+func loadGridTile(state *appState, idx int, g gridLayout) *gridTile {
+	if tile, ok := state.gridTiles[idx]; ok {
+		return tile
+	}
+	if state.gridTiles == nil {
+		state.gridTiles = map[int]*gridTile{}
+	}
+	tile := &gridTile{}
+	state.gridTiles[idx] = tile
+
+	item := state.results[idx]
+	if item.PreviewURL == "" {
+		return tile
+	}
+	entry := state.cache[item.PreviewURL]
+	if entry != nil && !usesFrames(state) {
+		// Native iTerm2 playback sends the cached GIF as is.
+		tile.anim = &gifAnimation{ID: state.nextImageID, RawGIF: entry.RawGIF, Width: entry.Width, Height: entry.Height}
+		state.nextImageID++
+		return tile
+	}
+	tile.loading = true
+	startGridFetch(state, idx, item.PreviewURL, entry, g)
+	return tile
+}
+
+// startGridFetch fetches (unless cached) and decodes a tile's thumbnail in
+// the background; at most gridFetchWorkers run at once.
+func startGridFetch(state *appState, idx int, url string, entry *gifCacheEntry, g gridLayout) {
+	if state.gridResults == nil {
+		state.gridResults = make(chan gridTileResult, 64)
+		state.gridSlots = make(chan struct{}, gridFetchWorkers)
+	}
+	results, slots := state.gridResults, state.gridSlots
+	decode := usesFrames(state)
+	opts := decodeOptions(state, gifdecode.CellBox{})
+	aspect := cellAspectRatio(state.cell)
+	var cached []byte
+	if entry != nil {
+		cached = entry.RawGIF
+	}
+	go func() {
+		slots <- struct{}{}
+		defer func() { <-slots }()
+		res := gridTileResult{idx: idx, url: url}
+		data := cached
+		if data == nil {
+			data, res.err = fetchGIF(url)
+			if res.err == nil {
+				res.entry = newGIFCacheEntry(data)
+			}
+		}
+		if res.err == nil && decode {
+			w, h := gifSize(data)
+			cols, rows := fitPreviewSize(g.thumbCols, g.thumbRows, &gifAnimation{Width: w, Height: h}, aspect)
+			if cols > 0 && rows > 0 {
+				opts.TargetCells = gifdecode.CellBox{Cols: cols, Rows: rows}
+			}
+			res.frames, res.err = gifdecode.Decode(data, opts)
+		}
+		results <- res
+	}()
+}
+
+// pollGridTiles applies the thumbnails that finished loading.
+func pollGridTiles(state *appState) {
+	for {
+		select {
+		case res := <-state.gridResults:
+			applyGridTile(state, res)
+		default:
+			return
+		}
+	}
+}
+
+// applyGridTile caches a fetched GIF and gives its tile the thumbnail,
+// unless the tile was evicted or now shows another result.
+func applyGridTile(state *appState, res gridTileResult) {
+	if res.entry != nil && state.cache[res.url] == nil {
+		state.cache[res.url] = res.entry
+	}
+	tile, ok := state.gridTiles[res.idx]
+	if !ok || !tile.loading || res.idx >= len(state.results) || state.results[res.idx].PreviewURL != res.url {
+		return
+	}
+	tile.loading = false
+	tile.placed = false
+	state.renderDirty = true
+	entry := state.cache[res.url]
+	if res.err != nil || entry == nil || (usesFrames(state) && (res.frames == nil || len(res.frames.Frames) == 0)) {
+		return
+	}
+	anim := &gifAnimation{ID: state.nextImageID, RawGIF: entry.RawGIF, Width: entry.Width, Height: entry.Height}
+	if res.frames != nil {
+		anim.Frames = res.frames.Frames
+		if anim.Width <= 0 || anim.Height <= 0 {
+			anim.Width, anim.Height = res.frames.Width, res.frames.Height
+		}
+	}
+	state.nextImageID++
+	tile.anim = anim
+}
+
+// drawGridTile draws a thumbnail centered in its box. Kitty and native
+// iTerm2 animate it; sixel, text and software playback show the first
+// frame.
+func drawGridTile(state *appState, out *bufio.Writer, tile *gridTile, g gridLayout, row, col int) {
+	tile.placed = true
+	anim := tile.anim
+	if anim == nil {
+		return
+	}
+	cols, rows := fitPreviewSize(g.thumbCols, g.thumbRows, anim, cellAspectRatio(state.cell))
+	col += (g.thumbCols - cols) / 2
+	if usesFrames(state) && len(anim.Frames) == 0 {
+		return
+	}
+	saveCursor(out)
+	moveCursor(out, row, col)
+	switch state.inline {
+	case termcaps.InlineKitty:
+		switch {
+		case tile.sent:
+			state.kitty.PlaceImage(out, anim.ID, cols, rows)
+		case state.useSoftwareAnim:
+			state.kitty.SendFrame(out, anim.ID, anim.Frames[0], cols, rows)
+		default:
+			state.kitty.SendAnimation(out, anim.ID, anim.Frames, cols, rows)
+		}
+		tile.sent = true
+	case termcaps.InlineIterm:
+		file := iterm.File{Name: "gifgrep.gif", Data: anim.RawGIF, WidthCells: cols, HeightCells: rows}
+		if usesFrames(state) {
+			file = iterm.File{Name: "gifgrep.png", Data: anim.Frames[0].PNG, WidthCells: cols, HeightCells: rows}
+		}
+		iterm.SendInlineFile(out, file)
+	case termcaps.InlineSixel:
+		if img := sixelFrame(anim, 0); img != nil {
+			_, _ = out.Write(img.Data)
+		}
+	case termcaps.InlineText:
+		for i, line := range textFrame(anim, 0, cols, rows, state.colorDepth) {
+			moveCursor(out, row+i, col)
+			_, _ = out.WriteString(line)
+		}
+	case termcaps.InlineNone:
+	}
+	restoreCursor(out)
+}
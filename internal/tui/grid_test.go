@@ -0,0 +1,224 @@
+package tui
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steipete/gifgrep/internal/model"
+	"github.com/steipete/gifgrep/internal/termcaps"
+	"github.com/steipete/gifgrep/internal/testutil"
+)
+
+func gridTestState(n int) *appState {
+	gifData := testutil.MakeTestGIF()
+	state := &appState{
+		mode:     modeBrowse,
+		grid:     true,
+		lastRows: 30,
+		lastCols: 100,
+		inline:   termcaps.InlineKitty,
+		cache:    map[string]*gifCacheEntry{},
+		// Kitty image IDs start at 1, as in runWith.
+		nextImageID: 1,
+	}
+	for i := 0; i < n; i++ {
+		url := fmt.Sprintf("https://example.test/%d.gif", i)
+		state.results = append(state.results, model.Result{ID: fmt.Sprint(i), Title: fmt.Sprintf("GIF %d", i), PreviewURL: url})
+		state.cache[url] = newGIFCacheEntry(gifData)
+	}
+	return state
+}
+
+// waitGridTiles applies tile results, as the run loop does, until no
+// visible tile is loading.
+func waitGridTiles(t *testing.T, state *appState) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		loading := false
+		for _, tile := range state.gridTiles {
+			loading = loading || tile.loading
+		}
+		if !loading {
+			return
+		}
+		select {
+		case res := <-state.gridResults:
+			applyGridTile(state, res)
+		case <-timeout:
+			t.Fatalf("grid tiles did not load")
+		}
+	}
+}
+
+func TestBuildGridLayout(t *testing.T) {
+	l := buildLayoutFor(nil, 30, 100, 0.5)
+	g := buildGridLayout(l, 0.5)
+	// 21-column thumbnails at 4:3 are 8 rows; title and gap make 10.
+	if g.perRow != 4 || g.thumbCols != 21 || g.thumbRows != 8 || g.tileRows != 10 || g.rows != 2 {
+		t.Fatalf("unexpected grid: %+v", g)
+	}
+	if row, col := g.tilePos(l, 5); row != l.contentTop+10 || col != 1+gridTileCols {
+		t.Fatalf("unexpected tile position %d,%d", row, col)
+	}
+
+	small := buildGridLayout(buildLayoutFor(nil, 8, 15, 0.5), 0.5)
+	if small.perRow != 1 || small.rows != 1 || small.thumbCols != 15 || small.thumbRows != 3 {
+		t.Fatalf("unexpected small grid: %+v", small)
+	}
+}
+
+func TestGridNavigation(t *testing.T) {
+	state := gridTestState(10)
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+
+	steps := []struct {
+		kind keyKind
+		want int
+	}{
+		{keyRight, 1},
+		{keyDown, 5},
+		{keyDown, 9},
+		{keyUp, 5},
+		{keyLeft, 4},
+		{keyUp, 0},
+		{keyUp, 0},
+		{keyPageDown, 8},
+		{keyPageUp, 0},
+		{keyEnd, 9},
+	}
+	for i, step := range steps {
+		handleInput(state, inputEvent{kind: step.kind}, out)
+		if state.selected != step.want {
+			t.Fatalf("step %d: selected %d, want %d", i, state.selected, step.want)
+		}
+	}
+	if state.currentAnim != nil {
+		t.Fatalf("expected no single preview in grid mode")
+	}
+}
+
+func TestGridDrawsAndEvictsTiles(t *testing.T) {
+	state := gridTestState(10)
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+
+	render(state, out, state.lastRows, state.lastCols)
+	_ = out.Flush()
+	// Thumbnails load in the background; the tiles show a placeholder.
+	if len(state.gridTiles) != 8 || strings.Contains(buf.String(), "a=T") || strings.Count(buf.String(), "loading…") != 8 {
+		t.Fatalf("expected 8 placeholders, got %d tiles: %q", len(state.gridTiles), buf.String())
+	}
+	if !strings.Contains(buf.String(), "GIF 0") || !strings.Contains(buf.String(), "GIF 7") {
+		t.Fatalf("expected tile titles while loading")
+	}
+	buf.Reset()
+	waitGridTiles(t, state)
+	render(state, out, state.lastRows, state.lastCols)
+	_ = out.Flush()
+	// Two rows of four tiles fit; each gets its own image.
+	if len(state.gridTiles) != 8 || strings.Count(buf.String(), "a=T") != 8 {
+		t.Fatalf("expected 8 tiles uploaded, got %d tiles, %d uploads", len(state.gridTiles), strings.Count(buf.String(), "a=T"))
+	}
+	ids := map[uint32]bool{}
+	for _, tile := range state.gridTiles {
+		ids[tile.anim.ID] = true
+	}
+	if len(ids) != 8 {
+		t.Fatalf("expected distinct image ids, got %v", ids)
+	}
+	if strings.Contains(buf.String(), "loading…") {
+		t.Fatalf("expected the placeholders replaced, got %q", buf.String())
+	}
+
+	// Moving within the page only redraws titles.
+	buf.Reset()
+	handleInput(state, inputEvent{kind: keyRight}, out)
+	render(state, out, state.lastRows, state.lastCols)
+	_ = out.Flush()
+	if strings.Contains(buf.String(), "\x1b_G") {
+		t.Fatalf("expected no image traffic, got %q", buf.String())
+	}
+
+	// Scrolling to the third row evicts the first.
+	firstID := state.gridTiles[0].anim.ID
+	buf.Reset()
+	state.selected = 9
+	render(state, out, state.lastRows, state.lastCols)
+	_ = out.Flush()
+	if _, ok := state.gridTiles[0]; ok || state.gridScroll != 1 {
+		t.Fatalf("expected first row evicted, scroll %d", state.gridScroll)
+	}
+	if !strings.Contains(buf.String(), fmt.Sprintf("a=d,d=I,i=%d,", firstID)) {
+		t.Fatalf("expected evicted image deleted, got %q", buf.String())
+	}
+	if len(state.gridTiles) != 6 {
+		t.Fatalf("expected 6 visible tiles, got %d", len(state.gridTiles))
+	}
+}
+
+func TestGridHighlightsFilterMatches(t *testing.T) {
+	state := gridTestState(3)
+	state.useColor = true
+	state.results[2].Title = ""
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+
+	handleInput(state, inputEvent{kind: keyRune, ch: 'f', mod: modCtrl}, out)
+	handleInput(state, inputEvent{kind: keyPaste, text: "2"}, out)
+	buf.Reset()
+	render(state, out, state.lastRows, state.lastCols)
+	_ = out.Flush()
+	// The untitled result shows and matches its ID, selected as the only match.
+	want := "\x1b[1m\x1b[36m> \x1b[0m\x1b[1m\x1b[36m\x1b[1m\x1b[33m2\x1b[0m\x1b[1m\x1b[36m\x1b[0m"
+	if !strings.Contains(buf.String(), want) {
+		t.Fatalf("expected the highlighted ID, got %q", buf.String())
+	}
+}
+
+func TestGridToggle(t *testing.T) {
+	state := gridTestState(3)
+	state.grid = false
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+
+	handleInput(state, inputEvent{kind: keyRune, ch: 'g'}, out)
+	if !state.grid || state.currentAnim != nil {
+		t.Fatalf("expected grid mode without a preview")
+	}
+	render(state, out, state.lastRows, state.lastCols)
+	waitGridTiles(t, state)
+	render(state, out, state.lastRows, state.lastCols)
+	id := state.gridTiles[0].anim.ID
+
+	buf.Reset()
+	handleInput(state, inputEvent{kind: keyRune, ch: 'g'}, out)
+	_ = out.Flush()
+	if state.grid || len(state.gridTiles) != 0 || state.currentAnim == nil {
+		t.Fatalf("expected list mode with the preview back")
+	}
+	if !strings.Contains(buf.String(), fmt.Sprintf("a=d,d=I,i=%d,", id)) {
+		t.Fatalf("expected tiles deleted, got %q", buf.String())
+	}
+}
+
+func TestGridMouse(t *testing.T) {
+	state := gridTestState(10)
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+	l := buildLayout(state, state.lastRows, state.lastCols)
+
+	handleInput(state, inputEvent{kind: mouseClick, row: l.contentTop + 12, col: 2*gridTileCols + 3}, out)
+	if state.selected != 6 {
+		t.Fatalf("expected click to select tile 6, got %d", state.selected)
+	}
+	handleInput(state, inputEvent{kind: mouseWheelUp, row: l.contentTop, col: 1}, out)
+	if state.selected != 2 {
+		t.Fatalf("expected wheel to move a row up, got %d", state.selected)
+	}
+}
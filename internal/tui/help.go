@@ -0,0 +1,149 @@
+package tui
+
+import (
+	"bufio"
+	"strings"
+)
+
+// helpGap separates the columns of the key help.
+const helpGap = "    "
+
+// helpEntry is one line of the key help: the keys bound to an action.
+type helpEntry struct {
+	keys  string
+	label string
+}
+
+// helpEntries lists the bound actions of km in help order.
+func helpEntries(km *keymap) []helpEntry {
+	entries := make([]helpEntry, 0, len(actionDefs))
+	for _, def := range actionDefs {
+		keys := km.keys[def.name]
+		if len(keys) == 0 {
+			continue
+		}
+		labels := make([]string, 0, len(keys))
+		for _, k := range keys {
+			labels = append(labels, k.label())
+		}
+		entries = append(entries, helpEntry{keys: strings.Join(labels, " "), label: def.label})
+	}
+	return entries
+}
+
+// toggleHelp shows or hides the key help. It covers the content area, so
+// the preview (or the grid's tiles) is dropped and brought back after.
+func toggleHelp(state *appState, out *bufio.Writer) {
+	state.help = !state.help
+	state.helpScroll = 0
+	if state.grid {
+		resetGrid(state, out)
+	}
+	state.screen.invalidate()
+	loadSelectedImage(state)
+	state.renderDirty = true
+}
+
+// helpLayout arranges the help in columns, filled top to bottom.
+type helpLayout struct {
+	perRow, rows         int
+	keyWidth, labelWidth int
+}
+
+func buildHelpLayout(entries []helpEntry, cols int) helpLayout {
+	var h helpLayout
+	for _, e := range entries {
+		h.keyWidth = maxInt(h.keyWidth, visibleRuneLen(e.keys))
+		h.labelWidth = maxInt(h.labelWidth, visibleRuneLen(e.label))
+	}
+	colWidth := h.keyWidth + 1 + h.labelWidth
+	h.perRow = maxInt(1, (cols+runeLen(helpGap))/(colWidth+runeLen(helpGap)))
+	h.rows = (len(entries) + h.perRow - 1) / h.perRow
+	return h
+}
+
+// helpVisibleRows is how many help rows fit under its title.
+func helpVisibleRows(layout layout) int {
+	return maxInt(0, layout.contentHeight-2)
+}
+
+func drawHelp(out *bufio.Writer, state *appState, layout layout) {
+	km := activeKeymap(state)
+	entries := helpEntries(km)
+	h := buildHelpLayout(entries, layout.cols)
+	visible := helpVisibleRows(layout)
+	state.helpScroll = minInt(maxInt(0, h.rows-visible), maxInt(0, state.helpScroll))
+
+	closeKeys := "esc"
+	if key := km.keyLabel(actHelp); key != "" {
+		closeKeys = key + " or esc"
+	}
+	title := styleIf(state.useColor, "Keys", "\x1b[1m") + styleIf(state.useColor, " ("+km.name+") · "+closeKeys+" closes", "\x1b[90m")
+	if h.rows > visible {
+		title += styleIf(state.useColor, " · ↑↓ scrolls", "\x1b[90m")
+	}
+	state.screen.writeLineAt(out, layout.contentTop, 1, title, layout.cols)
+	state.screen.writeLineAt(out, layout.contentTop+1, 1, "", layout.cols)
+
+	for i := 0; i < visible; i++ {
+		row := state.helpScroll + i
+		parts := make([]string, 0, h.perRow)
+		for c := 0; c < h.perRow && row < h.rows; c++ {
+			idx := c*h.rows + row
+			if idx >= len(entries) {
+				break
+			}
+			e := entries[idx]
+			keys := e.keys + strings.Repeat(" ", h.keyWidth-visibleRuneLen(e.keys))
+			label := e.label + strings.Repeat(" ", h.labelWidth-visibleRuneLen(e.label))
+			parts = append(parts, styleIf(state.useColor, keys, "\x1b[1m", "\x1b[36m")+" "+label)
+		}
+		line := strings.TrimRight(strings.Join(parts, helpGap), " ")
+		state.screen.writeLineAt(out, layout.contentTop+2+i, 1, line, layout.cols)
+	}
+}
+
+// handleHelpInput handles input while the help is shown: it scrolls and
+// closes the help, and quit still quits. Other keys are ignored.
+func handleHelpInput(state *appState, ev inputEvent, out *bufio.Writer) bool {
+	switch ev.kind {
+	case mouseWheelUp:
+		scrollHelp(state, -wheelStep)
+		return false
+	case mouseWheelDown:
+		scrollHelp(state, wheelStep)
+		return false
+	case mouseClick:
+		toggleHelp(state, out)
+		return false
+	case keyRune, keyEnter, keyBackspace, keyEsc, keyUp, keyDown, keyCtrlC, keyUnknown, keyLeft, keyRight, keyHome, keyEnd,
+		keyPageUp, keyPageDown, keyDelete, keyTab, keyDeleteWord, keyDeleteLine, keyPaste, keyKillEnd, keyYank:
+	}
+	act, _ := activeKeymap(state).lookup(ev)
+	if act == actQuit {
+		return true
+	}
+	if act == actHelp || ev.kind == keyEsc {
+		toggleHelp(state, out)
+		return false
+	}
+	page := maxInt(1, helpVisibleRows(buildLayout(state, state.lastRows, state.lastCols)))
+	deltas := map[action]int{
+		actUp:       -1,
+		actDown:     1,
+		actPageUp:   -page,
+		actPageDown: page,
+		actTop:      -len(actionDefs),
+		actBottom:   len(actionDefs),
+	}
+	if delta, ok := deltas[act]; ok {
+		scrollHelp(state, delta)
+	}
+	return false
+}
+
+// scrollHelp moves the help by delta rows; drawHelp clamps it.
+func scrollHelp(state *appState, delta int) {
+	state.helpScroll = maxInt(0, state.helpScroll+delta)
+	state.renderDirty = true
+}
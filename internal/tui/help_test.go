@@ -0,0 +1,152 @@
+package tui
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHelpOverlay(t *testing.T) {
+	state := gridTestState(3)
+	state.grid = false
+	state.screen = newScreenBuffer()
+	loadSelectedImage(state)
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+
+	handleInput(state, inputEvent{kind: keyRune, ch: '?'}, out)
+	if !state.help || state.currentAnim != nil {
+		t.Fatalf("expected the help without a preview")
+	}
+	render(state, out, state.lastRows, state.lastCols)
+	_ = out.Flush()
+	for _, want := range []string{"Keys (default)", "d       Download", "S       Save sheet", "q ^C", "? Help"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Fatalf("expected %q in the help, got %q", want, buf.String())
+		}
+	}
+
+	if handleInput(state, inputEvent{kind: keyRune, ch: 'z'}, out) || !state.help || state.mode != modeBrowse {
+		t.Fatalf("expected unbound keys ignored while the help is open")
+	}
+	handleInput(state, inputEvent{kind: keyEsc}, out)
+	if state.help || state.currentAnim == nil || state.mode != modeBrowse {
+		t.Fatalf("expected esc to close the help and bring the preview back")
+	}
+
+	handleInput(state, inputEvent{kind: keyRune, ch: '?'}, out)
+	if !handleInput(state, inputEvent{kind: keyRune, ch: 'q'}, out) {
+		t.Fatalf("expected quit to work in the help")
+	}
+}
+
+func TestHelpScroll(t *testing.T) {
+	state := gridTestState(1)
+	state.grid = false
+	state.lastRows, state.lastCols = 12, 45
+	state.screen = newScreenBuffer()
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+
+	handleInput(state, inputEvent{kind: keyRune, ch: '?'}, out)
+	for i := 0; i < 100; i++ {
+		handleInput(state, inputEvent{kind: keyDown}, out)
+	}
+	render(state, out, state.lastRows, state.lastCols)
+	_ = out.Flush()
+	l := buildLayout(state, state.lastRows, state.lastCols)
+	h := buildHelpLayout(helpEntries(defaultKeymap), l.cols)
+	if h.perRow != 1 || state.helpScroll != h.rows-helpVisibleRows(l) {
+		t.Fatalf("expected the help scrolled to its end, got %d of %d rows", state.helpScroll, h.rows)
+	}
+	if !strings.Contains(buf.String(), "↑↓ scrolls") || !strings.Contains(buf.String(), "Quit") {
+		t.Fatalf("expected the last entries and a scroll hint, got %q", buf.String())
+	}
+	handleInput(state, inputEvent{kind: mouseWheelUp}, out)
+	if state.helpScroll != h.rows-helpVisibleRows(l)-wheelStep {
+		t.Fatalf("expected the wheel to scroll, got %d", state.helpScroll)
+	}
+	handleInput(state, inputEvent{kind: mouseClick, row: 5, col: 5}, out)
+	if state.help {
+		t.Fatalf("expected a click to close the help")
+	}
+}
+
+func TestDetailPane(t *testing.T) {
+	state := gridTestState(2)
+	state.results[1].Tags = []string{"cat", "keyboard"}
+	state.results[1].Width, state.results[1].Height = 498, 280
+	state.results[1].URL = "https://example.test/full/1.gif"
+	state.selected = 1
+	saved := filepath.Join(t.TempDir(), "GIF_1.gif")
+	if err := os.WriteFile(saved, []byte("gif"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	trackSavedPath(state, state.results[1], saved)
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+
+	handleInput(state, inputEvent{kind: keyTab}, out)
+	if state.grid || !state.detail {
+		t.Fatalf("expected details to leave the grid")
+	}
+	text := strings.Join(detailLines(state, 60), "\n")
+	for _, want := range []string{
+		"GIF 1", "ID       1", "Size     498×280", "Tags     cat, keyboard",
+		"2×2 · 2 frames · 0.12s", "URL      https://example.test/full/1.gif", "Saved    " + saved,
+	} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected %q in the details, got %q", want, text)
+		}
+	}
+	if lines := detailLines(state, 30); !strings.Contains(strings.Join(lines, "\n"), "URL      https://example.test/\n         full/1.gif") {
+		t.Fatalf("expected the URL wrapped, got %q", lines)
+	}
+
+	l := buildLayout(state, state.lastRows, state.lastCols)
+	handleInput(state, inputEvent{kind: mouseClick, row: l.contentTop, col: l.listCol}, out)
+	if state.selected != 1 {
+		t.Fatalf("expected clicks on the details not to select, got %d", state.selected)
+	}
+	handleInput(state, inputEvent{kind: keyTab}, out)
+	if state.detail {
+		t.Fatalf("expected the detail pane closed")
+	}
+	handleInput(state, inputEvent{kind: keyRune, ch: 'i'}, out)
+	if !state.detail || state.mode != modeBrowse {
+		t.Fatalf("expected i to open the detail pane")
+	}
+}
+
+func TestPreviewSummaryCountsSourceFrames(t *testing.T) {
+	state := gridTestState(2)
+	// The preview decode keeps 60 of these.
+	entry := newGIFCacheEntry(longTestGIF(t, 90))
+	state.cache["https://example.test/0.gif"] = entry
+	loadSelectedImage(state)
+	if entry.Stats != nil {
+		t.Fatalf("expected the stats left until the detail pane asks")
+	}
+	if got := previewSummary(state, "https://example.test/0.gif"); !strings.HasSuffix(got, "2×2 · 90 frames · 1.80s") {
+		t.Fatalf("expected every source frame, got %q", got)
+	}
+	entry.RawGIF = nil
+	if got := previewSummary(state, "https://example.test/0.gif"); !strings.HasSuffix(got, "90 frames · 1.80s") {
+		t.Fatalf("expected the stats kept, got %q", got)
+	}
+	state.cache["https://example.test/1.gif"] = newGIFCacheEntry([]byte("GIF89a broken"))
+	if got := previewSummary(state, "https://example.test/1.gif"); !strings.Contains(got, "error: ") {
+		t.Fatalf("expected the decode error, got %q", got)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	for n, want := range map[int]string{512: "512 B", 2048: "2.0 KB", 3 << 20: "3.0 MB"} {
+		if got := formatBytes(n); got != want {
+			t.Fatalf("formatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,414 @@
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// keyMod is the modifier state of a key, as encoded in CSI parameters
+// (value-1: shift 1, alt 2, ctrl 4).
+type keyMod int
+
+const (
+	modShift keyMod = 1 << iota
+	modAlt
+	modCtrl
+)
+
+// maxPaste bounds how much of a bracketed paste is kept; the rest is read
+// and dropped.
+const maxPaste = 64 << 10
+
+// escDelay is how long an ESC waits for the rest of a sequence before it
+// counts as the Esc key. Over SSH or tmux a sequence can arrive split
+// across reads.
+const escDelay = 30 * time.Millisecond
+
+// inputDecoder turns the terminal's input byte stream into inputEvents:
+// UTF-8 text, control keys, CSI and SS3 sequences with modifiers, SGR mouse
+// reports, bracketed paste and the Kitty keyboard protocol (CSI ... u).
+type inputDecoder struct {
+	r   *bufio.Reader
+	src *chunkReader
+}
+
+func newInputDecoder(r io.Reader) *inputDecoder {
+	src := newChunkReader(r)
+	return &inputDecoder{r: bufio.NewReader(src), src: src}
+}
+
+// chunkReader reads from r on its own goroutine, so the decoder can wait a
+// bounded time for more input.
+type chunkReader struct {
+	chunks  chan []byte
+	err     error // set before chunks is closed
+	pending []byte
+	closed  bool
+}
+
+func newChunkReader(r io.Reader) *chunkReader {
+	c := &chunkReader{chunks: make(chan []byte)}
+	go func() {
+		for {
+			buf := make([]byte, 4096)
+			n, err := r.Read(buf)
+			if n > 0 {
+				c.chunks <- buf[:n]
+			}
+			if err != nil {
+				c.err = err
+				close(c.chunks)
+				return
+			}
+		}
+	}()
+	return c
+}
+
+func (c *chunkReader) Read(p []byte) (int, error) {
+	if len(c.pending) == 0 {
+		if c.closed {
+			return 0, c.err
+		}
+		if chunk, ok := <-c.chunks; !c.receive(chunk, ok) {
+			return 0, c.err
+		}
+	}
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+// wait reports whether input is ready within d; the end of the input
+// counts as ready, so the next read returns the error.
+func (c *chunkReader) wait(d time.Duration) bool {
+	if len(c.pending) > 0 || c.closed {
+		return true
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case chunk, ok := <-c.chunks:
+		c.receive(chunk, ok)
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+func (c *chunkReader) receive(chunk []byte, ok bool) bool {
+	if !ok {
+		c.closed = true
+		return false
+	}
+	c.pending = chunk
+	return true
+}
+
+// next returns the next event; an error means the input is gone.
+func (d *inputDecoder) next() (inputEvent, error) {
+	b, err := d.r.ReadByte()
+	if err != nil {
+		return inputEvent{}, err
+	}
+	switch b {
+	case 0x1b:
+		return d.escape()
+	case 0x7f:
+		return inputEvent{kind: keyBackspace}, nil
+	}
+	if b < 0x20 {
+		return controlEvent(b), nil
+	}
+	if b < utf8.RuneSelf {
+		return inputEvent{kind: keyRune, ch: rune(b)}, nil
+	}
+	_ = d.r.UnreadByte()
+	r, size, err := d.r.ReadRune()
+	if err != nil {
+		return inputEvent{}, err
+	}
+	if r == utf8.RuneError && size == 1 {
+		return inputEvent{kind: keyUnknown}, nil
+	}
+	return inputEvent{kind: keyRune, ch: r}, nil
+}
+
+// escape decodes what follows an ESC. An ESC with nothing behind it within
+// escDelay is the Esc key itself.
+func (d *inputDecoder) escape() (inputEvent, error) {
+	if d.r.Buffered() == 0 && !d.src.wait(escDelay) {
+		return inputEvent{kind: keyEsc}, nil
+	}
+	b, err := d.r.ReadByte()
+	if err != nil {
+		return inputEvent{kind: keyEsc}, nil
+	}
+	switch b {
+	case '[':
+		return d.csi()
+	case 'O':
+		return d.ss3()
+	case 0x7f, 0x08:
+		return inputEvent{kind: keyDeleteWord, mod: modAlt}, nil
+	case 0x1b:
+		_ = d.r.UnreadByte()
+		return inputEvent{kind: keyEsc}, nil
+	}
+	// Alt+key arrives as ESC followed by the key.
+	_ = d.r.UnreadByte()
+	ev, err := d.next()
+	if err != nil {
+		return inputEvent{kind: keyEsc}, nil
+	}
+	ev.mod |= modAlt
+	return ev, nil
+}
+
+func (d *inputDecoder) csi() (inputEvent, error) {
+	var sb strings.Builder
+	final := byte(0)
+	for sb.Len() < 64 {
+		b, err := d.r.ReadByte()
+		if err != nil {
+			return inputEvent{kind: keyUnknown}, nil
+		}
+		if b >= 0x40 && b <= 0x7e {
+			final = b
+			break
+		}
+		sb.WriteByte(b)
+	}
+	params := sb.String()
+	if final == 0 {
+		return inputEvent{kind: keyUnknown}, nil
+	}
+	if strings.HasPrefix(params, "<") && (final == 'M' || final == 'm') {
+		return parseSGRMouse(params[1:], final), nil
+	}
+	nums := csiParams(params)
+	mod := modifiers(nums)
+	switch final {
+	case 'A', 'B', 'C', 'D', 'H', 'F':
+		return inputEvent{kind: cursorKey(final), mod: mod}, nil
+	case '~':
+		if nums[0] == 200 {
+			return d.paste(), nil
+		}
+		return inputEvent{kind: tildeKey(nums[0]), mod: mod}, nil
+	case 'u':
+		return kittyKey(nums[0], mod), nil
+	default:
+		return inputEvent{kind: keyUnknown}, nil
+	}
+}
+
+// ss3 decodes ESC O x, sent for cursor keys in application mode.
+func (d *inputDecoder) ss3() (inputEvent, error) {
+	b, err := d.r.ReadByte()
+	if err != nil {
+		return inputEvent{kind: keyUnknown}, nil
+	}
+	return inputEvent{kind: cursorKey(b)}, nil
+}
+
+// paste reads a bracketed paste up to ESC [ 201 ~.
+func (d *inputDecoder) paste() inputEvent {
+	const end = "\x1b[201~"
+	var buf []byte
+	for {
+		b, err := d.r.ReadByte()
+		if err != nil {
+			break
+		}
+		buf = append(buf, b)
+		if len(buf) >= len(end) && string(buf[len(buf)-len(end):]) == end {
+			buf = buf[:len(buf)-len(end)]
+			break
+		}
+		if len(buf) > maxPaste+len(end) {
+			// Keep the tail short enough to still spot the end marker.
+			tail := append([]byte(nil), buf[len(buf)-len(end):]...)
+			buf = append(buf[:maxPaste], tail...)
+		}
+	}
+	if len(buf) > maxPaste {
+		buf = buf[:maxPaste]
+	}
+	return inputEvent{kind: keyPaste, text: string(buf)}
+}
+
+// csiParams parses "1;5" into numbers; missing ones are 0 and sub-parameters
+// (Kitty's "97:65") keep only their first value. The result always has at
+// least two entries.
+func csiParams(params string) []int {
+	params = strings.TrimLeft(params, "?>=")
+	fields := strings.Split(params, ";")
+	nums := make([]int, maxInt(2, len(fields)))
+	for i, field := range fields {
+		if j := strings.IndexByte(field, ':'); j >= 0 {
+			field = field[:j]
+		}
+		nums[i], _ = strconv.Atoi(field)
+	}
+	return nums
+}
+
+func modifiers(nums []int) keyMod {
+	if nums[1] <= 1 {
+		return 0
+	}
+	return keyMod(nums[1]-1) & (modShift | modAlt | modCtrl)
+}
+
+func cursorKey(final byte) keyKind {
+	switch final {
+	case 'A':
+		return keyUp
+	case 'B':
+		return keyDown
+	case 'C':
+		return keyRight
+	case 'D':
+		return keyLeft
+	case 'H':
+		return keyHome
+	case 'F':
+		return keyEnd
+	default:
+		return keyUnknown
+	}
+}
+
+// controlEvent decodes a C0 control byte other than ESC: Enter, Backspace,
+// Tab, the editing keys ctrlKey knows, or else Ctrl plus a letter (or
+// space, for NUL) for the keymap.
+func controlEvent(b byte) inputEvent {
+	switch b {
+	case '\r', '\n':
+		return inputEvent{kind: keyEnter}
+	case 0x08:
+		return inputEvent{kind: keyBackspace}
+	case '\t':
+		return inputEvent{kind: keyTab}
+	case 0x00:
+		return inputEvent{kind: keyRune, ch: ' ', mod: modCtrl}
+	}
+	if kind := ctrlKey(b); kind != keyUnknown {
+		return inputEvent{kind: kind}
+	}
+	if b <= 0x1a {
+		return inputEvent{kind: keyRune, ch: rune('a' + b - 1), mod: modCtrl}
+	}
+	return inputEvent{kind: keyUnknown}
+}
+
+// ctrlKey maps the C0 control codes gifgrep binds (readline's, mostly).
+func ctrlKey(b byte) keyKind {
+	switch b {
+	case 0x01: // Ctrl-A
+		return keyHome
+	case 0x02: // Ctrl-B
+		return keyLeft
+	case 0x03: // Ctrl-C
+		return keyCtrlC
+	case 0x04: // Ctrl-D
+		return keyDelete
+	case 0x05: // Ctrl-E
+		return keyEnd
+	case 0x0b: // Ctrl-K
+		return keyKillEnd
+	case 0x15: // Ctrl-U
+		return keyDeleteLine
+	case 0x17: // Ctrl-W
+		return keyDeleteWord
+	case 0x19: // Ctrl-Y
+		return keyYank
+	default:
+		return keyUnknown
+	}
+}
+
+func tildeKey(n int) keyKind {
+	switch n {
+	case 1, 7:
+		return keyHome
+	case 4, 8:
+		return keyEnd
+	case 3:
+		return keyDelete
+	case 5:
+		return keyPageUp
+	case 6:
+		return keyPageDown
+	default:
+		return keyUnknown
+	}
+}
+
+// kittyKey maps a Kitty keyboard protocol key (CSI code ; mods u). With the
+// "disambiguate" flag, Esc and Ctrl/Alt combinations arrive this way.
+func kittyKey(code int, mod keyMod) inputEvent {
+	switch code {
+	case 27:
+		return inputEvent{kind: keyEsc, mod: mod}
+	case 13:
+		return inputEvent{kind: keyEnter, mod: mod}
+	case 9:
+		return inputEvent{kind: keyTab, mod: mod}
+	case 127, 8:
+		if mod&(modAlt|modCtrl) != 0 {
+			return inputEvent{kind: keyDeleteWord, mod: mod}
+		}
+		return inputEvent{kind: keyBackspace, mod: mod}
+	}
+	if mod&modCtrl != 0 && code >= 'a' && code <= 'z' {
+		// Decoded like the control byte it stands for.
+		ev := controlEvent(byte(code) & 0x1f)
+		ev.mod |= mod
+		return ev
+	}
+	// Kitty's functional keys live in the private use area.
+	if code < 0x20 || (code >= 0xe000 && code <= 0xf8ff) || !utf8.ValidRune(rune(code)) {
+		return inputEvent{kind: keyUnknown}
+	}
+	return inputEvent{kind: keyRune, ch: rune(code), mod: mod}
+}
+
+// enableKeyboard turns on bracketed paste and, when the terminal has it,
+// the Kitty keyboard protocol with the "disambiguate escape codes" flag.
+func enableKeyboard(out *bufio.Writer, kittyKeyboard bool) {
+	_, _ = fmt.Fprint(out, "\x1b[?2004h")
+	if kittyKeyboard {
+		_, _ = fmt.Fprint(out, "\x1b[>1u")
+	}
+}
+
+func disableKeyboard(out *bufio.Writer, kittyKeyboard bool) {
+	if kittyKeyboard {
+		_, _ = fmt.Fprint(out, "\x1b[<u")
+	}
+	_, _ = fmt.Fprint(out, "\x1b[?2004l")
+}
+
+// pasteText makes pasted text fit the one-line search box: line breaks and
+// tabs become spaces, other control characters are dropped.
+func pasteText(text string) string {
+	var sb strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '\r' || r == '\n' || r == '\t':
+			sb.WriteByte(' ')
+		case unicode.IsControl(r) || r == utf8.RuneError:
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
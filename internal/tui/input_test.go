@@ -0,0 +1,176 @@
+package tui
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func decodeAll(t *testing.T, input string) []inputEvent {
+	t.Helper()
+	dec := newInputDecoder(strings.NewReader(input))
+	var events []inputEvent
+	for {
+		ev, err := dec.next()
+		if errors.Is(err, io.EOF) {
+			return events
+		}
+		if err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		events = append(events, ev)
+	}
+}
+
+func TestInputDecoder(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+		want  []inputEvent
+	}{
+		{"utf8", "café ñ", []inputEvent{
+			{kind: keyRune, ch: 'c'}, {kind: keyRune, ch: 'a'}, {kind: keyRune, ch: 'f'}, {kind: keyRune, ch: 'é'},
+			{kind: keyRune, ch: ' '}, {kind: keyRune, ch: 'ñ'},
+		}},
+		{"invalid utf8", "\xff", []inputEvent{{kind: keyUnknown}}},
+		{"controls", "\x03\r\x7f\t\x17\x15\x01\x05\x02\x06\x04\x0b\x19\x1c", []inputEvent{
+			{kind: keyCtrlC}, {kind: keyEnter}, {kind: keyBackspace}, {kind: keyTab},
+			{kind: keyDeleteWord}, {kind: keyDeleteLine}, {kind: keyHome}, {kind: keyEnd},
+			{kind: keyLeft}, {kind: keyRune, ch: 'f', mod: modCtrl}, {kind: keyDelete}, {kind: keyKillEnd}, {kind: keyYank}, {kind: keyUnknown},
+		}},
+		{"ctrl letters", "\x11\x07\x00\x1b[113;5u", []inputEvent{
+			{kind: keyRune, ch: 'q', mod: modCtrl}, {kind: keyRune, ch: 'g', mod: modCtrl},
+			{kind: keyRune, ch: ' ', mod: modCtrl}, {kind: keyRune, ch: 'q', mod: modCtrl},
+		}},
+		{"csi cursor", "\x1b[A\x1b[B\x1b[C\x1b[D\x1b[H\x1b[F", []inputEvent{
+			{kind: keyUp}, {kind: keyDown}, {kind: keyRight}, {kind: keyLeft}, {kind: keyHome}, {kind: keyEnd},
+		}},
+		{"ss3 cursor", "\x1bOA\x1bOH\x1bOF", []inputEvent{{kind: keyUp}, {kind: keyHome}, {kind: keyEnd}}},
+		{"tilde keys", "\x1b[5~\x1b[6~\x1b[1~\x1b[4~\x1b[7~\x1b[8~\x1b[3~\x1b[15~", []inputEvent{
+			{kind: keyPageUp}, {kind: keyPageDown}, {kind: keyHome}, {kind: keyEnd},
+			{kind: keyHome}, {kind: keyEnd}, {kind: keyDelete}, {kind: keyUnknown},
+		}},
+		{"modifiers", "\x1b[1;5C\x1b[1;2A\x1b[5;3~", []inputEvent{
+			{kind: keyRight, mod: modCtrl}, {kind: keyUp, mod: modShift}, {kind: keyPageUp, mod: modAlt},
+		}},
+		{"alt", "\x1bb\x1b\x7f", []inputEvent{
+			{kind: keyRune, ch: 'b', mod: modAlt}, {kind: keyDeleteWord, mod: modAlt},
+		}},
+		{"esc", "\x1b\x1b", []inputEvent{{kind: keyEsc}, {kind: keyEsc}}},
+		{"paste", "\x1b[200~héllo\nworld\x1b[201~x", []inputEvent{
+			{kind: keyPaste, text: "héllo\nworld"}, {kind: keyRune, ch: 'x'},
+		}},
+		{"kitty keyboard", "\x1b[27u\x1b[99;5u\x1b[119;5u\x1b[117;5u\x1b[97;3u\x1b[233u\x1b[127;3u\x1b[107;5u\x1b[57399u", []inputEvent{
+			{kind: keyEsc}, {kind: keyCtrlC, mod: modCtrl}, {kind: keyDeleteWord, mod: modCtrl},
+			{kind: keyDeleteLine, mod: modCtrl}, {kind: keyRune, ch: 'a', mod: modAlt}, {kind: keyRune, ch: 'é'},
+			{kind: keyDeleteWord, mod: modAlt}, {kind: keyKillEnd, mod: modCtrl}, {kind: keyUnknown},
+		}},
+		{"unknown csi", "\x1b[?1;2cq", []inputEvent{{kind: keyUnknown}, {kind: keyRune, ch: 'q'}}},
+	}
+	for _, tc := range cases {
+		got := decodeAll(t, tc.input)
+		if len(got) != len(tc.want) {
+			t.Fatalf("%s: got %d events, want %d: %+v", tc.name, len(got), len(tc.want), got)
+		}
+		for i := range tc.want {
+			if got[i] != tc.want[i] {
+				t.Fatalf("%s: event %d: got %+v want %+v", tc.name, i, got[i], tc.want[i])
+			}
+		}
+	}
+}
+
+func TestInputDecoderLoneEsc(t *testing.T) {
+	// ESC with nothing behind it within escDelay is the Esc key.
+	r, w := io.Pipe()
+	dec := newInputDecoder(r)
+	go func() {
+		_, _ = w.Write([]byte{0x1b})
+		time.Sleep(3 * escDelay)
+		_, _ = w.Write([]byte("[A"))
+		_ = w.Close()
+	}()
+	for _, want := range []inputEvent{{kind: keyEsc}, {kind: keyRune, ch: '['}, {kind: keyRune, ch: 'A'}} {
+		if ev, err := dec.next(); err != nil || ev != want {
+			t.Fatalf("expected %+v, got %+v %v", want, ev, err)
+		}
+	}
+}
+
+func TestInputDecoderSplitSequence(t *testing.T) {
+	// Over SSH or tmux, a sequence can arrive split across reads.
+	r, w := io.Pipe()
+	dec := newInputDecoder(r)
+	go func() {
+		_, _ = w.Write([]byte{0x1b})
+		time.Sleep(escDelay / 3)
+		_, _ = w.Write([]byte("[A"))
+		_ = w.Close()
+	}()
+	if ev, err := dec.next(); err != nil || ev.kind != keyUp {
+		t.Fatalf("expected up, got %+v %v", ev, err)
+	}
+	if _, err := dec.next(); !errors.Is(err, io.EOF) {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+}
+
+func TestQueryEditing(t *testing.T) {
+	state := &appState{mode: modeQuery, query: "funny café"}
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+
+	handleInput(state, inputEvent{kind: keyBackspace}, out)
+	if state.query != "funny caf" {
+		t.Fatalf("expected rune-safe backspace, got %q", state.query)
+	}
+	handleInput(state, inputEvent{kind: keyRune, ch: 'é'}, out)
+	handleInput(state, inputEvent{kind: keyRune, ch: 'x', mod: modAlt}, out)
+	if state.query != "funny café" {
+		t.Fatalf("expected alt rune ignored, got %q", state.query)
+	}
+	handleInput(state, inputEvent{kind: keyDeleteWord}, out)
+	if state.query != "funny " {
+		t.Fatalf("expected last word deleted, got %q", state.query)
+	}
+	handleInput(state, inputEvent{kind: keyPaste, text: "cat\r\ndog\x07\ttail"}, out)
+	if state.query != "funny cat  dog tail" {
+		t.Fatalf("expected cleaned paste, got %q", state.query)
+	}
+	handleInput(state, inputEvent{kind: keyDeleteLine}, out)
+	if state.query != "" {
+		t.Fatalf("expected cleared query, got %q", state.query)
+	}
+}
+
+func TestBrowsePaging(t *testing.T) {
+	state := mouseTestState(20)
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+
+	handleInput(state, inputEvent{kind: keyPageDown}, out)
+	if state.selected != 8 {
+		t.Fatalf("expected page down to 8, got %d", state.selected)
+	}
+	handleInput(state, inputEvent{kind: keyEnd}, out)
+	if state.selected != 19 || state.scroll != 12 {
+		t.Fatalf("expected end, got selected=%d scroll=%d", state.selected, state.scroll)
+	}
+	handleInput(state, inputEvent{kind: keyPageUp}, out)
+	if state.selected != 11 {
+		t.Fatalf("expected page up to 11, got %d", state.selected)
+	}
+	handleInput(state, inputEvent{kind: keyHome}, out)
+	if state.selected != 0 || state.scroll != 0 {
+		t.Fatalf("expected home, got selected=%d scroll=%d", state.selected, state.scroll)
+	}
+
+	handleInput(state, inputEvent{kind: keyPaste, text: "cats\n"}, out)
+	if state.mode != modeQuery || state.query != "cats" {
+		t.Fatalf("expected paste to start a query, got mode=%v query=%q", state.mode, state.query)
+	}
+}
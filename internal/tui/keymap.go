@@ -0,0 +1,689 @@
+package tui
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// action is a named browse-mode command that keys are bound to.
+type action string
+
+const (
+	actQuit           action = "quit"
+	actSearch         action = "search"
+	actUp             action = "up"
+	actDown           action = "down"
+	actLeft           action = "left"
+	actRight          action = "right"
+	actPageUp         action = "page-up"
+	actPageDown       action = "page-down"
+	actTop            action = "top"
+	actBottom         action = "bottom"
+	actDownload       action = "download"
+	actReveal         action = "reveal"
+	actGrid           action = "grid"
+	actMark           action = "mark"
+	actMarkAll        action = "mark-all"
+	actCopyURL        action = "copy-url"
+	actCopyMarkdown   action = "copy-markdown"
+	actCopyHTML       action = "copy-html"
+	actExportMarkdown action = "export-markdown"
+	actExportJSON     action = "export-json"
+	actPause          action = "pause"
+	actStepBack       action = "step-back"
+	actStepForward    action = "step-forward"
+	actScrubBack      action = "scrub-back"
+	actScrubForward   action = "scrub-forward"
+	actSlower         action = "slower"
+	actFaster         action = "faster"
+	actStill          action = "still"
+	actSheet          action = "sheet"
+	actDetail         action = "detail"
+	actHelp           action = "help"
+	actFilter         action = "filter"
+)
+
+// actionDef names an action for the help; the order is the order of the
+// help.
+type actionDef struct {
+	name  action
+	label string
+}
+
+var actionDefs = []actionDef{
+	{actSearch, "Edit search"},
+	{actFilter, "Filter results"},
+	{actUp, "Up"},
+	{actDown, "Down"},
+	{actLeft, "Left (grid)"},
+	{actRight, "Right (grid)"},
+	{actPageUp, "Page up"},
+	{actPageDown, "Page down"},
+	{actTop, "First result"},
+	{actBottom, "Last result"},
+	{actDownload, "Download"},
+	{actReveal, "Reveal"},
+	{actDetail, "Details"},
+	{actGrid, "Grid view"},
+	{actMark, "Mark"},
+	{actMarkAll, "Mark all"},
+	{actCopyURL, "Copy URL"},
+	{actCopyMarkdown, "Copy markdown"},
+	{actCopyHTML, "Copy <img>"},
+	{actExportMarkdown, "Export markdown"},
+	{actExportJSON, "Export JSON"},
+	{actPause, "Pause"},
+	{actStepBack, "Frame back"},
+	{actStepForward, "Frame forward"},
+	{actScrubBack, "Jump back"},
+	{actScrubForward, "Jump forward"},
+	{actSlower, "Slower"},
+	{actFaster, "Faster"},
+	{actStill, "Save still"},
+	{actSheet, "Save sheet"},
+	{actHelp, "Help"},
+	{actQuit, "Quit"},
+}
+
+// actionRuns runs each action; true means quit.
+var actionRuns = map[action]func(state *appState, out *bufio.Writer) bool{
+	actSearch: stateAction(func(state *appState) {
+		state.mode = modeQuery
+		state.status = "Type a search and press Enter"
+		state.renderDirty = true
+	}),
+	actFilter:         stateAction(startFilter),
+	actUp:             moveTo(actUp),
+	actDown:           moveTo(actDown),
+	actLeft:           moveTo(actLeft),
+	actRight:          moveTo(actRight),
+	actPageUp:         moveTo(actPageUp),
+	actPageDown:       moveTo(actPageDown),
+	actTop:            moveTo(actTop),
+	actBottom:         moveTo(actBottom),
+	actDownload:       outAction(downloadMarked),
+	actReveal:         handleRevealSelected,
+	actDetail:         outAction(toggleDetail),
+	actGrid:           outAction(toggleGrid),
+	actMark:           stateAction(toggleMark),
+	actMarkAll:        stateAction(markAll),
+	actCopyURL:        outAction(func(state *appState, out *bufio.Writer) { copyResults(state, out, copyURL) }),
+	actCopyMarkdown:   outAction(func(state *appState, out *bufio.Writer) { copyResults(state, out, copyMarkdown) }),
+	actCopyHTML:       outAction(func(state *appState, out *bufio.Writer) { copyResults(state, out, copyHTML) }),
+	actExportMarkdown: stateAction(func(state *appState) { exportResults(state, "markdown") }),
+	actExportJSON:     stateAction(func(state *appState) { exportResults(state, "json") }),
+	actPause:          outAction(togglePause),
+	actStepBack:       stateAction(func(state *appState) { stepFrame(state, -1) }),
+	actStepForward:    stateAction(func(state *appState) { stepFrame(state, 1) }),
+	actScrubBack:      stateAction(func(state *appState) { scrub(state, -1) }),
+	actScrubForward:   stateAction(func(state *appState) { scrub(state, 1) }),
+	actSlower:         stateAction(func(state *appState) { changeSpeed(state, -1) }),
+	actFaster:         stateAction(func(state *appState) { changeSpeed(state, 1) }),
+	actStill:          stateAction(exportStill),
+	actSheet:          stateAction(exportSheet),
+	actHelp:           outAction(toggleHelp),
+	actQuit:           func(*appState, *bufio.Writer) bool { return true },
+}
+
+func stateAction(f func(*appState)) func(*appState, *bufio.Writer) bool {
+	return func(state *appState, _ *bufio.Writer) bool {
+		f(state)
+		return false
+	}
+}
+
+func outAction(f func(*appState, *bufio.Writer)) func(*appState, *bufio.Writer) bool {
+	return func(state *appState, out *bufio.Writer) bool {
+		f(state, out)
+		return false
+	}
+}
+
+func moveTo(act action) func(*appState, *bufio.Writer) bool {
+	return stateAction(func(state *appState) { moveSelectionBy(state, act) })
+}
+
+func findAction(name action) (actionDef, bool) {
+	for _, def := range actionDefs {
+		if def.name == name {
+			return def, true
+		}
+	}
+	return actionDef{}, false
+}
+
+// runAction runs a bound action; true means quit.
+func runAction(state *appState, act action, out *bufio.Writer) bool {
+	run, ok := actionRuns[act]
+	if !ok {
+		return false
+	}
+	return run(state, out)
+}
+
+// moveSelectionBy moves the selection through the grid or the list.
+func moveSelectionBy(state *appState, act action) {
+	if state.grid && gridMove(state, act) {
+		return
+	}
+	deltas := map[action]int{
+		actUp:       -1,
+		actDown:     1,
+		actPageUp:   -listPage(state),
+		actPageDown: listPage(state),
+		actTop:      -state.selected,
+		actBottom:   len(state.results) - 1 - state.selected,
+	}
+	if delta, ok := deltas[act]; ok {
+		moveSelection(state, delta)
+	}
+}
+
+// keyStroke is a key as the keymap sees it. Shift is folded into the rune
+// ("G", not shift+g), and Ctrl+letters the decoder turns into editing keys
+// are those keys (ctrl+d is keyDelete).
+type keyStroke struct {
+	kind keyKind
+	ch   rune
+	mod  keyMod
+}
+
+func strokeFor(ev inputEvent) keyStroke {
+	k := keyStroke{kind: ev.kind, mod: ev.mod}
+	if ev.kind == keyRune {
+		k.ch = ev.ch
+		if k.mod&modShift != 0 {
+			k.ch = unicode.ToUpper(k.ch)
+		}
+		k.mod &^= modShift
+	}
+	return k
+}
+
+// keymap binds browse-mode keys to actions. With typeToSearch, unbound
+// letters and digits start a new search instead of doing nothing.
+type keymap struct {
+	name         string
+	bindings     map[keyStroke]action
+	keys         map[action][]keyStroke // in binding order, for hints and help
+	typeToSearch bool
+}
+
+// Presets: the default one keeps gifgrep's single-letter keys, and other
+// letters start a search; chords leaves every letter and digit to the
+// search box and puts actions on Ctrl/Alt chords, symbols and special keys;
+// vim and emacs bind the keys their users expect.
+var keymapPresets = map[string]struct {
+	typeToSearch bool
+	bindings     map[action]string
+}{
+	"default": {typeToSearch: true, bindings: map[action]string{
+		actQuit:           "q ctrl+c",
+		actSearch:         "/ enter esc",
+		actUp:             "up",
+		actDown:           "down",
+		actLeft:           "left",
+		actRight:          "right",
+		actPageUp:         "pgup",
+		actPageDown:       "pgdown",
+		actTop:            "home",
+		actBottom:         "end",
+		actDownload:       "d",
+		actReveal:         "f",
+		actGrid:           "g",
+		actMark:           "space",
+		actMarkAll:        "*",
+		actCopyURL:        "c",
+		actCopyMarkdown:   "m",
+		actCopyHTML:       "H",
+		actExportMarkdown: "e",
+		actExportJSON:     "E",
+		actPause:          "p",
+		actStepBack:       ",",
+		actStepForward:    ".",
+		actScrubBack:      "[",
+		actScrubForward:   "]",
+		actSlower:         "-",
+		actFaster:         "+ =",
+		actStill:          "s",
+		actSheet:          "S",
+		actDetail:         "i tab",
+		actHelp:           "?",
+		actFilter:         "ctrl+f",
+	}},
+	"chords": {typeToSearch: true, bindings: map[action]string{
+		actQuit:           "ctrl+q ctrl+c",
+		actSearch:         "/ enter esc",
+		actUp:             "up",
+		actDown:           "down",
+		actLeft:           "left",
+		actRight:          "right",
+		actPageUp:         "pgup",
+		actPageDown:       "pgdown",
+		actTop:            "home",
+		actBottom:         "end",
+		actDownload:       "ctrl+s",
+		actReveal:         "ctrl+o",
+		actGrid:           "ctrl+g",
+		actMark:           "space",
+		actMarkAll:        "*",
+		actCopyURL:        "alt+c",
+		actCopyMarkdown:   "alt+m",
+		actCopyHTML:       "alt+h",
+		actExportMarkdown: "alt+e",
+		actExportJSON:     "alt+j",
+		actPause:          "alt+p",
+		actStepBack:       ",",
+		actStepForward:    ".",
+		actScrubBack:      "[",
+		actScrubForward:   "]",
+		actSlower:         "-",
+		actFaster:         "+ =",
+		actStill:          "alt+s",
+		actSheet:          "alt+S",
+		actDetail:         "tab alt+i",
+		actHelp:           "?",
+		actFilter:         "ctrl+f",
+	}},
+	"vim": {bindings: map[action]string{
+		actQuit:           "q ctrl+c",
+		actSearch:         "/ enter esc",
+		actUp:             "k up",
+		actDown:           "j down",
+		actLeft:           "h left",
+		actRight:          "l right",
+		actPageUp:         "ctrl+u pgup",
+		actPageDown:       "ctrl+d pgdown",
+		actTop:            "g home",
+		actBottom:         "G end",
+		actDownload:       "d",
+		actReveal:         "f",
+		actGrid:           "v",
+		actMark:           "space x",
+		actMarkAll:        "*",
+		actCopyURL:        "y",
+		actCopyMarkdown:   "m",
+		actCopyHTML:       "H",
+		actExportMarkdown: "e",
+		actExportJSON:     "E",
+		actPause:          "p",
+		actStepBack:       ",",
+		actStepForward:    ".",
+		actScrubBack:      "[",
+		actScrubForward:   "]",
+		actSlower:         "-",
+		actFaster:         "+ =",
+		actStill:          "s",
+		actSheet:          "S",
+		actDetail:         "i",
+		actHelp:           "?",
+		actFilter:         "ctrl+f",
+	}},
+	"emacs": {typeToSearch: true, bindings: map[action]string{
+		actQuit:           "ctrl+q ctrl+c",
+		actSearch:         "ctrl+s / enter esc",
+		actUp:             "ctrl+p up",
+		actDown:           "ctrl+n down",
+		actLeft:           "ctrl+b left",
+		actRight:          "ctrl+f right",
+		actPageUp:         "alt+v pgup",
+		actPageDown:       "ctrl+v pgdown",
+		actTop:            "alt+< home",
+		actBottom:         "alt+> end",
+		actDownload:       "alt+d",
+		actReveal:         "ctrl+o",
+		actGrid:           "alt+g",
+		actMark:           "ctrl+space space",
+		actMarkAll:        "alt+a",
+		actCopyURL:        "alt+w",
+		actCopyMarkdown:   "alt+m",
+		actCopyHTML:       "alt+h",
+		actExportMarkdown: "alt+e",
+		actExportJSON:     "alt+j",
+		actPause:          "alt+p",
+		actStepBack:       ",",
+		actStepForward:    ".",
+		actScrubBack:      "[",
+		actScrubForward:   "]",
+		actSlower:         "-",
+		actFaster:         "+ =",
+		actStill:          "alt+s",
+		actSheet:          "alt+S",
+		actDetail:         "tab alt+i",
+		actHelp:           "?",
+		actFilter:         "ctrl+r",
+	}},
+}
+
+// presetKeymap returns a preset by name.
+func presetKeymap(name string) (*keymap, bool) {
+	preset, ok := keymapPresets[name]
+	if !ok {
+		return nil, false
+	}
+	km := &keymap{name: name, bindings: map[keyStroke]action{}, keys: map[action][]keyStroke{}, typeToSearch: preset.typeToSearch}
+	for _, act := range slices.Sorted(maps.Keys(preset.bindings)) {
+		if err := km.bind(act, preset.bindings[act]); err != nil {
+			panic(fmt.Sprintf("keymap preset %s: %v", name, err))
+		}
+	}
+	return km, true
+}
+
+var defaultKeymap, _ = presetKeymap("default")
+
+// activeKeymap is the session's keymap, or the default one.
+func activeKeymap(state *appState) *keymap {
+	if state.keys != nil {
+		return state.keys
+	}
+	return defaultKeymap
+}
+
+// bind replaces the keys of act with the space-separated keys in spec; a
+// key bound elsewhere moves to act.
+func (km *keymap) bind(act action, spec string) error {
+	strokes := make([]keyStroke, 0, 4)
+	for _, field := range strings.Fields(spec) {
+		k, err := parseKeyStroke(field)
+		if err != nil {
+			return err
+		}
+		strokes = append(strokes, k)
+	}
+	for _, k := range km.keys[act] {
+		delete(km.bindings, k)
+	}
+	km.keys[act] = nil
+	for _, k := range strokes {
+		if prev, ok := km.bindings[k]; ok {
+			km.keys[prev] = removeStroke(km.keys[prev], k)
+		}
+		km.bindings[k] = act
+		km.keys[act] = append(km.keys[act], k)
+	}
+	return nil
+}
+
+func removeStroke(keys []keyStroke, k keyStroke) []keyStroke {
+	out := keys[:0]
+	for _, key := range keys {
+		if key != k {
+			out = append(out, key)
+		}
+	}
+	return out
+}
+
+// lookup returns the action bound to ev. Special keys bound without
+// modifiers also match with them (ctrl+up is still up).
+func (km *keymap) lookup(ev inputEvent) (action, bool) {
+	k := strokeFor(ev)
+	if act, ok := km.bindings[k]; ok {
+		return act, true
+	}
+	if k.kind != keyRune && k.mod != 0 {
+		k.mod = 0
+		act, ok := km.bindings[k]
+		return act, ok
+	}
+	return "", false
+}
+
+var namedKeys = map[string]keyKind{
+	"enter":     keyEnter,
+	"return":    keyEnter,
+	"esc":       keyEsc,
+	"escape":    keyEsc,
+	"tab":       keyTab,
+	"backspace": keyBackspace,
+	"delete":    keyDelete,
+	"del":       keyDelete,
+	"up":        keyUp,
+	"down":      keyDown,
+	"left":      keyLeft,
+	"right":     keyRight,
+	"home":      keyHome,
+	"end":       keyEnd,
+	"pgup":      keyPageUp,
+	"pageup":    keyPageUp,
+	"pgdown":    keyPageDown,
+	"pagedown":  keyPageDown,
+}
+
+// parseKeyStroke parses a key like "d", "G", "space", "pgdown", "ctrl+s",
+// "alt+shift+s" or "alt+<".
+func parseKeyStroke(s string) (keyStroke, error) {
+	var mod keyMod
+	rest := s
+	for {
+		i := strings.Index(rest, "+")
+		if i <= 0 || i == len(rest)-1 {
+			break
+		}
+		switch strings.ToLower(rest[:i]) {
+		case "ctrl", "c":
+			mod |= modCtrl
+		case "alt", "meta", "m":
+			mod |= modAlt
+		case "shift", "s":
+			mod |= modShift
+		default:
+			return keyStroke{}, fmt.Errorf("unknown modifier in %q", s)
+		}
+		rest = rest[i+1:]
+	}
+	var ev inputEvent
+	kind, named := namedKeys[strings.ToLower(rest)]
+	switch {
+	case strings.EqualFold(rest, "space"):
+		ev = inputEvent{kind: keyRune, ch: ' '}
+	case named:
+		ev = inputEvent{kind: kind}
+	case utf8.RuneCountInString(rest) == 1:
+		r, _ := utf8.DecodeRuneInString(rest)
+		ev = inputEvent{kind: keyRune, ch: r}
+	default:
+		return keyStroke{}, fmt.Errorf("unknown key %q", s)
+	}
+	if ev.kind == keyRune && mod&modCtrl != 0 {
+		// As the terminal sends it: a control byte, decoded like input.
+		r := unicode.ToLower(ev.ch)
+		if r != ' ' && (r < 'a' || r > 'z') {
+			return keyStroke{}, fmt.Errorf("unsupported key %q", s)
+		}
+		ctrl := controlEvent(byte(r) & 0x1f)
+		ev.kind, ev.ch = ctrl.kind, ctrl.ch
+		mod &^= modCtrl
+		if ev.kind == keyRune {
+			mod |= modCtrl
+		}
+	}
+	ev.mod = mod
+	return strokeFor(ev), nil
+}
+
+// label is how a key shows in the hint bar and help.
+func (k keyStroke) label() string {
+	var b strings.Builder
+	if k.mod&modCtrl != 0 {
+		b.WriteString("^")
+	}
+	if k.mod&modAlt != 0 {
+		b.WriteString("M-")
+	}
+	if k.mod&modShift != 0 {
+		b.WriteString("S-")
+	}
+	switch k.kind {
+	case keyRune:
+		switch {
+		case k.ch == ' ':
+			b.WriteString("space")
+		case k.mod&modCtrl != 0:
+			b.WriteRune(unicode.ToUpper(k.ch))
+		default:
+			b.WriteRune(k.ch)
+		}
+	case keyEnter:
+		b.WriteString("⏎")
+	case keyUp:
+		b.WriteString("↑")
+	case keyDown:
+		b.WriteString("↓")
+	case keyLeft:
+		b.WriteString("←")
+	case keyRight:
+		b.WriteString("→")
+	case keyEsc:
+		b.WriteString("esc")
+	case keyTab:
+		b.WriteString("tab")
+	case keyBackspace:
+		b.WriteString("⌫")
+	case keyHome:
+		b.WriteString("home")
+	case keyEnd:
+		b.WriteString("end")
+	case keyPageUp:
+		b.WriteString("pgup")
+	case keyPageDown:
+		b.WriteString("pgdn")
+	case keyDelete:
+		// ^D and the Delete key arrive alike.
+		b.WriteString("^D")
+	case keyCtrlC:
+		b.WriteString("^C")
+	case keyDeleteWord:
+		b.WriteString("^W")
+	case keyDeleteLine:
+		b.WriteString("^U")
+	case keyKillEnd:
+		b.WriteString("^K")
+	case keyYank:
+		b.WriteString("^Y")
+	case keyUnknown, mouseClick, mouseWheelUp, mouseWheelDown, keyPaste:
+	}
+	return b.String()
+}
+
+// keyLabel is the label of the first key bound to act, or "".
+func (km *keymap) keyLabel(act action) string {
+	if keys := km.keys[act]; len(keys) > 0 {
+		return keys[0].label()
+	}
+	return ""
+}
+
+// keymapPath is where the keymap file lives:
+// $XDG_CONFIG_HOME/gifgrep/keymap.conf, or ~/.config/gifgrep/keymap.conf.
+func keymapPath(getenv func(string) string) string {
+	dir := strings.TrimSpace(getenv("XDG_CONFIG_HOME"))
+	if dir == "" {
+		home := strings.TrimSpace(getenv("HOME"))
+		if home == "" {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "gifgrep", "keymap.conf")
+}
+
+// loadKeymap returns the keymap to use: GIFGREP_KEYMAP names a preset or a
+// file, else the keymap file if there is one, else the default preset. On
+// error the default preset comes back along with the error.
+func loadKeymap(getenv func(string) string) (*keymap, error) {
+	path := keymapPath(getenv)
+	if v := strings.TrimSpace(getenv("GIFGREP_KEYMAP")); v != "" {
+		if km, ok := presetKeymap(v); ok {
+			return km, nil
+		}
+		path = v
+	}
+	if path == "" {
+		return defaultKeymap, nil
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) && path == keymapPath(getenv) {
+		return defaultKeymap, nil
+	}
+	if err != nil {
+		return defaultKeymap, err
+	}
+	defer func() { _ = f.Close() }()
+	km, err := parseKeymap(f)
+	if err != nil {
+		return defaultKeymap, fmt.Errorf("%s: %w", path, err)
+	}
+	return km, nil
+}
+
+// parseKeymap reads a keymap file: "name = value" lines, # comments.
+//
+//	preset = vim            # start from default, chords, vim or emacs
+//	type-to-search = true   # unbound letters start a search
+//	download = d ctrl+s     # replaces the preset's keys; empty unbinds
+func parseKeymap(r io.Reader) (*keymap, error) {
+	type setting struct {
+		line        int
+		name, value string
+	}
+	var settings []setting
+	preset := "default"
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := sc.Text()
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected name = keys", n)
+		}
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if name == "preset" {
+			preset = value
+			continue
+		}
+		settings = append(settings, setting{line: n, name: name, value: value})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	km, ok := presetKeymap(preset)
+	if !ok {
+		return nil, fmt.Errorf("unknown preset %q (default, chords, vim, emacs)", preset)
+	}
+	km.name = "custom"
+	for _, s := range settings {
+		if s.name == "type-to-search" {
+			on, err := strconv.ParseBool(s.value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: type-to-search wants true or false", s.line)
+			}
+			km.typeToSearch = on
+			continue
+		}
+		if _, ok := findAction(action(s.name)); !ok {
+			return nil, fmt.Errorf("line %d: unknown action %q", s.line, s.name)
+		}
+		if err := km.bind(action(s.name), s.value); err != nil {
+			return nil, fmt.Errorf("line %d: %w", s.line, err)
+		}
+	}
+	return km, nil
+}
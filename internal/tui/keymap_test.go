@@ -0,0 +1,238 @@
+package tui
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseKeyStroke(t *testing.T) {
+	cases := []struct {
+		spec string
+		want keyStroke
+	}{
+		{"q", keyStroke{kind: keyRune, ch: 'q'}},
+		{"G", keyStroke{kind: keyRune, ch: 'G'}},
+		{"shift+g", keyStroke{kind: keyRune, ch: 'G'}},
+		{"ctrl+q", keyStroke{kind: keyRune, ch: 'q', mod: modCtrl}},
+		{"Ctrl+Q", keyStroke{kind: keyRune, ch: 'q', mod: modCtrl}},
+		{"ctrl+d", keyStroke{kind: keyDelete}},
+		{"ctrl+space", keyStroke{kind: keyRune, ch: ' ', mod: modCtrl}},
+		{"alt+<", keyStroke{kind: keyRune, ch: '<', mod: modAlt}},
+		{"meta+x", keyStroke{kind: keyRune, ch: 'x', mod: modAlt}},
+		{"space", keyStroke{kind: keyRune, ch: ' '}},
+		{"pgdown", keyStroke{kind: keyPageDown}},
+		{"enter", keyStroke{kind: keyEnter}},
+	}
+	for _, tc := range cases {
+		got, err := parseKeyStroke(tc.spec)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.spec, err)
+		}
+		if got != tc.want {
+			t.Fatalf("%s: got %+v want %+v", tc.spec, got, tc.want)
+		}
+	}
+	for _, bad := range []string{"", "ctrl+", "hyper+x", "ctrl+1", "nope"} {
+		if _, err := parseKeyStroke(bad); err == nil {
+			t.Fatalf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestKeymapPresets(t *testing.T) {
+	for _, def := range actionDefs {
+		if actionRuns[def.name] == nil {
+			t.Fatalf("%s has no run func", def.name)
+		}
+	}
+	if len(actionRuns) != len(actionDefs) {
+		t.Fatalf("%d run funcs for %d actions", len(actionRuns), len(actionDefs))
+	}
+	for name := range keymapPresets {
+		km, ok := presetKeymap(name)
+		if !ok {
+			t.Fatalf("missing preset %s", name)
+		}
+		for _, def := range actionDefs {
+			if len(km.keys[def.name]) == 0 {
+				t.Fatalf("%s: %s is unbound", name, def.name)
+			}
+		}
+		for k, act := range km.bindings {
+			if _, ok := findAction(act); !ok {
+				t.Fatalf("%s: %+v bound to unknown action %q", name, k, act)
+			}
+		}
+	}
+	vim, _ := presetKeymap("vim")
+	if act, _ := vim.lookup(inputEvent{kind: keyRune, ch: 'j'}); act != actDown {
+		t.Fatalf("vim: expected j to move down, got %q", act)
+	}
+	if act, _ := vim.lookup(inputEvent{kind: keyRune, ch: 'g', mod: modShift}); act != actBottom {
+		t.Fatalf("vim: expected G to go to the bottom, got %q", act)
+	}
+	emacs, _ := presetKeymap("emacs")
+	if act, _ := emacs.lookup(inputEvent{kind: keyRune, ch: 'n', mod: modCtrl}); act != actDown {
+		t.Fatalf("emacs: expected ctrl+n to move down, got %q", act)
+	}
+	if act, _ := emacs.lookup(inputEvent{kind: keyDown, mod: modShift}); act != actDown {
+		t.Fatalf("emacs: expected shift+down to fall back to down, got %q", act)
+	}
+}
+
+func TestParseKeymap(t *testing.T) {
+	km, err := parseKeymap(strings.NewReader(`
+# mine
+preset = vim
+type-to-search = true
+download = D ctrl+s   # moved
+reveal =
+quit = f
+`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if km.name != "custom" || !km.typeToSearch {
+		t.Fatalf("unexpected keymap %q typeToSearch=%v", km.name, km.typeToSearch)
+	}
+	if act, _ := km.lookup(inputEvent{kind: keyRune, ch: 'D'}); act != actDownload {
+		t.Fatalf("expected D to download, got %q", act)
+	}
+	if _, ok := km.lookup(inputEvent{kind: keyRune, ch: 'd'}); ok {
+		t.Fatalf("expected d unbound")
+	}
+	if act, _ := km.lookup(inputEvent{kind: keyRune, ch: 'f'}); act != actQuit {
+		t.Fatalf("expected f moved to quit, got %q", act)
+	}
+	if len(km.keys[actReveal]) != 0 || km.keyLabel(actReveal) != "" {
+		t.Fatalf("expected reveal unbound, got %+v", km.keys[actReveal])
+	}
+	if km.keyLabel(actDownload) != "D" || km.keys[actDownload][1].label() != "^S" {
+		t.Fatalf("unexpected download labels %+v", km.keys[actDownload])
+	}
+
+	for _, bad := range []string{"preset = nano", "type-to-search = yes", "fly = x", "download", "download = ctrl+%"} {
+		if _, err := parseKeymap(strings.NewReader(bad)); err == nil {
+			t.Fatalf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestLoadKeymap(t *testing.T) {
+	dir := t.TempDir()
+	env := map[string]string{"XDG_CONFIG_HOME": dir}
+	getenv := func(k string) string { return env[k] }
+
+	km, err := loadKeymap(getenv)
+	if err != nil || km != defaultKeymap {
+		t.Fatalf("expected the default keymap without a file, got %v %v", km.name, err)
+	}
+
+	env["GIFGREP_KEYMAP"] = "emacs"
+	if km, err = loadKeymap(getenv); err != nil || km.name != "emacs" {
+		t.Fatalf("expected the emacs preset, got %v %v", km.name, err)
+	}
+	delete(env, "GIFGREP_KEYMAP")
+
+	path := filepath.Join(dir, "gifgrep", "keymap.conf")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte("preset = vim\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if km, err = loadKeymap(getenv); err != nil || km.name != "custom" || km.typeToSearch {
+		t.Fatalf("expected the keymap file, got %v %v", km.name, err)
+	}
+
+	if err := os.WriteFile(path, []byte("bogus = x\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if km, err = loadKeymap(getenv); err == nil || km != defaultKeymap || !strings.Contains(err.Error(), path) {
+		t.Fatalf("expected the default keymap and an error naming the file, got %v %v", km.name, err)
+	}
+
+	env["GIFGREP_KEYMAP"] = filepath.Join(dir, "missing.conf")
+	if _, err = loadKeymap(getenv); err == nil {
+		t.Fatalf("expected an error for a missing GIFGREP_KEYMAP file")
+	}
+}
+
+func TestTypeToSearch(t *testing.T) {
+	state := batchTestState()
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+
+	// The default keymap keeps gifgrep's letter keys; the others type.
+	handleInput(state, inputEvent{kind: keyRune, ch: 'z'}, out)
+	if state.mode != modeQuery || state.query != "z" {
+		t.Fatalf("expected z to start a search, got mode=%v query=%q", state.mode, state.query)
+	}
+	state.mode = modeBrowse
+	if !handleInput(state, inputEvent{kind: keyRune, ch: 'q'}, out) {
+		t.Fatalf("expected q to quit")
+	}
+
+	state.keys, _ = presetKeymap("chords")
+	for _, ch := range "qd" {
+		state.mode = modeBrowse
+		if handleInput(state, inputEvent{kind: keyRune, ch: ch}, out) {
+			t.Fatalf("expected %c to start a search, not quit", ch)
+		}
+		if state.mode != modeQuery || state.query != string(ch) {
+			t.Fatalf("expected %c to start a search, got mode=%v query=%q", ch, state.mode, state.query)
+		}
+	}
+	state.mode = modeBrowse
+	if !handleInput(state, inputEvent{kind: keyRune, ch: 'q', mod: modCtrl}, out) {
+		t.Fatalf("expected ctrl+q to quit")
+	}
+
+	state.keys, _ = presetKeymap("vim")
+	state.mode = modeBrowse
+	state.query = "cats"
+	handleInput(state, inputEvent{kind: keyRune, ch: 'j'}, out)
+	if state.mode != modeBrowse || state.selected != 1 || state.query != "cats" {
+		t.Fatalf("expected vim j to move down, got mode=%v selected=%d", state.mode, state.selected)
+	}
+	handleInput(state, inputEvent{kind: keyRune, ch: 'z'}, out)
+	if state.mode != modeBrowse || state.query != "cats" {
+		t.Fatalf("expected unbound z ignored in vim, got mode=%v query=%q", state.mode, state.query)
+	}
+	if !handleInput(state, inputEvent{kind: keyRune, ch: 'q'}, out) {
+		t.Fatalf("expected vim q to quit")
+	}
+}
+
+func TestHintsFollowKeymap(t *testing.T) {
+	var labels []string
+	for _, h := range defaultKeymap.hints() {
+		labels = append(labels, h.key+" "+h.label)
+	}
+	if got := strings.Join(labels, ", "); got != "⏎ Search, / Edit, ↑↓ Select, d Download, f Reveal, ? Help, q Quit" {
+		t.Fatalf("unexpected default hints %q", got)
+	}
+	chords, _ := presetKeymap("chords")
+	labels = labels[:0]
+	for _, h := range chords.hints() {
+		labels = append(labels, h.key+" "+h.label)
+	}
+	if got := strings.Join(labels, ", "); got != "⏎ Search, / Edit, ↑↓ Select, ^S Download, ^O Reveal, ? Help, ^Q Quit" {
+		t.Fatalf("unexpected chords hints %q", got)
+	}
+	vim, _ := presetKeymap("vim")
+	if err := vim.bind(actReveal, ""); err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	labels = labels[:0]
+	for _, h := range vim.hints() {
+		labels = append(labels, h.key+" "+h.label)
+	}
+	if got := strings.Join(labels, ", "); got != "⏎ Search, / Edit, kj Select, d Download, ? Help, q Quit" {
+		t.Fatalf("unexpected vim hints %q", got)
+	}
+}
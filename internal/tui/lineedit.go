@@ -0,0 +1,220 @@
+package tui
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// The search line is edited in place. The cursor is stored as the number of
+// bytes of state.query after it (state.queryTail), so code that sets the
+// query outright leaves the cursor at its end.
+
+const zeroWidthJoiner = '\u200d'
+
+func queryCursor(state *appState) int {
+	return len(state.query) - minInt(len(state.query), maxInt(0, state.queryTail))
+}
+
+func setQuery(state *appState, text string, pos int) {
+	state.query = text
+	state.queryTail = len(text) - pos
+	state.renderDirty = true
+}
+
+func moveQueryCursor(state *appState, pos int) {
+	if pos != queryCursor(state) {
+		setQuery(state, state.query, pos)
+	}
+}
+
+func insertQuery(state *appState, text string) {
+	if text == "" {
+		return
+	}
+	pos := queryCursor(state)
+	setQuery(state, state.query[:pos]+text+state.query[pos:], pos+len(text))
+}
+
+// killQuery removes query[from:to] and keeps it for yanking.
+func killQuery(state *appState, from, to int) {
+	if from >= to {
+		return
+	}
+	state.killed = state.query[from:to]
+	deleteQuery(state, from, to)
+}
+
+func deleteQuery(state *appState, from, to int) {
+	if from >= to {
+		return
+	}
+	pos := queryCursor(state)
+	switch {
+	case pos >= to:
+		pos -= to - from
+	case pos > from:
+		pos = from
+	}
+	setQuery(state, state.query[:from]+state.query[to:], pos)
+}
+
+// editQuery applies an editing key to the search line: readline-style
+// motion, deletion by grapheme cluster, and kill/yank.
+func editQuery(state *appState, ev inputEvent) {
+	s, pos := state.query, queryCursor(state)
+	word := ev.mod&(modAlt|modCtrl) != 0
+	switch ev.kind {
+	case keyRune:
+		editQueryRune(state, ev)
+	case keyPaste:
+		insertQuery(state, pasteText(ev.text))
+	case keyLeft:
+		if word {
+			moveQueryCursor(state, wordStart(s, pos))
+		} else {
+			moveQueryCursor(state, prevGrapheme(s, pos))
+		}
+	case keyRight:
+		if word {
+			moveQueryCursor(state, wordEnd(s, pos))
+		} else {
+			moveQueryCursor(state, nextGrapheme(s, pos))
+		}
+	case keyHome:
+		moveQueryCursor(state, 0)
+	case keyEnd:
+		moveQueryCursor(state, len(s))
+	case keyBackspace:
+		deleteQuery(state, prevGrapheme(s, pos), pos)
+	case keyDelete:
+		deleteQuery(state, pos, nextGrapheme(s, pos))
+	case keyDeleteWord:
+		killQuery(state, wordStart(s, pos), pos)
+	case keyDeleteLine:
+		killQuery(state, 0, pos)
+	case keyKillEnd:
+		killQuery(state, pos, len(s))
+	case keyYank:
+		insertQuery(state, state.killed)
+	case keyEnter, keyEsc, keyUp, keyDown, keyCtrlC, keyUnknown, mouseClick, mouseWheelUp, mouseWheelDown,
+		keyPageUp, keyPageDown, keyTab:
+	}
+}
+
+// editQueryRune inserts a typed rune; Ctrl+f is forward-char (it decodes as
+// itself so the keymap can bind it), Alt+b/f/d are word motions and other
+// Ctrl/Alt combinations are ignored.
+func editQueryRune(state *appState, ev inputEvent) {
+	s, pos := state.query, queryCursor(state)
+	if ev.mod&(modAlt|modCtrl) == modCtrl && ev.ch == 'f' {
+		moveQueryCursor(state, nextGrapheme(s, pos))
+		return
+	}
+	if ev.mod&modAlt != 0 && ev.mod&modCtrl == 0 {
+		switch ev.ch {
+		case 'b':
+			moveQueryCursor(state, wordStart(s, pos))
+		case 'f':
+			moveQueryCursor(state, wordEnd(s, pos))
+		case 'd':
+			killQuery(state, pos, wordEnd(s, pos))
+		}
+		return
+	}
+	if ev.mod&(modAlt|modCtrl) != 0 {
+		return
+	}
+	insertQuery(state, string(ev.ch))
+}
+
+// prevGrapheme returns the start of the grapheme cluster before pos. A
+// cluster is a rune plus the zero-width runes after it (combining marks,
+// variation selectors) and anything joined to it by U+200D.
+func prevGrapheme(s string, pos int) int {
+	for pos > 0 {
+		r, size := utf8.DecodeLastRuneInString(s[:pos])
+		pos -= size
+		if runewidth.RuneWidth(r) == 0 && pos > 0 {
+			continue
+		}
+		if prev, _ := utf8.DecodeLastRuneInString(s[:pos]); prev == zeroWidthJoiner {
+			continue
+		}
+		return pos
+	}
+	return 0
+}
+
+// nextGrapheme returns the end of the grapheme cluster at pos.
+func nextGrapheme(s string, pos int) int {
+	if pos >= len(s) {
+		return len(s)
+	}
+	r, size := utf8.DecodeRuneInString(s[pos:])
+	pos += size
+	for pos < len(s) {
+		next, size := utf8.DecodeRuneInString(s[pos:])
+		if r != zeroWidthJoiner && runewidth.RuneWidth(next) != 0 {
+			break
+		}
+		r = next
+		pos += size
+	}
+	return pos
+}
+
+// wordStart returns the start of the word before pos, skipping spaces.
+func wordStart(s string, pos int) int {
+	pos = len(strings.TrimRightFunc(s[:pos], unicode.IsSpace))
+	i := strings.LastIndexFunc(s[:pos], unicode.IsSpace)
+	if i < 0 {
+		return 0
+	}
+	_, size := utf8.DecodeRuneInString(s[i:])
+	return i + size
+}
+
+// wordEnd returns the end of the word after pos, skipping spaces.
+func wordEnd(s string, pos int) int {
+	rest := s[pos:]
+	trimmed := strings.TrimLeftFunc(rest, unicode.IsSpace)
+	pos += len(rest) - len(trimmed)
+	if i := strings.IndexFunc(trimmed, unicode.IsSpace); i >= 0 {
+		return pos + i
+	}
+	return len(s)
+}
+
+// queryWithCursor renders the query for the search line, width cells wide,
+// with the cluster under the cursor in reverse video (a bar past the end).
+func queryWithCursor(state *appState, width int) string {
+	return textWithCursor(state.query, queryCursor(state), state.useColor, width)
+}
+
+// textWithCursor renders s with the cursor at byte pos, like
+// queryWithCursor.
+func textWithCursor(s string, pos int, useColor bool, width int) string {
+	before, after := queryWindow(s, pos, width)
+	if after == "" {
+		if useColor {
+			return before + styleIf(true, "▍", "\x1b[36m")
+		}
+		return before + styleIf(true, " ", "\x1b[7m")
+	}
+	end := nextGrapheme(after, 0)
+	return before + styleIf(true, after[:end], "\x1b[7m") + after[end:]
+}
+
+// queryWindow returns the part of the query that fits in width cells with
+// the cursor visible, split at the cursor. Clusters scroll off the left
+// once the text before the cursor is too wide.
+func queryWindow(s string, pos, width int) (string, string) {
+	before, after := s[:pos], s[pos:]
+	for before != "" && runewidth.StringWidth(before) >= width {
+		before = before[nextGrapheme(before, 0):]
+	}
+	return before, after
+}
@@ -0,0 +1,137 @@
+package tui
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func editKeys(state *appState, events ...inputEvent) {
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+	for _, ev := range events {
+		handleInput(state, ev, out)
+	}
+}
+
+func cursorText(state *appState) string {
+	pos := queryCursor(state)
+	return state.query[:pos] + "|" + state.query[pos:]
+}
+
+func TestLineEditorMotion(t *testing.T) {
+	state := &appState{mode: modeQuery, query: "funny cat gif"}
+	cases := []struct {
+		ev   inputEvent
+		want string
+	}{
+		{inputEvent{kind: keyLeft}, "funny cat gi|f"},
+		{inputEvent{kind: keyLeft, mod: modCtrl}, "funny cat |gif"},
+		{inputEvent{kind: keyRune, ch: 'b', mod: modAlt}, "funny |cat gif"},
+		{inputEvent{kind: keyHome}, "|funny cat gif"},
+		{inputEvent{kind: keyRight}, "f|unny cat gif"},
+		{inputEvent{kind: keyRune, ch: 'f', mod: modCtrl}, "fu|nny cat gif"},
+		{inputEvent{kind: keyRune, ch: 'f', mod: modAlt}, "funny| cat gif"},
+		{inputEvent{kind: keyRight, mod: modAlt}, "funny cat| gif"},
+		{inputEvent{kind: keyEnd}, "funny cat gif|"},
+		{inputEvent{kind: keyRight}, "funny cat gif|"},
+	}
+	for i, tc := range cases {
+		editKeys(state, tc.ev)
+		if got := cursorText(state); got != tc.want {
+			t.Fatalf("step %d: got %q want %q", i, got, tc.want)
+		}
+	}
+}
+
+func TestLineEditorInsertAndDelete(t *testing.T) {
+	state := &appState{mode: modeQuery, query: "cat"}
+	editKeys(state,
+		inputEvent{kind: keyHome},
+		inputEvent{kind: keyRune, ch: 'ñ'},
+		inputEvent{kind: keyRune, ch: ' '},
+	)
+	if got := cursorText(state); got != "ñ |cat" {
+		t.Fatalf("got %q", got)
+	}
+	editKeys(state, inputEvent{kind: keyDelete})
+	if got := cursorText(state); got != "ñ |at" {
+		t.Fatalf("got %q", got)
+	}
+	editKeys(state, inputEvent{kind: keyBackspace}, inputEvent{kind: keyBackspace})
+	if got := cursorText(state); got != "|at" {
+		t.Fatalf("expected rune-safe backspace, got %q", got)
+	}
+	editKeys(state, inputEvent{kind: keyPaste, text: "big\n"})
+	if got := cursorText(state); got != "big|at" {
+		t.Fatalf("expected paste at the cursor, got %q", got)
+	}
+}
+
+func TestLineEditorGraphemes(t *testing.T) {
+	// e + combining acute and a ZWJ family each delete as one unit.
+	state := &appState{mode: modeQuery, query: "cafe\u0301"}
+	editKeys(state, inputEvent{kind: keyBackspace})
+	if state.query != "caf" {
+		t.Fatalf("expected combining mark removed with its base, got %q", state.query)
+	}
+
+	family := "\U0001F468\u200d\U0001F469\u200d\U0001F467"
+	state = &appState{mode: modeQuery, query: "x" + family + "y"}
+	editKeys(state, inputEvent{kind: keyHome}, inputEvent{kind: keyRight}, inputEvent{kind: keyDelete})
+	if state.query != "xy" {
+		t.Fatalf("expected ZWJ sequence deleted as one, got %q", state.query)
+	}
+
+	if got := prevGrapheme("a\u0301", len("a\u0301")); got != 0 {
+		t.Fatalf("prevGrapheme: got %d", got)
+	}
+	if got := nextGrapheme("a\u0301b", 0); got != len("a\u0301") {
+		t.Fatalf("nextGrapheme: got %d", got)
+	}
+}
+
+func TestLineEditorKillYank(t *testing.T) {
+	state := &appState{mode: modeQuery, query: "funny cat gif"}
+	editKeys(state, inputEvent{kind: keyDeleteWord})
+	if got := cursorText(state); got != "funny cat |" || state.killed != "gif" {
+		t.Fatalf("ctrl-w: got %q killed %q", got, state.killed)
+	}
+	editKeys(state, inputEvent{kind: keyHome}, inputEvent{kind: keyYank})
+	if got := cursorText(state); got != "gif|funny cat " {
+		t.Fatalf("ctrl-y: got %q", got)
+	}
+	editKeys(state, inputEvent{kind: keyKillEnd})
+	if got := cursorText(state); got != "gif|" || state.killed != "funny cat " {
+		t.Fatalf("ctrl-k: got %q killed %q", got, state.killed)
+	}
+	editKeys(state, inputEvent{kind: keyLeft}, inputEvent{kind: keyDeleteLine})
+	if got := cursorText(state); got != "|f" || state.killed != "gi" {
+		t.Fatalf("ctrl-u: got %q killed %q", got, state.killed)
+	}
+	editKeys(state, inputEvent{kind: keyRune, ch: 'd', mod: modAlt})
+	if got := cursorText(state); got != "|" || state.killed != "f" {
+		t.Fatalf("alt-d: got %q killed %q", got, state.killed)
+	}
+}
+
+func TestDrawSearchCursor(t *testing.T) {
+	state := &appState{mode: modeQuery, query: "héllo", queryTail: len("llo")}
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+	drawSearch(out, state, layout{searchRow: 1, cols: 40})
+	_ = out.Flush()
+	if !strings.Contains(buf.String(), "[Search] hé\x1b[7ml\x1b[0mlo") {
+		t.Fatalf("expected reverse-video cursor, got %q", buf.String())
+	}
+
+	state.queryTail = 0
+	state.query = strings.Repeat("x", 60)
+	buf.Reset()
+	drawSearch(out, state, layout{searchRow: 1, cols: 40})
+	_ = out.Flush()
+	if !strings.Contains(buf.String(), "x\x1b[7m \x1b[0m") {
+		t.Fatalf("expected the cursor to stay visible, got %q", buf.String())
+	}
+}
@@ -3,11 +3,18 @@ package tui
 import (
 	"bufio"
 	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/steipete/gifgrep/gifdecode"
+	"github.com/steipete/gifgrep/internal/kitty"
 	"github.com/steipete/gifgrep/internal/termcaps"
 )
 
@@ -99,3 +106,199 @@ func TestAdvanceManualAnimationGuards(t *testing.T) {
 	state.previewCol = 1
 	advanceManualAnimation(state, out)
 }
+
+func TestSixelPreviewAnimation(t *testing.T) {
+	solid := func(c color.Color) []byte {
+		img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+		draw.Draw(img, img.Bounds(), &image.Uniform{C: c}, image.Point{}, draw.Src)
+		var buf bytes.Buffer
+		_ = png.Encode(&buf, img)
+		return buf.Bytes()
+	}
+	state := &appState{
+		inline: termcaps.InlineSixel,
+		currentAnim: &gifAnimation{
+			ID: 1,
+			Frames: []gifdecode.Frame{
+				{PNG: solid(color.RGBA{R: 0xff, A: 0xff}), Delay: 10 * time.Millisecond},
+				{PNG: solid(color.RGBA{B: 0xff, A: 0xff}), Delay: 10 * time.Millisecond},
+			},
+		},
+		previewNeedsSend: true,
+		previewRow:       2,
+		previewCol:       2,
+	}
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+	drawPreview(state, out, 10, 5, 2, 2)
+	_ = out.Flush()
+	if !strings.Contains(buf.String(), "\x1bP0;1;0q") || !strings.Contains(buf.String(), ";2;100;0;0") {
+		t.Fatalf("expected sixel red frame, got %q", buf.String())
+	}
+	if strings.Contains(buf.String(), "\x1b_G") {
+		t.Fatalf("unexpected kitty output")
+	}
+	if !state.manualAnim {
+		t.Fatalf("expected software animation for sixel")
+	}
+
+	buf.Reset()
+	state.manualNext = time.Now().Add(-time.Millisecond)
+	advanceManualAnimation(state, out)
+	_ = out.Flush()
+	if !strings.Contains(buf.String(), ";2;0;0;100") {
+		t.Fatalf("expected sixel blue frame, got %q", buf.String())
+	}
+	if len(state.currentAnim.sixels) != 2 || state.currentAnim.sixels[1] == nil {
+		t.Fatalf("expected cached sixel frames")
+	}
+
+	// Redraws repaint the frame because surrounding text may have erased it.
+	buf.Reset()
+	drawPreview(state, out, 10, 5, 2, 2)
+	_ = out.Flush()
+	if !strings.Contains(buf.String(), "\x1bP0;1;0q") {
+		t.Fatalf("expected sixel repaint")
+	}
+}
+
+func TestTextPreviewAnimation(t *testing.T) {
+	solid := func(c color.Color) []byte {
+		img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+		draw.Draw(img, img.Bounds(), &image.Uniform{C: c}, image.Point{}, draw.Src)
+		var buf bytes.Buffer
+		_ = png.Encode(&buf, img)
+		return buf.Bytes()
+	}
+	state := &appState{
+		inline:     termcaps.InlineText,
+		colorDepth: termcaps.ColorTrue,
+		currentAnim: &gifAnimation{
+			ID: 1,
+			Frames: []gifdecode.Frame{
+				{PNG: solid(color.RGBA{R: 0xff, A: 0xff}), Delay: 10 * time.Millisecond},
+				{PNG: solid(color.RGBA{B: 0xff, A: 0xff}), Delay: 10 * time.Millisecond},
+			},
+		},
+		previewNeedsSend: true,
+		previewRow:       2,
+		previewCol:       2,
+	}
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+	drawPreview(state, out, 4, 3, 2, 2)
+	_ = out.Flush()
+	s := buf.String()
+	if !strings.Contains(s, "38;2;255;0;0") || strings.Count(s, "▀") != 12 {
+		t.Fatalf("expected red half-block frame, got %q", s)
+	}
+	if !strings.Contains(s, "\x1b[4;2H") || strings.Contains(s, "\x1b[K") {
+		t.Fatalf("expected lines placed in the box without erasing the row, got %q", s)
+	}
+	if !state.manualAnim {
+		t.Fatalf("expected software animation for text")
+	}
+
+	buf.Reset()
+	state.manualNext = time.Now().Add(-time.Millisecond)
+	advanceManualAnimation(state, out)
+	_ = out.Flush()
+	if !strings.Contains(buf.String(), "38;2;0;0;255") {
+		t.Fatalf("expected blue frame, got %q", buf.String())
+	}
+
+	buf.Reset()
+	drawPreview(state, out, 4, 3, 2, 2)
+	_ = out.Flush()
+	if !strings.Contains(buf.String(), "38;2;0;0;255") {
+		t.Fatalf("expected text repaint")
+	}
+}
+
+func TestKittyPlaceholderPreviewRepaints(t *testing.T) {
+	state := &appState{
+		inline: termcaps.InlineKitty,
+		kitty:  kitty.Mode{Unicode: true, Passthrough: kitty.PassthroughTmux},
+		currentAnim: &gifAnimation{
+			ID:     5,
+			Frames: []gifdecode.Frame{{PNG: []byte{1}, Delay: 10 * time.Millisecond}},
+		},
+		previewNeedsSend: true,
+	}
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+	drawPreview(state, out, 3, 2, 2, 2)
+	_ = out.Flush()
+	s := buf.String()
+	if !strings.HasPrefix(s, "\x1bPtmux;\x1b\x1b_G") || !strings.Contains(s, "U=1") {
+		t.Fatalf("expected virtual placement through tmux, got %q", s)
+	}
+	if strings.Count(s, "\U0010EEEE") != 6 {
+		t.Fatalf("expected 3x2 placeholder cells, got %q", s)
+	}
+
+	// Placeholders are text, so every redraw writes them again.
+	buf.Reset()
+	drawPreview(state, out, 3, 2, 2, 2)
+	_ = out.Flush()
+	s = buf.String()
+	if !strings.Contains(s, "a=p,U=1,i=5") || strings.Count(s, "\U0010EEEE") != 6 {
+		t.Fatalf("expected placeholders repainted, got %q", s)
+	}
+}
+
+func TestItermSoftwarePreviewAnimation(t *testing.T) {
+	prevClear := clearItermRectFn
+	t.Cleanup(func() { clearItermRectFn = prevClear })
+	var clears []string
+	clearItermRectFn = func(_ *bufio.Writer, row, col, cols, rows int) {
+		clears = append(clears, fmt.Sprintf("%d,%d %dx%d", row, col, cols, rows))
+	}
+
+	state := &appState{
+		inline:          termcaps.InlineIterm,
+		useSoftwareAnim: true,
+		currentAnim: &gifAnimation{
+			ID:     1,
+			RawGIF: []byte("GIF89a"),
+			Frames: []gifdecode.Frame{
+				{PNG: []byte("frame-one"), Delay: 10 * time.Millisecond},
+				{PNG: []byte("frame-two"), Delay: 10 * time.Millisecond},
+			},
+		},
+		previewNeedsSend: true,
+		previewRow:       2,
+		previewCol:       3,
+	}
+	state.itermLast.row, state.itermLast.col, state.itermLast.cols, state.itermLast.rows = 5, 1, 4, 4
+	if !usesFrames(state) {
+		t.Fatalf("expected decoded frames for software iTerm playback")
+	}
+
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+	drawPreview(state, out, 10, 5, 2, 3)
+	_ = out.Flush()
+	s := buf.String()
+	if !strings.Contains(s, "\x1b]1337;File=") || !strings.Contains(s, "width=10") {
+		t.Fatalf("expected OSC 1337 frame sized to the box, got %q", s)
+	}
+	if !strings.Contains(s, base64.StdEncoding.EncodeToString([]byte("frame-one"))) || strings.Contains(s, base64.StdEncoding.EncodeToString([]byte("GIF89a"))) {
+		t.Fatalf("expected the first decoded frame instead of the raw GIF, got %q", s)
+	}
+	if len(clears) != 2 || clears[0] != "5,1 4x4" || clears[1] != "2,3 10x5" {
+		t.Fatalf("expected old and new rect cleared, got %v", clears)
+	}
+
+	buf.Reset()
+	clears = nil
+	state.manualNext = time.Now().Add(-time.Millisecond)
+	advanceManualAnimation(state, out)
+	_ = out.Flush()
+	if !strings.Contains(buf.String(), base64.StdEncoding.EncodeToString([]byte("frame-two"))) {
+		t.Fatalf("expected second frame, got %q", buf.String())
+	}
+	if len(clears) != 0 {
+		t.Fatalf("frames overwrite each other; no clear expected, got %v", clears)
+	}
+}
@@ -0,0 +1,185 @@
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/steipete/gifgrep/internal/termcaps"
+)
+
+// wheelStep is how many list rows one wheel notch scrolls.
+const wheelStep = 3
+
+// mouseEnabled reports whether to turn on mouse reporting; GIFGREP_MOUSE=0
+// keeps the terminal's own text selection.
+func mouseEnabled(getenv func(string) string) bool {
+	switch strings.ToLower(strings.TrimSpace(getenv("GIFGREP_MOUSE"))) {
+	case "0", "false", "no", "off":
+		return false
+	default:
+		return true
+	}
+}
+
+// enableMouse turns on button-press reporting (1000) with SGR coordinates
+// (1006), which have no 223-column limit.
+func enableMouse(out *bufio.Writer) {
+	_, _ = fmt.Fprint(out, "\x1b[?1000h\x1b[?1006h")
+}
+
+func disableMouse(out *bufio.Writer) {
+	_, _ = fmt.Fprint(out, "\x1b[?1006l\x1b[?1000l")
+}
+
+// parseSGRMouse parses an SGR mouse report, ESC [ < b ; x ; y M (press) or
+// m (release), given the parameters after the '<' and the final byte. Left
+// presses and wheel notches become events; releases, drags and other
+// buttons are keyUnknown.
+func parseSGRMouse(params string, final byte) inputEvent {
+	parts := strings.Split(params, ";")
+	if len(parts) != 3 {
+		return inputEvent{kind: keyUnknown}
+	}
+	var nums [3]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return inputEvent{kind: keyUnknown}
+		}
+		nums[i] = n
+	}
+	button, col, row := nums[0], nums[1], nums[2]
+	if final == 'm' || button&32 != 0 {
+		return inputEvent{kind: keyUnknown}
+	}
+	// Modifier bits (shift 4, meta 8, ctrl 16) don't change the action.
+	switch button &^ 28 {
+	case 0:
+		return inputEvent{kind: mouseClick, row: row, col: col}
+	case 64:
+		return inputEvent{kind: mouseWheelUp, row: row, col: col}
+	case 65:
+		return inputEvent{kind: mouseWheelDown, row: row, col: col}
+	default:
+		return inputEvent{kind: keyUnknown}
+	}
+}
+
+// handleMouse handles mouse events the same way in browse and query mode.
+func handleMouse(state *appState, ev inputEvent, out *bufio.Writer) bool {
+	if state.lastRows <= 0 || state.lastCols <= 0 {
+		return false
+	}
+	layout := buildLayout(state, state.lastRows, state.lastCols)
+	if state.grid && ev.row != layout.searchRow && ev.row != layout.hintsRow {
+		handleGridMouse(state, layout, ev)
+		return false
+	}
+	switch ev.kind {
+	case mouseWheelUp:
+		scrollList(state, layout, -wheelStep)
+	case mouseWheelDown:
+		scrollList(state, layout, wheelStep)
+	case mouseClick:
+		return handleClick(state, layout, ev.row, ev.col, out)
+	case keyRune, keyEnter, keyBackspace, keyEsc, keyUp, keyDown, keyCtrlC, keyUnknown,
+		keyLeft, keyRight, keyHome, keyEnd, keyPageUp, keyPageDown, keyDelete, keyTab, keyDeleteWord, keyDeleteLine, keyPaste, keyKillEnd, keyYank:
+	}
+	return false
+}
+
+func scrollList(state *appState, layout layout, delta int) {
+	maxScroll := maxInt(0, len(state.results)-layout.listHeight)
+	scroll := minInt(maxScroll, maxInt(0, state.scroll+delta))
+	if scroll != state.scroll {
+		state.scroll = scroll
+		state.renderDirty = true
+	}
+}
+
+func handleClick(state *appState, layout layout, row, col int, out *bufio.Writer) bool {
+	switch {
+	case inPreview(state, layout, row, col):
+		togglePause(state, out)
+	case !state.detail && layout.hasContent && row >= layout.contentTop && row < layout.contentTop+layout.listHeight && col >= layout.listCol:
+		idx := state.scroll + row - layout.contentTop
+		if idx >= len(state.results) {
+			return false
+		}
+		state.mode = modeBrowse
+		if idx != state.selected {
+			state.selected = idx
+			loadSelectedImage(state)
+		}
+		state.renderDirty = true
+	case row == layout.statusRow:
+		seekTimeline(state, layout, col)
+	case row == layout.searchRow:
+		state.mode = modeQuery
+		state.status = "Type a search and press Enter"
+		state.renderDirty = true
+	case row == layout.hintsRow:
+		if h, ok := hintAt(activeKeymap(state).hints(), layout.cols, col); ok {
+			return runHint(state, h, out)
+		}
+	}
+	return false
+}
+
+func inPreview(state *appState, layout layout, row, col int) bool {
+	if state.currentAnim == nil || layout.previewCols <= 0 || layout.previewRows <= 0 {
+		return false
+	}
+	return row >= layout.previewRow && row < layout.previewRow+layout.previewRows &&
+		col >= layout.previewCol && col < layout.previewCol+layout.previewCols
+}
+
+// hintAt returns the hint drawn at col of the hint bar.
+func hintAt(hs []hint, cols, col int) (hint, bool) {
+	start := hintsPad(hs, cols) + 1
+	for _, h := range hs {
+		end := start + runeLen(h.key) + 1 + runeLen(h.label)
+		if col >= start && col < end {
+			return h, true
+		}
+		start = end + runeLen(hintGap)
+	}
+	return hint{}, false
+}
+
+func runHint(state *appState, h hint, out *bufio.Writer) bool {
+	if h.key == "⏎" {
+		return handleInput(state, inputEvent{kind: keyEnter}, out)
+	}
+	return runAction(state, h.act, out)
+}
+
+// togglePause stops or resumes the preview animation. Software playback
+// simply stops advancing; Kitty animations are stopped in the terminal.
+func togglePause(state *appState, out *bufio.Writer) {
+	if state.currentAnim == nil {
+		return
+	}
+	native := state.inline == termcaps.InlineKitty && !state.useSoftwareAnim
+	if !native && !usesFrames(state) {
+		flashHeader(state, "Pause needs software playback (GIFGREP_SOFTWARE_ANIM=1)")
+		state.renderDirty = true
+		return
+	}
+	state.paused = !state.paused
+	if native && state.activeImageID != 0 {
+		state.kitty.SetPlaying(out, state.activeImageID, !state.paused)
+	}
+	if !state.paused && state.manualAnim {
+		state.manualNext = time.Now()
+	}
+	if state.paused {
+		state.status = "Paused"
+	} else {
+		state.status = "Playing"
+	}
+	state.renderDirty = true
+}
@@ -0,0 +1,194 @@
+package tui
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steipete/gifgrep/gifdecode"
+	"github.com/steipete/gifgrep/internal/model"
+	"github.com/steipete/gifgrep/internal/termcaps"
+)
+
+func TestReadInputMouse(t *testing.T) {
+	in := strings.NewReader("\x1b[<0;12;5M\x1b[<0;12;5m\x1b[<64;3;4M\x1b[<65;3;4M\x1b[<32;1;1M\x1b[<2;1;1M")
+	ch := make(chan inputEvent, 8)
+	readInput(in, ch, make(chan struct{}))
+	close(ch)
+	var got []inputEvent
+	for ev := range ch {
+		got = append(got, ev)
+	}
+	want := []inputEvent{
+		{kind: mouseClick, row: 5, col: 12},
+		{kind: keyUnknown},
+		{kind: mouseWheelUp, row: 4, col: 3},
+		{kind: mouseWheelDown, row: 4, col: 3},
+		{kind: keyUnknown},
+		{kind: keyUnknown},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d events, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("event %d: got %+v want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func mouseTestState(n int) *appState {
+	results := make([]model.Result, n)
+	for i := range results {
+		results[i] = model.Result{ID: string(rune('a' + i)), Title: "GIF"}
+	}
+	return &appState{
+		mode:     modeBrowse,
+		results:  results,
+		selected: 0,
+		lastRows: 12,
+		lastCols: 60,
+		inline:   termcaps.InlineText,
+		cache:    map[string]*gifCacheEntry{},
+	}
+}
+
+func TestMouseClickSelectsResult(t *testing.T) {
+	state := mouseTestState(5)
+	state.mode = modeQuery
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+	// Rows start at contentTop (2), so row 4 is the third result.
+	if handleInput(state, inputEvent{kind: mouseClick, row: 4, col: 5}, out) {
+		t.Fatalf("unexpected quit")
+	}
+	if state.selected != 2 || state.mode != modeBrowse {
+		t.Fatalf("expected third result selected in browse mode, got %d (mode %v)", state.selected, state.mode)
+	}
+	// Below the last result: nothing happens.
+	handleInput(state, inputEvent{kind: mouseClick, row: 8, col: 5}, out)
+	if state.selected != 2 {
+		t.Fatalf("expected selection unchanged, got %d", state.selected)
+	}
+}
+
+func TestMouseWheelScrollsList(t *testing.T) {
+	state := mouseTestState(30)
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+	handleInput(state, inputEvent{kind: mouseWheelDown, row: 3, col: 3}, out)
+	if state.scroll != wheelStep || state.selected != 0 {
+		t.Fatalf("expected scroll %d with selection kept, got scroll %d selected %d", wheelStep, state.scroll, state.selected)
+	}
+	for i := 0; i < 20; i++ {
+		handleInput(state, inputEvent{kind: mouseWheelDown}, out)
+	}
+	layout := buildLayout(state, state.lastRows, state.lastCols)
+	if state.scroll != len(state.results)-layout.listHeight {
+		t.Fatalf("expected scroll clamped to the last page, got %d", state.scroll)
+	}
+	for i := 0; i < 20; i++ {
+		handleInput(state, inputEvent{kind: mouseWheelUp}, out)
+	}
+	if state.scroll != 0 {
+		t.Fatalf("expected scroll back at the top, got %d", state.scroll)
+	}
+}
+
+func TestMouseClickHints(t *testing.T) {
+	state := mouseTestState(2)
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+	layout := buildLayout(state, state.lastRows, state.lastCols)
+
+	hs := defaultKeymap.hints()
+	col := hintsPad(hs, layout.cols) + 1
+	for _, h := range hs {
+		if h.key == "/" {
+			break
+		}
+		col += runeLen(h.key) + 1 + runeLen(h.label) + runeLen(hintGap)
+	}
+	if h, ok := hintAt(hs, layout.cols, col); !ok || h.key != "/" {
+		t.Fatalf("expected the Edit hint at col %d, got %+v", col, h)
+	}
+	handleInput(state, inputEvent{kind: mouseClick, row: layout.hintsRow, col: col}, out)
+	if state.mode != modeQuery {
+		t.Fatalf("expected Edit hint to enter query mode")
+	}
+
+	state.mode = modeBrowse
+	last := hs[len(hs)-1]
+	qCol := hintsPad(hs, layout.cols) + visibleRuneLen(hintsText(hs)) - runeLen(last.label)
+	if !handleInput(state, inputEvent{kind: mouseClick, row: layout.hintsRow, col: qCol}, out) {
+		t.Fatalf("expected Quit hint to quit")
+	}
+	if handleInput(state, inputEvent{kind: mouseClick, row: layout.hintsRow, col: 1}, out) {
+		t.Fatalf("expected a click beside the hints to do nothing")
+	}
+}
+
+func hintsText(hs []hint) string {
+	parts := make([]string, 0, len(hs))
+	for _, h := range hs {
+		parts = append(parts, h.key+" "+h.label)
+	}
+	return strings.Join(parts, hintGap)
+}
+
+func TestMouseClickPreviewPauses(t *testing.T) {
+	state := mouseTestState(1)
+	state.lastRows, state.lastCols = 24, 100
+	state.useSoftwareAnim = true
+	state.inline = termcaps.InlineKitty
+	state.currentAnim = &gifAnimation{
+		ID:     1,
+		Width:  100,
+		Height: 100,
+		Frames: []gifdecode.Frame{
+			{PNG: []byte{1}, Delay: 10 * time.Millisecond},
+			{PNG: []byte{2}, Delay: 10 * time.Millisecond},
+		},
+	}
+	state.manualAnim = true
+	state.previewRow, state.previewCol = 2, 1
+	state.lastPreview.cols, state.lastPreview.rows = 10, 5
+	layout := buildLayout(state, state.lastRows, state.lastCols)
+
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+	handleInput(state, inputEvent{kind: mouseClick, row: layout.previewRow, col: layout.previewCol}, out)
+	if !state.paused {
+		t.Fatalf("expected click on preview to pause")
+	}
+	state.manualNext = time.Now().Add(-time.Millisecond)
+	advanceManualAnimation(state, out)
+	if state.manualFrame != 0 {
+		t.Fatalf("expected paused animation to stay on its frame")
+	}
+
+	handleInput(state, inputEvent{kind: mouseClick, row: layout.previewRow, col: layout.previewCol}, out)
+	advanceManualAnimation(state, out)
+	if state.paused || state.manualFrame != 1 {
+		t.Fatalf("expected resume to advance, paused=%v frame=%d", state.paused, state.manualFrame)
+	}
+}
+
+func TestMouseClickPreviewPausesNativeKitty(t *testing.T) {
+	state := mouseTestState(1)
+	state.lastRows, state.lastCols = 24, 100
+	state.inline = termcaps.InlineKitty
+	state.activeImageID = 9
+	state.currentAnim = &gifAnimation{ID: 9, Width: 100, Height: 100}
+	layout := buildLayout(state, state.lastRows, state.lastCols)
+
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+	handleInput(state, inputEvent{kind: mouseClick, row: layout.previewRow, col: layout.previewCol}, out)
+	_ = out.Flush()
+	if !state.paused || !strings.Contains(buf.String(), "a=a,i=9,s=1") {
+		t.Fatalf("expected kitty animation stopped, got %q", buf.String())
+	}
+}
@@ -0,0 +1,207 @@
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/steipete/gifgrep/gifdecode"
+	"github.com/steipete/gifgrep/internal/stills"
+	"github.com/steipete/gifgrep/internal/termcaps"
+)
+
+// Playback speed is 2^speedStep, from 0.25x to 4x.
+const (
+	minSpeedStep = -2
+	maxSpeedStep = 2
+)
+
+// timelineBarCols is the width of the scrubber bar in the status row.
+const timelineBarCols = 20
+
+// playbackControls reports whether the preview can be stepped, scrubbed
+// and sped up. A native Kitty animation is switched to software playback
+// first; its decoded frames are already at hand.
+func playbackControls(state *appState) bool {
+	if state.grid || state.currentAnim == nil {
+		return false
+	}
+	if state.inline == termcaps.InlineIterm && !state.useSoftwareAnim {
+		flashHeader(state, "Playback controls need software playback (GIFGREP_SOFTWARE_ANIM=1)")
+		state.renderDirty = true
+		return false
+	}
+	if len(state.currentAnim.Frames) <= 1 {
+		return false
+	}
+	if state.inline == termcaps.InlineKitty && !state.useSoftwareAnim {
+		state.useSoftwareAnim = true
+		state.previewNeedsSend = true
+		state.previewDirty = true
+	}
+	return true
+}
+
+// softwarePlayback reports whether gifgrep draws the preview frame by frame
+// (and so knows which frame is showing).
+func softwarePlayback(state *appState) bool {
+	return state.currentAnim != nil && len(state.currentAnim.Frames) > 1 &&
+		(inTextGrid(state.inline) || state.useSoftwareAnim)
+}
+
+// stepFrame pauses and moves delta frames, wrapping around.
+func stepFrame(state *appState, delta int) {
+	if !playbackControls(state) {
+		return
+	}
+	state.paused = true
+	seekFrame(state, state.manualFrame+delta)
+}
+
+// scrub jumps a tenth of the loop forward or back, and at least one frame.
+func scrub(state *appState, delta int) {
+	if !playbackControls(state) {
+		return
+	}
+	frames := state.currentAnim.Frames
+	from := state.manualFrame
+	seekTime(state, frameStart(frames, from)+time.Duration(delta)*totalDelay(frames)/10)
+	if state.manualFrame == from {
+		seekFrame(state, from+delta)
+	}
+}
+
+// seekTime shows the frame on screen at t into the loop, as `still --at`
+// picks it.
+func seekTime(state *appState, t time.Duration) {
+	frames := state.currentAnim.Frames
+	if total := totalDelay(frames); total > 0 {
+		t %= total
+		if t < 0 {
+			t += total
+		}
+	}
+	idx, err := stills.FrameIndexAt(frames, t)
+	if err != nil {
+		return
+	}
+	seekFrame(state, idx)
+}
+
+// seekFrame shows frame idx; a playing animation continues from there.
+func seekFrame(state *appState, idx int) {
+	n := len(state.currentAnim.Frames)
+	state.manualFrame = ((idx % n) + n) % n
+	if !state.paused {
+		state.manualNext = time.Now().Add(playbackDelay(state, state.manualFrame))
+	}
+	state.previewDirty = true
+	state.renderDirty = true
+}
+
+func changeSpeed(state *appState, delta int) {
+	if !playbackControls(state) {
+		return
+	}
+	step := minInt(maxSpeedStep, maxInt(minSpeedStep, state.speedStep+delta))
+	if step == state.speedStep {
+		return
+	}
+	state.speedStep = step
+	if !state.paused {
+		state.manualNext = time.Now().Add(playbackDelay(state, state.manualFrame))
+	}
+	state.status = "Speed " + speedLabel(state)
+	state.renderDirty = true
+}
+
+func playbackSpeed(state *appState) float64 {
+	return math.Pow(2, float64(state.speedStep))
+}
+
+// playbackDelay is how long frame i stays up at the current speed.
+func playbackDelay(state *appState, i int) time.Duration {
+	return time.Duration(float64(state.currentAnim.Frames[i].Delay) / playbackSpeed(state))
+}
+
+func speedLabel(state *appState) string {
+	return strconv.FormatFloat(playbackSpeed(state), 'g', -1, 64) + "×"
+}
+
+// timeline renders the playback position for the status row: play state,
+// the frame's timestamp (what `still --at` takes), a scrubber bar and the
+// speed. barAt is the bar's offset in cells.
+func timeline(state *appState) (string, int) {
+	if state.grid || !softwarePlayback(state) {
+		return "", 0
+	}
+	frames := state.currentAnim.Frames
+	pos, total := frameStart(frames, state.manualFrame), totalDelay(frames)
+	icon := "▶"
+	if state.paused {
+		icon = "⏸"
+	}
+	head := fmt.Sprintf("%s %.2fs/%.2fs ", icon, pos.Seconds(), total.Seconds())
+	filled := 0
+	if total > 0 {
+		filled = int(float64(timelineBarCols-1) * float64(pos) / float64(total))
+	}
+	bar := strings.Repeat("━", filled) + "●" + strings.Repeat("─", timelineBarCols-1-filled)
+	return head + styleIf(state.useColor, bar, "\x1b[36m") + " " + speedLabel(state), runeLen(head)
+}
+
+// timelineBarCol returns the screen column of the scrubber bar, which is
+// right-aligned in the status row, or 0 without one.
+func timelineBarCol(state *appState, layout layout) int {
+	text, barAt := timeline(state)
+	if text == "" {
+		return 0
+	}
+	return statusCols(state, layout) - visibleRuneLen(text) + 1 + barAt
+}
+
+// seekTimeline jumps to the point of the loop under a click on the bar.
+func seekTimeline(state *appState, layout layout, col int) {
+	start := timelineBarCol(state, layout)
+	if start <= 0 || col < start || col >= start+timelineBarCols {
+		return
+	}
+	total := totalDelay(state.currentAnim.Frames)
+	// The last cell is the end of the loop, i.e. the last frame.
+	seekTime(state, minDuration(total-1, total*time.Duration(col-start)/time.Duration(timelineBarCols-1)))
+}
+
+// redrawTimeline updates the status row between renders, as frames advance.
+func redrawTimeline(state *appState, out *bufio.Writer) {
+	if state.lastRows <= 0 || state.lastCols <= 0 {
+		return
+	}
+	layout := buildLayout(state, state.lastRows, state.lastCols)
+	if !layout.hasContent {
+		return
+	}
+	drawStatusLine(out, state, layout)
+	state.screen.commit()
+}
+
+func frameStart(frames []gifdecode.Frame, i int) time.Duration {
+	var t time.Duration
+	for _, frame := range frames[:i] {
+		t += frame.Delay
+	}
+	return t
+}
+
+func totalDelay(frames []gifdecode.Frame) time.Duration {
+	return frameStart(frames, len(frames))
+}
+
+func minDuration(a, b time.Duration) time.Duration {
+	if a < b {
+		return a
+	}
+	return b
+}
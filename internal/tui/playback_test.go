@@ -0,0 +1,150 @@
+package tui
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steipete/gifgrep/gifdecode"
+	"github.com/steipete/gifgrep/internal/model"
+	"github.com/steipete/gifgrep/internal/termcaps"
+)
+
+func playbackTestState() *appState {
+	return &appState{
+		mode:     modeBrowse,
+		inline:   termcaps.InlineText,
+		results:  []model.Result{{ID: "1", Title: "one"}},
+		lastRows: 20,
+		lastCols: 80,
+		currentAnim: &gifAnimation{
+			ID:     1,
+			Width:  10,
+			Height: 10,
+			Frames: []gifdecode.Frame{
+				{PNG: []byte{1}, Delay: 100 * time.Millisecond},
+				{PNG: []byte{2}, Delay: 200 * time.Millisecond},
+				{PNG: []byte{3}, Delay: 300 * time.Millisecond},
+			},
+		},
+	}
+}
+
+func TestPlaybackStepAndSpeed(t *testing.T) {
+	state := playbackTestState()
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+
+	handleInput(state, inputEvent{kind: keyRune, ch: '.'}, out)
+	if !state.paused || state.manualFrame != 1 {
+		t.Fatalf("expected paused at frame 1, got %d (paused %v)", state.manualFrame, state.paused)
+	}
+	handleInput(state, inputEvent{kind: keyRune, ch: ','}, out)
+	handleInput(state, inputEvent{kind: keyRune, ch: ','}, out)
+	if state.manualFrame != 2 {
+		t.Fatalf("expected step back to wrap to frame 2, got %d", state.manualFrame)
+	}
+	if tl, _ := timeline(state); !strings.HasPrefix(tl, "⏸ 0.30s/0.60s ") || !strings.HasSuffix(tl, " 1×") {
+		t.Fatalf("unexpected timeline %q", tl)
+	}
+
+	for i := 0; i < 3; i++ {
+		handleInput(state, inputEvent{kind: keyRune, ch: '+'}, out)
+	}
+	if speedLabel(state) != "4×" || playbackDelay(state, 0) != 25*time.Millisecond {
+		t.Fatalf("expected 4x cap, got %s", speedLabel(state))
+	}
+	for i := 0; i < 5; i++ {
+		handleInput(state, inputEvent{kind: keyRune, ch: '-'}, out)
+	}
+	if speedLabel(state) != "0.25×" || playbackDelay(state, 0) != 400*time.Millisecond {
+		t.Fatalf("expected 0.25x floor, got %s", speedLabel(state))
+	}
+	if state.mode != modeBrowse || state.query != "" {
+		t.Fatalf("playback keys should not start a search")
+	}
+}
+
+func TestPlaybackScrub(t *testing.T) {
+	state := playbackTestState()
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+
+	handleInput(state, inputEvent{kind: keyRune, ch: '['}, out)
+	if state.manualFrame != 2 {
+		t.Fatalf("expected scrub back to wrap into the last frame, got %d", state.manualFrame)
+	}
+	handleInput(state, inputEvent{kind: keyRune, ch: ']'}, out)
+	if state.manualFrame != 0 {
+		t.Fatalf("expected scrub forward to wrap to frame 0, got %d", state.manualFrame)
+	}
+	// A tenth of the loop is shorter than frame 0; still move one frame.
+	handleInput(state, inputEvent{kind: keyRune, ch: ']'}, out)
+	if state.manualFrame != 1 {
+		t.Fatalf("expected scrub to move at least a frame, got %d", state.manualFrame)
+	}
+
+	l := buildLayout(state, state.lastRows, state.lastCols)
+	start := timelineBarCol(state, l)
+	if start <= 0 {
+		t.Fatalf("expected a timeline bar")
+	}
+	for _, tc := range []struct{ col, frame int }{
+		{start + timelineBarCols - 1, 2},
+		{start + 5, 1},
+		{start, 0},
+	} {
+		handleInput(state, inputEvent{kind: mouseClick, row: l.statusRow, col: tc.col}, out)
+		if state.manualFrame != tc.frame {
+			t.Fatalf("click at col %d: expected frame %d, got %d", tc.col, tc.frame, state.manualFrame)
+		}
+	}
+}
+
+func TestPlaybackTimelineInStatusRow(t *testing.T) {
+	state := playbackTestState()
+	state.manualFrame = 1
+	state.screen = newScreenBuffer()
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+
+	render(state, out, state.lastRows, state.lastCols)
+	_ = out.Flush()
+	if !strings.Contains(buf.String(), "▶ 0.10s/0.60s ") {
+		t.Fatalf("expected timeline in status row, got %q", buf.String())
+	}
+
+	// Advancing a frame redraws just the timeline.
+	buf.Reset()
+	state.manualAnim = true
+	state.lastPreview.cols, state.lastPreview.rows = 4, 4
+	state.previewRow, state.previewCol = 3, 3
+	state.manualNext = time.Now().Add(-time.Millisecond)
+	advanceManualAnimation(state, out)
+	_ = out.Flush()
+	if state.manualFrame != 2 || !strings.Contains(buf.String(), "▶ 0.30s/0.60s ") {
+		t.Fatalf("expected timeline update, got %q", buf.String())
+	}
+}
+
+func TestPlaybackControlsSwitchNativeKitty(t *testing.T) {
+	state := playbackTestState()
+	state.inline = termcaps.InlineKitty
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+
+	handleInput(state, inputEvent{kind: keyRune, ch: '.'}, out)
+	if !state.useSoftwareAnim || !state.previewNeedsSend || state.manualFrame != 1 {
+		t.Fatalf("expected switch to software playback at frame 1")
+	}
+
+	state = playbackTestState()
+	state.inline = termcaps.InlineIterm
+	state.currentAnim.Frames = nil
+	handleInput(state, inputEvent{kind: keyRune, ch: '+'}, out)
+	if state.speedStep != 0 || !strings.Contains(state.headerFlash, "software playback") {
+		t.Fatalf("expected native iTerm2 playback to refuse, got %q", state.headerFlash)
+	}
+}
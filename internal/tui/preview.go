@@ -5,9 +5,32 @@ import (
 	"time"
 
 	"github.com/steipete/gifgrep/gifdecode"
+	"github.com/steipete/gifgrep/internal/blocks"
+	"github.com/steipete/gifgrep/internal/sixel"
 	"github.com/steipete/gifgrep/internal/termcaps"
 )
 
+// usesFrames reports whether previews draw decoded frames (as opposed to
+// native iTerm playback, which gets the raw GIF).
+func usesFrames(state *appState) bool {
+	switch state.inline {
+	case termcaps.InlineKitty, termcaps.InlineSixel, termcaps.InlineText:
+		return true
+	case termcaps.InlineIterm:
+		return state.useSoftwareAnim
+	case termcaps.InlineNone:
+		return false
+	default:
+		return false
+	}
+}
+
+// inTextGrid reports whether the protocol paints into the character grid,
+// where any redraw around the preview may erase part of it.
+func inTextGrid(inline termcaps.InlineProtocol) bool {
+	return inline == termcaps.InlineSixel || inline == termcaps.InlineText || inline == termcaps.InlineIterm
+}
+
 func gifSize(raw []byte) (w, h int) {
 	if len(raw) < 10 {
 		return 0, 0
@@ -22,6 +45,12 @@ func gifSize(raw []byte) (w, h int) {
 }
 
 func loadSelectedImage(state *appState) {
+	if state.grid || state.help {
+		// The grid draws its own thumbnails instead of one preview; the
+		// help covers it.
+		state.currentAnim = nil
+		return
+	}
 	if state.selected < 0 || state.selected >= len(state.results) {
 		state.currentAnim = nil
 		state.previewDirty = true
@@ -33,39 +62,18 @@ func loadSelectedImage(state *appState) {
 		state.previewDirty = true
 		return
 	}
-	entry, ok := state.cache[item.PreviewURL]
-	if !ok {
-		data, err := fetchGIF(item.PreviewURL)
-		if err != nil {
-			state.status = "Image error: " + err.Error()
-			state.currentAnim = nil
-			return
-		}
-		w, h := gifSize(data)
-		entry = &gifCacheEntry{RawGIF: data, Width: w, Height: h}
-		if state.inline == termcaps.InlineKitty {
-			decoded, err := gifdecode.Decode(data, gifdecode.DefaultOptions())
-			if err != nil {
-				state.status = "Image error: " + err.Error()
-				state.currentAnim = nil
-				return
-			}
-			entry.Frames = decoded
-			entry.Width = decoded.Width
-			entry.Height = decoded.Height
-		}
-		state.cache[item.PreviewURL] = entry
+	entry, err := cachedGIF(state, item.PreviewURL)
+	if err != nil {
+		state.status = "Image error: " + err.Error()
+		state.currentAnim = nil
+		return
 	}
-	if entry != nil && entry.Frames == nil && state.inline == termcaps.InlineKitty {
-		decoded, err := gifdecode.Decode(entry.RawGIF, gifdecode.DefaultOptions())
-		if err != nil {
+	if usesFrames(state) && needsPreviewDecode(state, entry) {
+		if err := decodePreview(state, entry); err != nil {
 			state.status = "Image error: " + err.Error()
 			state.currentAnim = nil
 			return
 		}
-		entry.Frames = decoded
-		entry.Width = decoded.Width
-		entry.Height = decoded.Height
 	}
 
 	var frames []gifdecode.Frame
@@ -85,9 +93,163 @@ func loadSelectedImage(state *appState) {
 		state.currentAnim.Height = entry.Height
 	}
 	state.nextImageID++
+	state.paused = false
 	state.manualAnim = false
 	state.manualFrame = 0
 	state.manualNext = time.Time{}
 	state.previewNeedsSend = true
 	state.previewDirty = true
 }
+
+// previewCells returns the cell box the preview of a w x h image occupies at
+// the current terminal size.
+func previewCells(state *appState, w, h int) gifdecode.CellBox {
+	if state.lastRows <= 0 || state.lastCols <= 0 {
+		return gifdecode.CellBox{}
+	}
+	l := buildLayoutFor(&gifAnimation{Width: w, Height: h}, state.lastRows, state.lastCols, cellAspectRatio(state.cell))
+	return gifdecode.CellBox{Cols: l.previewCols, Rows: l.previewRows}
+}
+
+// needsPreviewDecode reports whether entry has no frames yet, or frames that
+// were downscaled for a smaller box than the preview now needs.
+func needsPreviewDecode(state *appState, entry *gifCacheEntry) bool {
+	if entry.Frames == nil {
+		return true
+	}
+	if entry.Cells.Cols <= 0 || entry.Cells.Rows <= 0 {
+		return false
+	}
+	box := previewCells(state, entry.Width, entry.Height)
+	return box.Cols > entry.Cells.Cols || box.Rows > entry.Cells.Rows
+}
+
+const (
+	textCellWidth  = 2
+	textCellHeight = 4
+)
+
+func decodePreview(state *appState, entry *gifCacheEntry) error {
+	box := gifdecode.CellBox{}
+	if entry.Width > 0 && entry.Height > 0 {
+		box = previewCells(state, entry.Width, entry.Height)
+	}
+	opts := decodeOptions(state, box)
+	decoded, err := gifdecode.Decode(entry.RawGIF, opts)
+	if err != nil {
+		return err
+	}
+	entry.Frames = decoded
+	entry.Cells = opts.TargetCells
+	if entry.Width <= 0 || entry.Height <= 0 {
+		entry.Width = decoded.Width
+		entry.Height = decoded.Height
+	}
+	return nil
+}
+
+// decodeOptions returns the decoder settings for frames shown in box with
+// the active protocol; a zero box keeps the GIF's own size.
+func decodeOptions(state *appState, box gifdecode.CellBox) gifdecode.Options {
+	opts := gifdecode.DefaultOptions()
+	opts.Recover = true
+	opts.MergeDuplicates = true
+	// Kitty plays uploaded animations itself; send changed rectangles only.
+	opts.Deltas = state.inline == termcaps.InlineKitty && !state.useSoftwareAnim
+	if box.Cols > 0 && box.Rows > 0 {
+		opts.TargetCells = box
+	}
+	if state.cell.Valid() {
+		// Frames at the real cell size are neither upscaled by the terminal
+		// nor larger than it can show.
+		opts.CellWidth = state.cell.Width
+		opts.CellHeight = state.cell.Height
+	} else if state.inline == termcaps.InlineSixel {
+		// Sixel draws 1:1 in pixels, so frames must fit the real cells.
+		opts.CellWidth = termcaps.DefaultCellWidth
+		opts.CellHeight = termcaps.DefaultCellHeight
+	}
+	if state.inline == termcaps.InlineText {
+		// Half-blocks show one pixel per column and two per row; keep a
+		// little extra for the box filter to average over.
+		opts.CellWidth = textCellWidth
+		opts.CellHeight = textCellHeight
+	}
+	return opts
+}
+
+// cachedGIF returns the cache entry for a preview URL, fetching it on
+// first use.
+func cachedGIF(state *appState, previewURL string) (*gifCacheEntry, error) {
+	if entry, ok := state.cache[previewURL]; ok {
+		return entry, nil
+	}
+	data, err := fetchGIF(previewURL)
+	if err != nil {
+		return nil, err
+	}
+	entry := newGIFCacheEntry(data)
+	state.cache[previewURL] = entry
+	return entry, nil
+}
+
+// newGIFCacheEntry wraps fetched GIF bytes with their size.
+func newGIFCacheEntry(data []byte) *gifCacheEntry {
+	w, h := gifSize(data)
+	return &gifCacheEntry{RawGIF: data, Width: w, Height: h}
+}
+
+// refreshPreviewResolution re-decodes the current preview when the terminal
+// grew past the size its frames were scaled for.
+func refreshPreviewResolution(state *appState) {
+	if state.currentAnim == nil || !usesFrames(state) {
+		return
+	}
+	if state.selected < 0 || state.selected >= len(state.results) {
+		return
+	}
+	entry, ok := state.cache[state.results[state.selected].PreviewURL]
+	if !ok || entry == nil || !needsPreviewDecode(state, entry) {
+		return
+	}
+	loadSelectedImage(state)
+}
+
+// sixelFrame returns frame idx encoded as sixel, encoding it on first use.
+func sixelFrame(anim *gifAnimation, idx int) *sixel.Image {
+	if anim == nil || idx < 0 || idx >= len(anim.Frames) {
+		return nil
+	}
+	if len(anim.sixels) != len(anim.Frames) {
+		anim.sixels = make([]*sixel.Image, len(anim.Frames))
+	}
+	if anim.sixels[idx] == nil {
+		img, err := sixel.EncodePNG(anim.Frames[idx].PNG, sixel.Options{})
+		if err != nil {
+			return nil
+		}
+		anim.sixels[idx] = img
+	}
+	return anim.sixels[idx]
+}
+
+// textFrame returns frame idx rendered as text for a cols x rows box,
+// rendering it on first use. Changing the box drops the cache.
+func textFrame(anim *gifAnimation, idx, cols, rows int, depth termcaps.ColorDepth) []string {
+	if anim == nil || idx < 0 || idx >= len(anim.Frames) {
+		return nil
+	}
+	box := gifdecode.CellBox{Cols: cols, Rows: rows}
+	if len(anim.text) != len(anim.Frames) || anim.textCells != box {
+		anim.text = make([][]string, len(anim.Frames))
+		anim.textCells = box
+	}
+	if anim.text[idx] == nil {
+		lines, err := blocks.RenderPNG(anim.Frames[idx].PNG, cols, rows, depth)
+		if err != nil {
+			return nil
+		}
+		anim.text[idx] = lines
+	}
+	return anim.text[idx]
+}
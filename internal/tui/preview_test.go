@@ -1,7 +1,11 @@
 package tui
 
 import (
+	"bytes"
 	"errors"
+	"image"
+	"image/color"
+	"image/gif"
 	"net/http"
 	"testing"
 	"time"
@@ -71,3 +75,86 @@ func TestFetchGIFError(t *testing.T) {
 		}
 	})
 }
+
+func TestLoadSelectedImageScalesToPreviewBox(t *testing.T) {
+	frame := image.NewPaletted(image.Rect(0, 0, 2000, 1000), color.Palette{color.Black, color.White})
+	var buf bytes.Buffer
+	if err := gif.Encode(&buf, frame, nil); err != nil {
+		t.Fatalf("gif encode failed: %v", err)
+	}
+	url := "https://example.test/big.gif"
+	entry := &gifCacheEntry{RawGIF: buf.Bytes(), Width: 2000, Height: 1000}
+	state := &appState{
+		results:  []model.Result{{Title: "big", PreviewURL: url}},
+		inline:   termcaps.InlineKitty,
+		cache:    map[string]*gifCacheEntry{url: entry},
+		lastRows: 30,
+		lastCols: 100,
+	}
+	loadSelectedImage(state)
+	if state.currentAnim == nil || entry.Frames == nil {
+		t.Fatalf("expected decoded animation")
+	}
+	if state.currentAnim.Width != 2000 || state.currentAnim.Height != 1000 {
+		t.Fatalf("expected source size for layout, got %dx%d", state.currentAnim.Width, state.currentAnim.Height)
+	}
+	small := entry.Cells
+	if small.Cols <= 0 || small.Rows <= 0 {
+		t.Fatalf("expected preview cell box, got %+v", small)
+	}
+	if entry.Frames.Width >= 2000 {
+		t.Fatalf("expected downscaled frames, got width %d", entry.Frames.Width)
+	}
+	layout := buildLayout(state, state.lastRows, state.lastCols)
+	if layout.previewCols != small.Cols || layout.previewRows != small.Rows {
+		t.Fatalf("expected box %+v to match layout %dx%d", small, layout.previewCols, layout.previewRows)
+	}
+
+	state.lastRows = 80
+	state.lastCols = 300
+	refreshPreviewResolution(state)
+	if entry.Cells.Cols <= small.Cols {
+		t.Fatalf("expected re-decode for larger box, got %+v", entry.Cells)
+	}
+
+	decoded := entry.Frames
+	state.lastRows = 30
+	state.lastCols = 100
+	refreshPreviewResolution(state)
+	if entry.Frames != decoded {
+		t.Fatalf("expected no re-decode when shrinking")
+	}
+}
+
+func TestDecodePreviewDeltasForNativeKitty(t *testing.T) {
+	pal := color.Palette{color.Black, color.White}
+	g := &gif.GIF{Config: image.Config{Width: 20, Height: 20, ColorModel: pal}}
+	for x := 0; x < 3; x++ {
+		frame := image.NewPaletted(image.Rect(0, 0, 20, 20), pal)
+		frame.SetColorIndex(x*5, 5, 1)
+		g.Image = append(g.Image, frame)
+		g.Delay = append(g.Delay, 5)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		t.Fatalf("gif encode failed: %v", err)
+	}
+
+	state := &appState{inline: termcaps.InlineKitty}
+	entry := &gifCacheEntry{RawGIF: buf.Bytes(), Width: 20, Height: 20}
+	if err := decodePreview(state, entry); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if entry.Frames.Frames[1].Delta == nil {
+		t.Fatalf("expected frame deltas for native kitty animation")
+	}
+
+	state.useSoftwareAnim = true
+	entry = &gifCacheEntry{RawGIF: buf.Bytes(), Width: 20, Height: 20}
+	if err := decodePreview(state, entry); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if entry.Frames.Frames[1].Delta != nil {
+		t.Fatalf("software playback sends whole frames; deltas are wasted work")
+	}
+}
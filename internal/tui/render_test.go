@@ -35,7 +35,7 @@ func TestRenderDoesNotClearItermPreviewEveryRender(t *testing.T) {
 	t.Cleanup(func() { clearPreviewAreaFn = prev })
 
 	var clears int
-	clearPreviewAreaFn = func(_ *bufio.Writer, _ layout) { clears++ }
+	clearPreviewAreaFn = func(_ *bufio.Writer, _ *screenBuffer, _ layout) { clears++ }
 
 	state := &appState{
 		mode:   modeBrowse,
@@ -69,7 +69,7 @@ func TestRenderKeepsClearingKittyPreview(t *testing.T) {
 	t.Cleanup(func() { clearPreviewAreaFn = prev })
 
 	var clears int
-	clearPreviewAreaFn = func(_ *bufio.Writer, _ layout) { clears++ }
+	clearPreviewAreaFn = func(_ *bufio.Writer, _ *screenBuffer, _ layout) { clears++ }
 
 	state := &appState{
 		mode:   modeBrowse,
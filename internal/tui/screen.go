@@ -0,0 +1,116 @@
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+)
+
+// screenBuffer does line-level change detection for the TUI's text. render
+// writes every line segment through it, keyed by row, column and width; a
+// segment whose text matches what the previous render wrote there is
+// skipped. It doesn't diff cells: a segment with one changed cell is
+// rewritten whole. A nil buffer writes everything.
+type screenBuffer struct {
+	front map[lineKey]string // what the terminal shows
+	back  map[lineKey]string // what the current render wrote
+}
+
+type lineKey struct {
+	row, col, width int
+	padded          bool // written by writeCellsAt
+}
+
+func newScreenBuffer() *screenBuffer {
+	return &screenBuffer{}
+}
+
+// writeLineAt writes a line unless the terminal already shows it, and
+// reports whether it wrote.
+func (s *screenBuffer) writeLineAt(out *bufio.Writer, row, col int, text string, width int) bool {
+	if s == nil {
+		writeLineAt(out, row, col, text, width)
+		return true
+	}
+	key := lineKey{row: row, col: col, width: width}
+	if s.back == nil {
+		s.back = map[lineKey]string{}
+	}
+	s.back[key] = text
+	if prev, ok := s.front[key]; ok && prev == text {
+		return false
+	}
+	writeLineAt(out, row, col, text, width)
+	return true
+}
+
+// writeCellsAt writes text padded to exactly width cells, leaving the rest
+// of the row alone (writeLineAt erases to the end of the line).
+func (s *screenBuffer) writeCellsAt(out *bufio.Writer, row, col int, text string, width int) {
+	if width <= 0 {
+		return
+	}
+	text = truncateANSI(text, width)
+	if pad := width - visibleRuneLen(text); pad > 0 {
+		text += strings.Repeat(" ", pad)
+	}
+	key := lineKey{row: row, col: col, width: width, padded: true}
+	if s != nil {
+		if s.back == nil {
+			s.back = map[lineKey]string{}
+		}
+		s.back[key] = text
+		if prev, ok := s.front[key]; ok && prev == text {
+			return
+		}
+	}
+	moveCursor(out, row, col)
+	_, _ = out.WriteString(text)
+}
+
+// swap makes what this render wrote the front buffer.
+func (s *screenBuffer) swap() {
+	if s == nil {
+		return
+	}
+	s.front, s.back = s.back, nil
+}
+
+// commit makes lines written outside a render (the playback timeline
+// between frames) part of the front buffer.
+func (s *screenBuffer) commit() {
+	if s == nil {
+		return
+	}
+	if s.front == nil {
+		s.front = map[lineKey]string{}
+	}
+	for key, text := range s.back {
+		s.front[key] = text
+	}
+	s.back = nil
+}
+
+// invalidate forgets the front buffer so the next render writes every line;
+// needed whenever something else (a clear, an image in the text grid) may
+// have overwritten the lines.
+func (s *screenBuffer) invalidate() {
+	if s == nil {
+		return
+	}
+	s.front = nil
+}
+
+// beginSync and endSync bracket a frame with DEC mode 2026, so terminals
+// that support it show the frame at once instead of line by line.
+func beginSync(out *bufio.Writer, enabled bool) {
+	if enabled {
+		_, _ = fmt.Fprint(out, "\x1b[?2026h")
+	}
+}
+
+func endSync(out *bufio.Writer, enabled bool) {
+	if enabled {
+		_, _ = fmt.Fprint(out, "\x1b[?2026l")
+	}
+}
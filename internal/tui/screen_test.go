@@ -0,0 +1,219 @@
+package tui
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steipete/gifgrep/gifdecode"
+	"github.com/steipete/gifgrep/internal/model"
+	"github.com/steipete/gifgrep/internal/termcaps"
+)
+
+func TestScreenBufferSkipsUnchangedLines(t *testing.T) {
+	screen := newScreenBuffer()
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+
+	screen.writeLineAt(out, 1, 1, "header", 20)
+	screen.writeLineAt(out, 2, 1, "first", 20)
+	screen.swap()
+	_ = out.Flush()
+	if !strings.Contains(buf.String(), "header") || !strings.Contains(buf.String(), "first") {
+		t.Fatalf("expected first frame to write every line, got %q", buf.String())
+	}
+
+	buf.Reset()
+	screen.writeLineAt(out, 1, 1, "header", 20)
+	screen.writeLineAt(out, 2, 1, "second", 20)
+	screen.swap()
+	_ = out.Flush()
+	if strings.Contains(buf.String(), "header") || !strings.Contains(buf.String(), "\x1b[2;1Hsecond") {
+		t.Fatalf("expected only the changed line, got %q", buf.String())
+	}
+
+	buf.Reset()
+	screen.invalidate()
+	screen.writeLineAt(out, 1, 1, "header", 20)
+	screen.swap()
+	_ = out.Flush()
+	if !strings.Contains(buf.String(), "header") {
+		t.Fatalf("expected invalidate to force a redraw, got %q", buf.String())
+	}
+}
+
+func TestRenderEmitsOnlyChangedLines(t *testing.T) {
+	state := &appState{
+		mode:    modeBrowse,
+		results: []model.Result{{Title: "Alpha"}, {Title: "Beta"}},
+		inline:  termcaps.InlineText,
+		status:  "2 results",
+		screen:  newScreenBuffer(),
+		opts:    model.Options{Source: "tenor"},
+	}
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+	render(state, out, 20, 90)
+	_ = out.Flush()
+	if !strings.Contains(buf.String(), "Alpha") || !strings.Contains(buf.String(), "Search") {
+		t.Fatalf("expected full first render, got %q", buf.String())
+	}
+
+	buf.Reset()
+	state.status = "Saved"
+	render(state, out, 20, 90)
+	_ = out.Flush()
+	s := buf.String()
+	if !strings.Contains(s, "Saved") {
+		t.Fatalf("expected changed status line, got %q", s)
+	}
+	if strings.Contains(s, "Alpha") || strings.Contains(s, "Search") || strings.Contains(s, "gifgrep") {
+		t.Fatalf("expected unchanged lines to be skipped, got %q", s)
+	}
+}
+
+func TestManualAnimationFrameIsSynchronized(t *testing.T) {
+	state := &appState{
+		inline:          termcaps.InlineKitty,
+		useSoftwareAnim: true,
+		syncOutput:      true,
+		manualAnim:      true,
+		manualNext:      time.Now().Add(-time.Millisecond),
+		previewRow:      2,
+		previewCol:      1,
+		currentAnim: &gifAnimation{
+			ID: 1,
+			Frames: []gifdecode.Frame{
+				{PNG: []byte{1}, Delay: 10 * time.Millisecond},
+				{PNG: []byte{2}, Delay: 10 * time.Millisecond},
+			},
+		},
+	}
+	state.lastPreview.cols, state.lastPreview.rows = 10, 5
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+	advanceManualAnimation(state, out)
+	s := buf.String()
+	begin, end := strings.Index(s, "\x1b[?2026h"), strings.Index(s, "\x1b[?2026l")
+	if begin < 0 || end < begin || !strings.Contains(s[begin:end], "\x1b_G") {
+		t.Fatalf("expected the frame inside a synchronized update, got %q", s)
+	}
+}
+
+func TestRenderKittySplitKeepsList(t *testing.T) {
+	state := &appState{
+		mode:    modeBrowse,
+		inline:  termcaps.InlineKitty,
+		results: []model.Result{{Title: "Alpha"}, {Title: "Beta"}},
+		currentAnim: &gifAnimation{
+			ID:     1,
+			Frames: []gifdecode.Frame{{PNG: []byte{1, 2, 3}, Delay: 80 * time.Millisecond}},
+			Width:  200,
+			Height: 100,
+		},
+		previewNeedsSend: true,
+		status:           "2 results",
+		screen:           newScreenBuffer(),
+		opts:             model.Options{Source: "tenor"},
+	}
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+	term := newTestTerminal()
+	for _, status := range []string{"2 results", "Saved", "Downloading 1/2"} {
+		state.status = status
+		buf.Reset()
+		render(state, out, 20, 100)
+		_ = out.Flush()
+		term.write(buf.String())
+		screen := term.text()
+		if !strings.Contains(screen, "Alpha") || !strings.Contains(screen, "Beta") || !strings.Contains(screen, status) {
+			t.Fatalf("expected the list to survive a render with status %q, screen:\n%s", status, screen)
+		}
+	}
+}
+
+// testTerminal keeps the text a terminal would show: cursor positioning,
+// erase to end of line and printable runes; other sequences are skipped.
+type testTerminal struct {
+	rows     map[int][]rune
+	row, col int
+}
+
+func newTestTerminal() *testTerminal {
+	return &testTerminal{rows: map[int][]rune{}, row: 1, col: 1}
+}
+
+func (t *testTerminal) write(s string) {
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if r != 0x1b {
+			if r >= 0x20 {
+				t.put(r)
+			}
+			continue
+		}
+		if i+1 >= len(runes) {
+			return
+		}
+		i++
+		switch runes[i] {
+		case '[':
+			j := i + 1
+			for j < len(runes) && (runes[j] < 0x40 || runes[j] > 0x7e) {
+				j++
+			}
+			if j >= len(runes) {
+				return
+			}
+			t.csi(string(runes[i+1:j]), runes[j])
+			i = j
+		case '_', 'P', ']':
+			// APC (Kitty graphics), DCS and OSC run to ST or BEL.
+			for i+1 < len(runes) && runes[i+1] != 0x07 && (runes[i+1] != 0x1b || i+2 >= len(runes) || runes[i+2] != '\\') {
+				i++
+			}
+			if i+1 < len(runes) && runes[i+1] == 0x1b {
+				i++
+			}
+			i++
+		}
+	}
+}
+
+func (t *testTerminal) csi(params string, final rune) {
+	switch final {
+	case 'H':
+		t.row, t.col = 1, 1
+		if parts := strings.Split(params, ";"); len(parts) == 2 {
+			_, _ = fmt.Sscan(parts[0], &t.row)
+			_, _ = fmt.Sscan(parts[1], &t.col)
+		}
+	case 'K':
+		if line := t.rows[t.row]; len(line) >= t.col {
+			t.rows[t.row] = line[:t.col-1]
+		}
+	}
+}
+
+func (t *testTerminal) put(r rune) {
+	line := t.rows[t.row]
+	for len(line) < t.col {
+		line = append(line, ' ')
+	}
+	line[t.col-1] = r
+	t.rows[t.row] = line
+	t.col++
+}
+
+func (t *testTerminal) text() string {
+	var b strings.Builder
+	for row := 1; row <= 100; row++ {
+		b.WriteString(strings.TrimRight(string(t.rows[row]), " "))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
@@ -0,0 +1,140 @@
+package tui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/steipete/gifgrep/gifdecode"
+	"github.com/steipete/gifgrep/internal/download"
+	"github.com/steipete/gifgrep/internal/model"
+	"github.com/steipete/gifgrep/internal/stills"
+)
+
+// sheetFrames is how many frames a contact sheet samples, as `sheet` does
+// by default.
+const sheetFrames = 12
+
+// stillsResult is what a still or sheet export sends back from its
+// goroutine. The run loop applies it with pollStills, so state is only
+// touched there.
+type stillsResult struct {
+	url   string
+	entry *gifCacheEntry // the fetched GIF; nil when it was cached
+	msg   string         // flashed in the header
+}
+
+// exportStill saves the frame the preview shows as a PNG in the download
+// directory. With native playback the frame isn't known, so it's the first.
+func exportStill(state *appState) {
+	var at time.Duration
+	if softwarePlayback(state) {
+		at = frameStart(state.currentAnim.Frames, state.manualFrame)
+	}
+	startStillsExport(state, "Saving still…", func(item model.Result, decoded *gifdecode.Frames) string {
+		data, _, err := stills.FrameAtPNG(decoded, at)
+		if err != nil {
+			return "Still error: " + err.Error()
+		}
+		name := fmt.Sprintf("-%.2fs.png", at.Seconds())
+		path, err := saveExport(item, name, data)
+		if err != nil {
+			return "Still error: " + err.Error()
+		}
+		return fmt.Sprintf("Saved still at %.2fs to %s", at.Seconds(), path)
+	})
+}
+
+// exportSheet saves a contact sheet of the selected GIF.
+func exportSheet(state *appState) {
+	startStillsExport(state, "Saving sheet…", func(item model.Result, decoded *gifdecode.Frames) string {
+		data, err := stills.ContactSheet(decoded, stills.SheetOptions{Count: sheetFrames, Padding: 2})
+		if err != nil {
+			return "Sheet error: " + err.Error()
+		}
+		path, err := saveExport(item, "-sheet.png", data)
+		if err != nil {
+			return "Sheet error: " + err.Error()
+		}
+		return "Saved sheet to " + path
+	})
+}
+
+// startStillsExport decodes every frame of the selected GIF at full size
+// and passes them to export, off the input loop: a long GIF takes seconds.
+// Preview frames are scaled and may be capped, so they can't be reused.
+func startStillsExport(state *appState, label string, export func(model.Result, *gifdecode.Frames) string) {
+	state.renderDirty = true
+	if state.stills != nil {
+		flashHeader(state, "Export in progress")
+		return
+	}
+	if state.selected < 0 || state.selected >= len(state.results) {
+		flashHeader(state, "No selection")
+		return
+	}
+	item := state.results[state.selected]
+	if item.PreviewURL == "" {
+		flashHeader(state, "No preview")
+		return
+	}
+	var cached []byte
+	if entry := state.cache[item.PreviewURL]; entry != nil {
+		cached = entry.RawGIF
+	}
+	results := make(chan stillsResult, 1)
+	state.stills = results
+	flashHeader(state, label)
+	go func() {
+		res := stillsResult{url: item.PreviewURL}
+		data := cached
+		if data == nil {
+			fetched, err := fetchGIF(item.PreviewURL)
+			if err != nil {
+				res.msg = "Image error: " + err.Error()
+				results <- res
+				return
+			}
+			data, res.entry = fetched, newGIFCacheEntry(fetched)
+		}
+		if decoded, err := decodeFull(data); err != nil {
+			res.msg = "Image error: " + err.Error()
+		} else {
+			res.msg = export(item, decoded)
+		}
+		results <- res
+	}()
+}
+
+// pollStills applies a finished still or sheet export.
+func pollStills(state *appState) {
+	select {
+	case res := <-state.stills:
+		applyStills(state, res)
+	default:
+	}
+}
+
+func applyStills(state *appState, res stillsResult) {
+	state.stills = nil
+	if res.entry != nil && state.cache[res.url] == nil {
+		state.cache[res.url] = res.entry
+	}
+	flashHeader(state, res.msg)
+	state.renderDirty = true
+}
+
+// decodeFull decodes every frame of a GIF at full size.
+func decodeFull(data []byte) (*gifdecode.Frames, error) {
+	opts := gifdecode.DefaultOptions()
+	opts.MaxFrames = -1 // 0 means the default cap
+	opts.Recover = true
+	return gifdecode.Decode(data, opts)
+}
+
+func saveExport(item model.Result, ext string, data []byte) (string, error) {
+	dir, err := exportDirFn()
+	if err != nil {
+		return "", err
+	}
+	return download.SaveFile(dir, download.FilenameForResult(item, ext), data)
+}
@@ -0,0 +1,142 @@
+package tui
+
+import (
+	"bufio"
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steipete/gifgrep/gifdecode"
+	"github.com/steipete/gifgrep/internal/model"
+	"github.com/steipete/gifgrep/internal/termcaps"
+	"github.com/steipete/gifgrep/internal/testutil"
+)
+
+func stillsTestState(t *testing.T) (*appState, string) {
+	t.Helper()
+	dir := t.TempDir()
+	orig := exportDirFn
+	t.Cleanup(func() { exportDirFn = orig })
+	exportDirFn = func() (string, error) { return dir, nil }
+
+	data := testutil.MakeTestGIF()
+	state := &appState{
+		mode:    modeBrowse,
+		inline:  termcaps.InlineText,
+		results: []model.Result{{ID: "1", Title: "one", PreviewURL: "https://example.test/1.gif"}},
+		cache: map[string]*gifCacheEntry{
+			"https://example.test/1.gif": {RawGIF: data, Width: 2, Height: 2},
+		},
+		currentAnim: &gifAnimation{ID: 1, Frames: []gifdecode.Frame{
+			{PNG: []byte{1}, Delay: 50 * time.Millisecond},
+			{PNG: []byte{2}, Delay: 70 * time.Millisecond},
+		}},
+	}
+	return state, dir
+}
+
+// waitStills applies the running export's result, as the run loop does.
+func waitStills(t *testing.T, state *appState) {
+	t.Helper()
+	select {
+	case res := <-state.stills:
+		applyStills(state, res)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("export did not finish")
+	}
+}
+
+func TestExportStillOfShownFrame(t *testing.T) {
+	state, dir := stillsTestState(t)
+	state.manualFrame = 1
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+
+	handleInput(state, inputEvent{kind: keyRune, ch: 's'}, out)
+	if state.headerFlash != "Saving still…" {
+		t.Fatalf("expected the export to run in the background, got %q", state.headerFlash)
+	}
+	waitStills(t, state)
+	path := filepath.Join(dir, "one-0.05s.png")
+	if state.headerFlash != "Saved still at 0.05s to "+path {
+		t.Fatalf("unexpected flash %q", state.headerFlash)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read still: %v", err)
+	}
+	opts := gifdecode.DefaultOptions()
+	opts.MaxFrames = 0
+	decoded, err := gifdecode.Decode(state.cache["https://example.test/1.gif"].RawGIF, opts)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !bytes.Equal(got, decoded.Frames[1].PNG) {
+		t.Fatalf("expected the second frame")
+	}
+}
+
+func TestExportSheet(t *testing.T) {
+	state, dir := stillsTestState(t)
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+
+	handleInput(state, inputEvent{kind: keyRune, ch: 'S'}, out)
+	handleInput(state, inputEvent{kind: keyRune, ch: 'S'}, out)
+	if state.headerFlash != "Export in progress" {
+		t.Fatalf("expected one export at a time, got %q", state.headerFlash)
+	}
+	waitStills(t, state)
+	handleInput(state, inputEvent{kind: keyRune, ch: 'S'}, out)
+	waitStills(t, state)
+	if !strings.HasSuffix(state.headerFlash, filepath.Join(dir, "one-sheet-1.png")) {
+		t.Fatalf("expected a second, numbered sheet, got %q", state.headerFlash)
+	}
+	f, err := os.Open(filepath.Join(dir, "one-sheet.png"))
+	if err != nil {
+		t.Fatalf("open sheet: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+	if _, err := png.DecodeConfig(f); err != nil {
+		t.Fatalf("expected a PNG sheet: %v", err)
+	}
+
+	state.results[0].PreviewURL = ""
+	handleInput(state, inputEvent{kind: keyRune, ch: 'S'}, out)
+	if state.headerFlash != "No preview" {
+		t.Fatalf("unexpected flash %q", state.headerFlash)
+	}
+}
+
+func TestDecodeFullKeepsEveryFrame(t *testing.T) {
+	decoded, err := decodeFull(longTestGIF(t, 90))
+	if err != nil || len(decoded.Frames) != 90 {
+		t.Fatalf("expected all 90 frames, got %v", err)
+	}
+}
+
+// longTestGIF encodes a 2×2 GIF of n frames, 20ms each; past 60, it's more
+// than the preview decode keeps.
+func longTestGIF(t *testing.T, n int) []byte {
+	t.Helper()
+	pal := color.Palette{color.Black, color.White}
+	long := &gif.GIF{}
+	for i := 0; i < n; i++ {
+		frame := image.NewPaletted(image.Rect(0, 0, 2, 2), pal)
+		frame.SetColorIndex(i%2, 0, 1)
+		long.Image = append(long.Image, frame)
+		long.Delay = append(long.Delay, 2)
+	}
+	var data bytes.Buffer
+	if err := gif.EncodeAll(&data, long); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	return data.Bytes()
+}
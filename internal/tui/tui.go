@@ -23,6 +23,12 @@ import (
 type inputEvent struct {
 	kind keyKind
 	ch   rune
+	mod  keyMod
+	// text is the pasted text of a keyPaste event.
+	text string
+	// row and col are the 1-based cell of a mouse event.
+	row int
+	col int
 }
 
 type keyKind int
@@ -36,6 +42,22 @@ const (
 	keyDown
 	keyCtrlC
 	keyUnknown
+	mouseClick
+	mouseWheelUp
+	mouseWheelDown
+	keyLeft
+	keyRight
+	keyHome
+	keyEnd
+	keyPageUp
+	keyPageDown
+	keyDelete
+	keyTab
+	keyDeleteWord
+	keyDeleteLine
+	keyPaste
+	keyKillEnd
+	keyYank
 )
 
 var ErrNotTerminal = errors.New("stdin is not a tty")
@@ -49,17 +71,17 @@ func Run(opts model.Options, query string) error {
 	return runWith(env, opts, query)
 }
 
-func errUnsupportedInline(getenv func(string) string) error {
-	if getenv == nil {
-		getenv = os.Getenv
+// previewColorDepth picks the palette for text previews; --color never
+// falls back to ASCII art.
+func previewColorDepth(opts model.Options, getenv func(string) string) termcaps.ColorDepth {
+	if opts.Color == "never" {
+		return termcaps.ColorNone
 	}
-	termProgram := strings.TrimSpace(getenv("TERM_PROGRAM"))
-	term := strings.TrimSpace(getenv("TERM"))
-	return fmt.Errorf(
-		"gifgrep tui needs inline image support.\n\nSupported terminals:\n  - Kitty (Kitty graphics protocol)\n  - Ghostty (Kitty graphics protocol)\n  - iTerm2 (OSC 1337 inline images)\n\nDetected:\n  TERM_PROGRAM=%q\n  TERM=%q\n\nSee: docs/kitty.md and docs/iterm.md\n\nTip: You can force detection with GIFGREP_INLINE=kitty|iterm|none",
-		termProgram,
-		term,
-	)
+	depth := termcaps.DetectColorDepth(getenv)
+	if depth == termcaps.ColorNone && opts.Color == "always" {
+		return termcaps.Color256
+	}
+	return depth
 }
 
 func runWith(env Env, opts model.Options, query string) error {
@@ -90,8 +112,19 @@ func runWith(env Env, opts model.Options, query string) error {
 
 	inline := termcaps.DetectInlineRobust(os.Getenv)
 	if inline == termcaps.InlineNone {
-		return errUnsupportedInline(os.Getenv)
+		// No graphics protocol: draw previews with text instead.
+		inline = termcaps.InlineText
+	}
+	kittyMode := kitty.DetectMode(os.Getenv)
+	if inline == termcaps.InlineKitty {
+		kittyMode.Medium = termcaps.DetectKittyMedium(os.Getenv, kittyMode)
 	}
+	// Probe before readInput starts, or it would swallow the replies.
+	cell := termcaps.DetectCellSize(env.FD)
+	syncOutput := termcaps.DetectSyncOutput(os.Getenv)
+	kittyKeyboard := termcaps.DetectKittyKeyboard(os.Getenv)
+	osc52 := termcaps.DetectClipboard(os.Getenv)
+	keys, keysErr := loadKeymap(os.Getenv)
 
 	oldState, err := env.MakeRaw(env.FD)
 	if err != nil {
@@ -105,10 +138,19 @@ func runWith(env Env, opts model.Options, query string) error {
 
 	out := bufio.NewWriter(env.Out)
 	hideCursor(out)
+	enableKeyboard(out, kittyKeyboard)
+	mouse := mouseEnabled(os.Getenv)
+	if mouse {
+		enableMouse(out)
+	}
 	defer func() {
+		if mouse {
+			disableMouse(out)
+		}
+		disableKeyboard(out, kittyKeyboard)
 		showCursor(out)
 		if inline == termcaps.InlineKitty {
-			clearImages(out)
+			clearImages(out, kittyMode)
 		}
 		_ = out.Flush()
 	}()
@@ -131,14 +173,24 @@ func runWith(env Env, opts model.Options, query string) error {
 		renderDirty:     true,
 		nextImageID:     1,
 		inline:          inline,
-		useSoftwareAnim: inline == termcaps.InlineKitty && useSoftwareAnimation(),
+		kitty:           kittyMode,
+		useSoftwareAnim: (inline == termcaps.InlineKitty || inline == termcaps.InlineIterm) && useSoftwareAnimation(),
 		useColor:        opts.Color != "never",
+		colorDepth:      previewColorDepth(opts, os.Getenv),
+		cell:            cell,
+		syncOutput:      syncOutput,
+		osc52:           osc52,
+		keys:            keys,
+		screen:          newScreenBuffer(),
 		opts:            opts,
 	}
 	if cols, rows, err := env.GetSize(env.FD); err == nil {
 		state.lastRows = rows
 		state.lastCols = cols
 	}
+	if keysErr != nil {
+		state.status = "Keymap error: " + keysErr.Error()
+	}
 
 	ticker := time.NewTicker(10 * time.Millisecond)
 	defer ticker.Stop()
@@ -187,13 +239,19 @@ func runWith(env Env, opts model.Options, query string) error {
 				state.lastRows = rows
 				state.lastCols = cols
 				ensureVisible(state)
+				refreshPreviewResolution(state)
 				state.renderDirty = true
 				state.previewDirty = true
 			}
 		}
 
+		pollBatch(state)
+		pollGridTiles(state)
+		pollStills(state)
 		if state.renderDirty {
+			beginSync(out, state.syncOutput)
 			render(state, out, state.lastRows, state.lastCols)
+			endSync(out, state.syncOutput)
 			state.renderDirty = false
 			_ = out.Flush()
 		}
@@ -203,7 +261,7 @@ func runWith(env Env, opts model.Options, query string) error {
 }
 
 func readInput(r io.Reader, ch chan<- inputEvent, stop <-chan struct{}) {
-	reader := bufio.NewReader(r)
+	dec := newInputDecoder(r)
 	for {
 		select {
 		case <-stop:
@@ -211,42 +269,11 @@ func readInput(r io.Reader, ch chan<- inputEvent, stop <-chan struct{}) {
 		default:
 		}
 
-		b, err := reader.ReadByte()
+		ev, err := dec.next()
 		if err != nil {
 			return
 		}
-		switch b {
-		case 0x03:
-			ch <- inputEvent{kind: keyCtrlC}
-		case '\r', '\n':
-			ch <- inputEvent{kind: keyEnter}
-		case 0x7f, 0x08:
-			ch <- inputEvent{kind: keyBackspace}
-		case 0x1b:
-			next, err := reader.ReadByte()
-			if err != nil {
-				ch <- inputEvent{kind: keyEsc}
-				continue
-			}
-			if next == '[' {
-				third, _ := reader.ReadByte()
-				switch third {
-				case 'A':
-					ch <- inputEvent{kind: keyUp}
-				case 'B':
-					ch <- inputEvent{kind: keyDown}
-				default:
-					ch <- inputEvent{kind: keyUnknown}
-				}
-			} else {
-				_ = reader.UnreadByte()
-				ch <- inputEvent{kind: keyEsc}
-			}
-		default:
-			if b >= 0x20 && b < 0x7f {
-				ch <- inputEvent{kind: keyRune, ch: rune(b)}
-			}
-		}
+		ch <- ev
 	}
 }
 
@@ -254,15 +281,21 @@ func handleInput(state *appState, ev inputEvent, out *bufio.Writer) bool {
 	if ev.kind == keyCtrlC {
 		return true
 	}
-	if ev.kind == keyRune && ev.ch == 'q' {
+	// Quit chords work while typing too; plain keys type.
+	if act, ok := activeKeymap(state).lookup(ev); ok && act == actQuit && !typesText(ev) {
 		return true
 	}
+	if state.help {
+		return handleHelpInput(state, ev, out)
+	}
 
 	switch state.mode {
 	case modeQuery:
 		return handleQueryInput(state, ev, out)
 	case modeBrowse:
 		return handleBrowseInput(state, ev, out)
+	case modeFilter:
+		return handleFilterInput(state, ev, out)
 	}
 
 	return false
@@ -270,14 +303,9 @@ func handleInput(state *appState, ev inputEvent, out *bufio.Writer) bool {
 
 func handleQueryInput(state *appState, ev inputEvent, out *bufio.Writer) bool {
 	switch ev.kind {
-	case keyRune:
-		state.query += string(ev.ch)
-		state.renderDirty = true
-	case keyBackspace:
-		if len(state.query) > 0 {
-			state.query = state.query[:len(state.query)-1]
-			state.renderDirty = true
-		}
+	case keyRune, keyPaste, keyLeft, keyRight, keyHome, keyEnd, keyBackspace, keyDelete,
+		keyDeleteWord, keyDeleteLine, keyKillEnd, keyYank:
+		editQuery(state, ev)
 	case keyEnter:
 		if strings.TrimSpace(state.query) == "" {
 			state.status = "Empty query"
@@ -292,6 +320,9 @@ func handleQueryInput(state *appState, ev inputEvent, out *bufio.Writer) bool {
 		if err != nil {
 			state.status = "Search error: " + err.Error()
 		} else {
+			resetGrid(state, out)
+			state.marked = nil
+			dropFilter(state)
 			state.results = results
 			state.selected = 0
 			state.scroll = 0
@@ -313,65 +344,69 @@ func handleQueryInput(state *appState, ev inputEvent, out *bufio.Writer) bool {
 		}
 	case keyCtrlC:
 		return true
-	case keyUp, keyDown, keyUnknown:
+	case mouseClick, mouseWheelUp, mouseWheelDown:
+		return handleMouse(state, ev, out)
+	case keyUp, keyDown, keyPageUp, keyPageDown, keyTab, keyUnknown:
 		// ignore
 	}
 	return false
 }
 
 func handleBrowseInput(state *appState, ev inputEvent, out *bufio.Writer) bool {
+	if ev.kind == keyEsc && state.allResults != nil {
+		// Esc drops a kept filter before it does anything else.
+		clearFilter(state, out)
+		return false
+	}
+	km := activeKeymap(state)
+	if act, ok := km.lookup(ev); ok {
+		return runAction(state, act, out)
+	}
 	switch ev.kind {
 	case keyRune:
-		if ev.ch == '/' {
-			state.mode = modeQuery
-			state.status = "Type a search and press Enter"
-			state.renderDirty = true
-			return false
-		}
-		switch ev.ch {
-		case 'd':
-			downloadSelected(state, out, state.opts.Reveal)
-			return false
-		case 'f':
-			return handleRevealSelected(state, out)
-		default:
-		}
-		if ev.ch >= 0x20 {
+		if km.typeToSearch && typesText(ev) {
 			state.mode = modeQuery
 			state.status = "Type a search and press Enter"
-			state.query = string(ev.ch)
-			state.renderDirty = true
-			return false
-		}
-	case keyUp:
-		if state.selected > 0 {
-			state.selected--
-			ensureVisible(state)
-			loadSelectedImage(state)
-			state.renderDirty = true
+			setQuery(state, string(ev.ch), len(string(ev.ch)))
 		}
-	case keyDown:
-		if state.selected < len(state.results)-1 {
-			state.selected++
-			ensureVisible(state)
-			loadSelectedImage(state)
-			state.renderDirty = true
-		}
-	case keyEnter:
+	case keyPaste:
 		state.mode = modeQuery
 		state.status = "Type a search and press Enter"
-		state.renderDirty = true
-	case keyEsc:
-		state.mode = modeQuery
-		state.renderDirty = true
+		text := pasteText(ev.text)
+		setQuery(state, text, len(text))
 	case keyCtrlC:
 		return true
-	case keyBackspace, keyUnknown:
-		// ignore
+	case mouseClick, mouseWheelUp, mouseWheelDown:
+		return handleMouse(state, ev, out)
+	case keyEnter, keyBackspace, keyEsc, keyUp, keyDown, keyUnknown, keyLeft, keyRight, keyHome, keyEnd,
+		keyPageUp, keyPageDown, keyDelete, keyTab, keyDeleteWord, keyDeleteLine, keyKillEnd, keyYank:
+		// unbound
 	}
 	return false
 }
 
+// typesText reports whether ev is a printable key without Ctrl or Alt.
+func typesText(ev inputEvent) bool {
+	return ev.kind == keyRune && ev.ch >= 0x20 && ev.mod&(modAlt|modCtrl) == 0
+}
+
+// moveSelection moves the selection by delta results, clamped to the list.
+func moveSelection(state *appState, delta int) {
+	idx := minInt(len(state.results)-1, maxInt(0, state.selected+delta))
+	if idx < 0 || idx == state.selected {
+		return
+	}
+	state.selected = idx
+	ensureVisible(state)
+	loadSelectedImage(state)
+	state.renderDirty = true
+}
+
+// listPage is how far PgUp/PgDn move: one screen of results.
+func listPage(state *appState) int {
+	return maxInt(1, state.lastRows-4)
+}
+
 func ensureVisible(state *appState) {
 	listHeight := state.lastRows - 4
 	if listHeight < 0 {
@@ -394,8 +429,15 @@ func render(state *appState, out *bufio.Writer, rows, cols int) {
 	}
 
 	layout := buildLayout(state, rows, cols)
+	defer state.screen.swap()
+	if state.previewDirty || layout.showRight != state.lastShowRight {
+		// The preview moved or changed; the lines it covered must be
+		// redrawn rather than trusted.
+		state.screen.invalidate()
+	}
 
 	if state.inline == termcaps.InlineIterm && layout.showRight && shouldSendItermPreview(state, layout) {
+		state.screen.invalidate()
 		if shouldHardClearIterm(state, layout) {
 			clearItermScreenFn(out)
 			state.itermLast = struct {
@@ -411,7 +453,7 @@ func render(state *appState, out *bufio.Writer, rows, cols int) {
 
 	if state.currentAnim == nil && state.activeImageID != 0 {
 		if state.inline == termcaps.InlineKitty {
-			kitty.DeleteImage(out, state.activeImageID)
+			state.kitty.DeleteImage(out, state.activeImageID)
 		}
 		state.activeImageID = 0
 	}
@@ -424,10 +466,30 @@ func render(state *appState, out *bufio.Writer, rows, cols int) {
 	if strings.TrimSpace(state.headerFlash) != "" {
 		headerTagline = state.headerFlash
 	}
-	drawHeader(out, state.useColor, cols, headerTagline)
+	drawHeader(out, state.screen, state.useColor, cols, headerTagline)
 
 	if !layout.hasContent {
-		clearAll(out, rows, cols)
+		clearAll(out, state.screen, rows, cols)
+		return
+	}
+
+	if state.help {
+		state.lastShowRight = false
+		drawHelp(out, state, layout)
+		drawStatus(out, state, layout)
+		drawSearch(out, state, layout)
+		drawHints(out, state, layout)
+		clearUnused(out, state.screen, layout)
+		return
+	}
+
+	if state.grid {
+		state.lastShowRight = false
+		drawGrid(out, state, layout)
+		drawStatus(out, state, layout)
+		drawSearch(out, state, layout)
+		drawHints(out, state, layout)
+		clearUnused(out, state.screen, layout)
 		return
 	}
 
@@ -436,13 +498,17 @@ func render(state *appState, out *bufio.Writer, rows, cols int) {
 		// so old list rows don't show through. For Kitty, it's cheap to clear every render;
 		// for iTerm images (inline in the text grid), clearing would erase the image.
 		if state.inline == termcaps.InlineKitty || !state.lastShowRight {
-			clearPreviewAreaFn(out, layout)
+			clearPreviewAreaFn(out, state.screen, layout)
 		}
 	}
 
 	state.lastShowRight = layout.showRight
 
-	drawList(out, state, layout)
+	if state.detail {
+		drawDetail(out, state, layout)
+	} else {
+		drawList(out, state, layout)
+	}
 	if state.inline == termcaps.InlineIterm && layout.showRight {
 		clearItermGapColumn(out, layout)
 	}
@@ -450,7 +516,7 @@ func render(state *appState, out *bufio.Writer, rows, cols int) {
 	drawStatus(out, state, layout)
 	drawSearch(out, state, layout)
 	drawHints(out, state, layout)
-	clearUnused(out, layout)
+	clearUnused(out, state.screen, layout)
 }
 
 type layout struct {
@@ -468,6 +534,11 @@ type layout struct {
 }
 
 func buildLayout(state *appState, rows, cols int) layout {
+	return buildLayoutFor(state.currentAnim, rows, cols, cellAspectRatio(state.cell))
+}
+
+// buildLayoutFor lays out the screen as if anim were the current preview.
+func buildLayoutFor(anim *gifAnimation, rows, cols int, aspect float64) layout {
 	layout := layout{rows: rows, cols: cols}
 	layout.searchRow = rows - 2
 	layout.statusRow = rows - 1
@@ -484,7 +555,7 @@ func buildLayout(state *appState, rows, cols int) layout {
 	layout.contentHeight = layout.contentBottom - layout.contentTop + 1
 	layout.hasContent = true
 
-	showRight := cols >= 80 && rows >= 14 && state.currentAnim != nil
+	showRight := cols >= 80 && rows >= 14 && anim != nil
 	minListWidth := 28
 	gapCols := 1
 	maxPreviewCols := cols
@@ -497,7 +568,7 @@ func buildLayout(state *appState, rows, cols int) layout {
 	layout.showRight = showRight
 
 	if showRight {
-		layout.previewCols, layout.previewRows = fitPreviewSize(maxPreviewCols, layout.contentHeight, state.currentAnim)
+		layout.previewCols, layout.previewRows = fitPreviewSize(maxPreviewCols, layout.contentHeight, anim, aspect)
 	} else {
 		availRows := layout.contentHeight / 2
 		if availRows < 6 {
@@ -506,9 +577,9 @@ func buildLayout(state *appState, rows, cols int) layout {
 		if availRows > layout.contentHeight-2 {
 			availRows = maxInt(0, layout.contentHeight-2)
 		}
-		layout.previewCols, layout.previewRows = fitPreviewSize(cols, availRows, state.currentAnim)
+		layout.previewCols, layout.previewRows = fitPreviewSize(cols, availRows, anim, aspect)
 	}
-	if state.currentAnim == nil {
+	if anim == nil {
 		layout.previewCols = 0
 		layout.previewRows = 0
 	}
@@ -534,7 +605,7 @@ func buildLayout(state *appState, rows, cols int) layout {
 	return layout
 }
 
-func drawHeader(out *bufio.Writer, useColor bool, cols int, tagline string) {
+func drawHeader(out *bufio.Writer, screen *screenBuffer, useColor bool, cols int, tagline string) {
 	header := styleIf(useColor, "gifgrep", "\x1b[1m", "\x1b[36m")
 	if strings.TrimSpace(tagline) == "" {
 		tagline = model.Tagline
@@ -545,18 +616,27 @@ func drawHeader(out *bufio.Writer, useColor bool, cols int, tagline string) {
 		codes = []string{"\x1b[33m"}
 	}
 	header += styleIf(useColor, " — "+tagline, codes...)
-	writeLineAt(out, 1, 1, header, cols)
+	screen.writeLineAt(out, 1, 1, header, cols)
 }
 
-func clearAll(out *bufio.Writer, rows, cols int) {
+func clearAll(out *bufio.Writer, screen *screenBuffer, rows, cols int) {
 	for row := 1; row <= rows; row++ {
-		writeLineAt(out, row, 1, "", cols)
+		screen.writeLineAt(out, row, 1, "", cols)
 	}
 }
 
-func clearPreviewArea(out *bufio.Writer, layout layout) {
+// clearPreviewArea blanks the content rows. The clear erases whole rows,
+// list included, so when it writes anything the lines drawn after it can't
+// be skipped as unchanged.
+func clearPreviewArea(out *bufio.Writer, screen *screenBuffer, layout layout) {
+	cleared := false
 	for i := 0; i < layout.contentHeight; i++ {
-		writeLineAt(out, layout.contentTop+i, 1, "", layout.clearWidth)
+		if screen.writeLineAt(out, layout.contentTop+i, 1, "", layout.clearWidth) {
+			cleared = true
+		}
+	}
+	if cleared {
+		screen.invalidate()
 	}
 }
 
@@ -567,18 +647,22 @@ func drawList(out *bufio.Writer, state *appState, layout layout) {
 		idx := state.scroll + i
 		if idx >= 0 && idx < len(state.results) {
 			item := state.results[idx]
-			label := item.Title
-			if label == "" {
-				label = item.ID
-			}
+			// The label the filter matched, so highlights land on it.
+			label := resultLabel(item)
 			prefix := "  "
+			var base []string
 			if idx == state.selected {
 				prefix = styleIf(state.useColor, "> ", "\x1b[1m", "\x1b[36m")
-				label = styleIf(state.useColor, label, "\x1b[1m")
+				base = []string{"\x1b[1m"}
 			}
-			writeLineAt(out, layout.contentTop+i, layout.listCol, prefix+label, layout.listWidth)
+			if state.useColor && state.filter != "" {
+				label = highlightRunes(label, filterPositions(label, state.filter), base...)
+			}
+			label = styleIf(state.useColor, label, base...)
+			label = markPrefix(state, item) + label
+			state.screen.writeLineAt(out, layout.contentTop+i, layout.listCol, prefix+label, layout.listWidth)
 		} else {
-			writeLineAt(out, layout.contentTop+i, layout.listCol, "", layout.listWidth)
+			state.screen.writeLineAt(out, layout.contentTop+i, layout.listCol, "", layout.listWidth)
 		}
 	}
 }
@@ -596,41 +680,59 @@ func drawPreviewIfNeeded(out *bufio.Writer, state *appState, layout layout) {
 	}
 
 	label := styleIf(state.useColor, "Preview", "\x1b[90m")
-	writeLineAt(out, layout.contentTop+layout.listHeight, 1, label, layout.cols)
+	state.screen.writeLineAt(out, layout.contentTop+layout.listHeight, 1, label, layout.cols)
 	state.previewRow = layout.previewRow
 	state.previewCol = layout.previewCol
 	for i := 0; i < layout.previewRows; i++ {
-		writeLineAt(out, state.previewRow+i, 1, "", layout.cols)
+		state.screen.writeLineAt(out, state.previewRow+i, 1, "", layout.cols)
 	}
 	moveCursor(out, state.previewRow, state.previewCol)
 	drawPreview(state, out, layout.previewCols, layout.previewRows, state.previewRow, state.previewCol)
 }
 
-func drawStatus(out *bufio.Writer, state *appState, layout layout) {
+// statusCols is the width of the status text, which leaves room for the
+// GIPHY logo.
+func statusCols(state *appState, layout layout) int {
+	if search.ResolveSource(state.opts.Source) == "giphy" && state.inline == termcaps.InlineKitty {
+		return maxInt(0, layout.cols-3)
+	}
+	return layout.cols
+}
+
+// drawStatusLine draws the status text, with the playback timeline
+// right-aligned when there is one.
+func drawStatusLine(out *bufio.Writer, state *appState, layout layout) {
 	status := state.status
 	if status == "" {
 		status = fmt.Sprintf("%d results", len(state.results))
 	}
-	source := search.ResolveSource(state.opts.Source)
-	showGiphyAttribution := source == "giphy"
-	showGiphyIcon := showGiphyAttribution && state.inline == termcaps.InlineKitty
-	logoCols := 2
-	logoRows := 1
-	statusWidth := layout.cols
-	if showGiphyIcon {
-		statusWidth = maxInt(0, layout.cols-(logoCols+1))
-	}
 	line := formatStatusLine(state.useColor, status)
-	if showGiphyAttribution {
+	if n := len(markedResults(state)); n > 0 {
+		line += styleIf(state.useColor, fmt.Sprintf(" · %d marked", n), "\x1b[32m")
+	}
+	if search.ResolveSource(state.opts.Source) == "giphy" {
 		line += styleIf(state.useColor, " · Powered by GIPHY", "\x1b[90m")
 	}
-	writeLineAt(out, layout.statusRow, 1, line, statusWidth)
+	width := statusCols(state, layout)
+	if tl, _ := timeline(state); tl != "" && visibleRuneLen(tl) < width {
+		left := width - visibleRuneLen(tl) - 1
+		line = truncateANSI(line, left)
+		line += strings.Repeat(" ", left-visibleRuneLen(line)+1) + tl
+	}
+	state.screen.writeLineAt(out, layout.statusRow, 1, line, width)
+}
+
+func drawStatus(out *bufio.Writer, state *appState, layout layout) {
+	drawStatusLine(out, state, layout)
+	logoCols := 2
+	logoRows := 1
+	showGiphyIcon := search.ResolveSource(state.opts.Source) == "giphy" && state.inline == termcaps.InlineKitty
 	if showGiphyIcon && layout.cols >= logoCols {
 		moveCursor(out, layout.statusRow, maxInt(1, layout.cols-logoCols+1))
-		kitty.SendFrame(out, giphyAttributionImageID, gifdecode.Frame{PNG: assets.GiphyIcon32PNG()}, logoCols, logoRows)
+		state.kitty.SendFrame(out, giphyAttributionImageID, gifdecode.Frame{PNG: assets.GiphyIcon32PNG()}, logoCols, logoRows)
 		state.giphyAttributionShown = true
 	} else if state.giphyAttributionShown && state.inline == termcaps.InlineKitty {
-		kitty.DeleteImage(out, giphyAttributionImageID)
+		state.kitty.DeleteImage(out, giphyAttributionImageID)
 		state.giphyAttributionShown = false
 	}
 }
@@ -652,19 +754,86 @@ func formatStatusLine(useColor bool, status string) string {
 }
 
 func drawSearch(out *bufio.Writer, state *appState, layout layout) {
-	pill := "[Search]"
-	query := state.query
+	name := "Search"
+	if state.mode == modeFilter {
+		name = "Filter"
+	}
+	pill := "[" + name + "]"
 	if state.useColor {
 		bg := "\x1b[48;5;236m"
-		if state.mode == modeQuery {
-			pill = styleIf(true, " Search ", bg, "\x1b[1m", "\x1b[33m")
-			query += styleIf(true, "▍", "\x1b[36m")
+		if state.mode == modeBrowse {
+			pill = styleIf(true, " "+name+" ", bg, "\x1b[90m")
 		} else {
-			pill = styleIf(true, " Search ", bg, "\x1b[90m")
+			pill = styleIf(true, " "+name+" ", bg, "\x1b[1m", "\x1b[33m")
+		}
+	}
+	query := state.query
+	switch state.mode {
+	case modeQuery:
+		query = queryWithCursor(state, layout.cols-visibleRuneLen(pill)-1)
+	case modeFilter:
+		pos := len(state.filter) - minInt(len(state.filter), maxInt(0, state.filterTail))
+		query = textWithCursor(state.filter, pos, state.useColor, layout.cols-visibleRuneLen(pill)-1)
+	case modeBrowse:
+		if state.allResults != nil {
+			query += styleIf(state.useColor, " · filter: "+state.filter, "\x1b[90m")
 		}
 	}
 	searchLine := pill + " " + query
-	writeLineAt(out, layout.searchRow, 1, searchLine, layout.cols)
+	state.screen.writeLineAt(out, layout.searchRow, 1, searchLine, layout.cols)
+}
+
+type hint struct {
+	key   string
+	label string
+	act   action // "" for the fixed hints
+}
+
+// hintActions are the actions the hint bar shows keys for, after ⏎ Search.
+var hintActions = []struct {
+	acts  []action
+	label string
+}{
+	{[]action{actSearch}, "Edit"},
+	{[]action{actUp, actDown}, "Select"},
+	{[]action{actDownload}, "Download"},
+	{[]action{actReveal}, "Reveal"},
+	{[]action{actHelp}, "Help"},
+	{[]action{actQuit}, "Quit"},
+}
+
+// hints returns the hint bar for km; unbound actions are left out.
+func (km *keymap) hints() []hint {
+	hs := []hint{{key: "⏎", label: "Search"}}
+	for _, ha := range hintActions {
+		key := ""
+		for _, act := range ha.acts {
+			key += km.keyLabel(act)
+		}
+		if key == "" {
+			continue
+		}
+		h := hint{key: key, label: ha.label}
+		if len(ha.acts) == 1 {
+			h.act = ha.acts[0]
+		}
+		hs = append(hs, h)
+	}
+	return hs
+}
+
+const hintGap = "  "
+
+// hintsPad is the indent that centers the hint bar.
+func hintsPad(hs []hint, cols int) int {
+	width := 0
+	for i, h := range hs {
+		if i > 0 {
+			width += runeLen(hintGap)
+		}
+		width += runeLen(h.key) + 1 + runeLen(h.label)
+	}
+	return maxInt(0, (cols-width)/2)
 }
 
 func drawHints(out *bufio.Writer, state *appState, layout layout) {
@@ -674,27 +843,23 @@ func drawHints(out *bufio.Writer, state *appState, layout layout) {
 		}
 		return styleIf(true, key, "\x1b[1m", "\x1b[36m") + " " + styleIf(true, label, "\x1b[90m")
 	}
-	hints := strings.Join([]string{
-		formatHint("⏎", "Search"),
-		formatHint("/", "Edit"),
-		formatHint("↑↓", "Select"),
-		formatHint("d", "Download"),
-		formatHint("f", "Reveal"),
-		formatHint("q", "Quit"),
-	}, "  ")
+	hs := activeKeymap(state).hints()
+	parts := make([]string, 0, len(hs))
+	for _, h := range hs {
+		parts = append(parts, formatHint(h.key, h.label))
+	}
 	// Hints live below the content area; center across the full terminal width,
 	// even when the content is split (preview left / list right).
-	pad := maxInt(0, (layout.cols-visibleRuneLen(hints))/2)
-	line := strings.Repeat(" ", pad) + hints
-	writeLineAt(out, layout.hintsRow, 1, line, layout.cols)
+	line := strings.Repeat(" ", hintsPad(hs, layout.cols)) + strings.Join(parts, hintGap)
+	state.screen.writeLineAt(out, layout.hintsRow, 1, line, layout.cols)
 }
 
-func clearUnused(out *bufio.Writer, layout layout) {
+func clearUnused(out *bufio.Writer, screen *screenBuffer, layout layout) {
 	for row := 1; row <= layout.rows; row++ {
 		if row == 1 || (row >= layout.contentTop && row <= layout.contentBottom) || row == layout.statusRow || row == layout.searchRow || row == layout.hintsRow {
 			continue
 		}
-		writeLineAt(out, row, 1, "", layout.cols)
+		screen.writeLineAt(out, row, 1, "", layout.cols)
 	}
 }
 
@@ -725,14 +890,15 @@ func availablePreviewSize(rows, cols, leftWidth int, showRight bool) (int, int)
 	return availCols, availRows
 }
 
-func fitPreviewSize(availCols, availRows int, anim *gifAnimation) (int, int) {
+// fitPreviewSize fits anim into the available cells; aspect is the cell's
+// width/height in pixels.
+func fitPreviewSize(availCols, availRows int, anim *gifAnimation, aspect float64) (int, int) {
 	if availCols <= 0 || availRows <= 0 {
 		return 0, 0
 	}
 	if anim == nil || anim.Width <= 0 || anim.Height <= 0 {
 		return availCols, availRows
 	}
-	aspect := cellAspectRatio()
 	targetCols := availCols
 	targetRows := int(math.Round(float64(targetCols) * aspect * float64(anim.Height) / float64(anim.Width)))
 	if targetRows > availRows {
@@ -752,7 +918,7 @@ func drawPreview(state *appState, out *bufio.Writer, cols, rows int, row, col in
 	if state.currentAnim == nil {
 		return
 	}
-	if state.inline == termcaps.InlineIterm {
+	if state.inline == termcaps.InlineIterm && !state.useSoftwareAnim {
 		if len(state.currentAnim.RawGIF) == 0 {
 			return
 		}
@@ -799,24 +965,24 @@ func drawPreview(state *appState, out *bufio.Writer, cols, rows int, row, col in
 	if len(state.currentAnim.Frames) == 0 {
 		return
 	}
-	if state.useSoftwareAnim && len(state.currentAnim.Frames) > 1 {
+	if inTextGrid(state.inline) || (state.useSoftwareAnim && len(state.currentAnim.Frames) > 1) {
 		drawPreviewSoftware(state, out, cols, rows, row, col)
 		return
 	}
 	if state.previewNeedsSend {
 		if state.activeImageID != 0 {
-			kitty.DeleteImage(out, state.activeImageID)
+			state.kitty.DeleteImage(out, state.activeImageID)
 		}
 		state.activeImageID = state.currentAnim.ID
-		kitty.SendAnimation(out, state.currentAnim.ID, state.currentAnim.Frames, cols, rows)
+		state.kitty.SendAnimation(out, state.currentAnim.ID, state.currentAnim.Frames, cols, rows)
 		state.previewNeedsSend = false
 		state.previewDirty = false
 		state.lastPreview.cols = cols
 		state.lastPreview.rows = rows
 		return
 	}
-	if state.previewDirty || state.lastPreview.cols != cols || state.lastPreview.rows != rows {
-		kitty.PlaceImage(out, state.activeImageID, cols, rows)
+	if state.previewDirty || state.lastPreview.cols != cols || state.lastPreview.rows != rows || state.kitty.Unicode {
+		state.kitty.PlaceImage(out, state.activeImageID, cols, rows)
 		state.previewDirty = false
 		state.lastPreview.cols = cols
 		state.lastPreview.rows = rows
@@ -838,39 +1004,98 @@ func drawPreviewSoftware(state *appState, out *bufio.Writer, cols, rows int, row
 	if state.currentAnim == nil || len(state.currentAnim.Frames) == 0 {
 		return
 	}
-	if state.activeImageID != 0 && state.activeImageID != state.currentAnim.ID {
-		kitty.DeleteImage(out, state.activeImageID)
+	if state.inline == termcaps.InlineKitty && state.activeImageID != 0 && state.activeImageID != state.currentAnim.ID {
+		state.kitty.DeleteImage(out, state.activeImageID)
 	}
 	state.activeImageID = state.currentAnim.ID
 	if state.previewNeedsSend {
+		// A new animation starts at frame 0; a resend (resize, switch from
+		// native playback) keeps the position.
 		state.manualAnim = true
-		state.manualFrame = 0
-		frame := state.currentAnim.Frames[state.manualFrame]
-		saveCursor(out)
-		moveCursor(out, row, col)
-		kitty.SendFrame(out, state.activeImageID, frame, cols, rows)
-		restoreCursor(out)
-		state.manualNext = time.Now().Add(frame.Delay)
+		state.manualFrame = minInt(state.manualFrame, len(state.currentAnim.Frames)-1)
+		sendPreviewFrame(state, out, row, col, cols, rows, true)
+		state.manualNext = time.Now().Add(playbackDelay(state, state.manualFrame))
 		state.previewNeedsSend = false
 		state.previewDirty = false
 		state.lastPreview.cols = cols
 		state.lastPreview.rows = rows
 		return
 	}
-	if state.previewDirty || state.lastPreview.cols != cols || state.lastPreview.rows != rows {
-		frame := state.currentAnim.Frames[state.manualFrame]
-		saveCursor(out)
-		moveCursor(out, row, col)
-		kitty.SendFrame(out, state.activeImageID, frame, cols, rows)
-		restoreCursor(out)
+	// Sixel, text and placeholder previews live in the text grid, so any
+	// redraw around them may have erased part of the picture; always repaint.
+	if state.previewDirty || state.lastPreview.cols != cols || state.lastPreview.rows != rows || inTextGrid(state.inline) || state.kitty.Unicode {
+		sendPreviewFrame(state, out, row, col, cols, rows, state.lastPreview.cols != cols || state.lastPreview.rows != rows)
 		state.previewDirty = false
 		state.lastPreview.cols = cols
 		state.lastPreview.rows = rows
 	}
 }
 
+// sendPreviewFrame draws the current animation frame into the preview box
+// with the active protocol, leaving the cursor where it was. fresh marks a
+// new image or box, whose area has to be blanked for text-grid protocols.
+func sendPreviewFrame(state *appState, out *bufio.Writer, row, col, cols, rows int, fresh bool) {
+	frame := state.currentAnim.Frames[state.manualFrame]
+	switch state.inline {
+	case termcaps.InlineSixel:
+		img := sixelFrame(state.currentAnim, state.manualFrame)
+		if img == nil {
+			return
+		}
+		// Undrawn sixel pixels keep what was on screen, so transparent frames
+		// would pile up on top of each other without a clear.
+		if fresh || img.Transparent {
+			clearItermRectFn(out, row, col, cols, rows)
+		}
+		saveCursor(out)
+		moveCursor(out, row, col)
+		_, _ = out.Write(img.Data)
+		restoreCursor(out)
+	case termcaps.InlineKitty:
+		saveCursor(out)
+		moveCursor(out, row, col)
+		state.kitty.SendFrame(out, state.activeImageID, frame, cols, rows)
+		restoreCursor(out)
+	case termcaps.InlineText:
+		// Lines are padded to the box width, so frames overwrite each other
+		// without a clear; no \x1b[K, which would wipe the list beside.
+		lines := textFrame(state.currentAnim, state.manualFrame, cols, rows, state.colorDepth)
+		if fresh {
+			clearItermRectFn(out, row, col, cols, rows)
+		}
+		saveCursor(out)
+		for i, line := range lines {
+			moveCursor(out, row+i, col)
+			_, _ = out.WriteString(line)
+		}
+		restoreCursor(out)
+	case termcaps.InlineIterm:
+		// Each image replaces the cells it covers, so frames overwrite each
+		// other; only a new box needs the old one (and stale text) cleared.
+		if fresh {
+			last := state.itermLast
+			if last.cols > 0 && last.rows > 0 && (last.row != row || last.col != col || last.cols != cols || last.rows != rows) {
+				clearItermRectFn(out, last.row, last.col, last.cols, last.rows)
+			}
+			clearItermRectFn(out, row, col, cols, rows)
+			state.itermLast.row, state.itermLast.col = row, col
+			state.itermLast.cols, state.itermLast.rows = cols, rows
+		}
+		saveCursor(out)
+		moveCursor(out, row, col)
+		iterm.SendInlineFile(out, iterm.File{
+			Name:        "gifgrep.png",
+			Data:        frame.PNG,
+			WidthCells:  cols,
+			HeightCells: rows,
+		})
+		restoreCursor(out)
+	case termcaps.InlineNone:
+	}
+}
+
 func advanceManualAnimation(state *appState, out *bufio.Writer) {
-	if !state.manualAnim || state.currentAnim == nil {
+	if !state.manualAnim || state.paused || state.currentAnim == nil {
 		return
 	}
 	if len(state.currentAnim.Frames) <= 1 {
@@ -887,12 +1112,11 @@ func advanceManualAnimation(state *appState, out *bufio.Writer) {
 		return
 	}
 	state.manualFrame = (state.manualFrame + 1) % len(state.currentAnim.Frames)
-	frame := state.currentAnim.Frames[state.manualFrame]
-	saveCursor(out)
-	moveCursor(out, state.previewRow, state.previewCol)
-	kitty.SendFrame(out, state.activeImageID, frame, state.lastPreview.cols, state.lastPreview.rows)
-	restoreCursor(out)
-	state.manualNext = now.Add(frame.Delay)
+	beginSync(out, state.syncOutput)
+	sendPreviewFrame(state, out, state.previewRow, state.previewCol, state.lastPreview.cols, state.lastPreview.rows, false)
+	redrawTimeline(state, out)
+	endSync(out, state.syncOutput)
+	state.manualNext = now.Add(playbackDelay(state, state.manualFrame))
 	_ = out.Flush()
 }
 
@@ -932,8 +1156,8 @@ func showCursor(out *bufio.Writer) {
 	_, _ = fmt.Fprint(out, "\x1b[?25h")
 }
 
-func clearImages(out *bufio.Writer) {
-	_, _ = fmt.Fprint(out, "\x1b_Ga=d\x1b\\")
+func clearImages(out *bufio.Writer, mode kitty.Mode) {
+	mode.DeleteAll(out)
 }
 
 func clearItermRect(out *bufio.Writer, row, col, cols, rows int) {
@@ -23,7 +23,7 @@ func TestRunTUIWithDefaultsNotTerminal(t *testing.T) {
 func TestRunTUIWithNoRestore(t *testing.T) {
 	t.Setenv("GIFGREP_INLINE", "kitty")
 	env := Env{
-		In:         bytes.NewReader([]byte("q")),
+		In:         bytes.NewReader([]byte("\x03")), // ctrl+c
 		Out:        io.Discard,
 		FD:         1,
 		IsTerminal: func(int) bool { return true },
@@ -39,7 +39,7 @@ func TestRunTUIWithNoRestore(t *testing.T) {
 func TestRunTUIWithSearchError(t *testing.T) {
 	t.Setenv("GIFGREP_INLINE", "kitty")
 	env := Env{
-		In:         bytes.NewReader([]byte("q")),
+		In:         bytes.NewReader([]byte("\x03")), // ctrl+c
 		Out:        io.Discard,
 		FD:         1,
 		IsTerminal: func(int) bool { return true },
@@ -56,7 +56,7 @@ func TestRunTUIWithSearchError(t *testing.T) {
 func TestRunTUIWithSizeError(t *testing.T) {
 	t.Setenv("GIFGREP_INLINE", "kitty")
 	env := Env{
-		In:         bytes.NewReader([]byte("q")),
+		In:         bytes.NewReader([]byte("\x03")), // ctrl+c
 		Out:        io.Discard,
 		FD:         1,
 		IsTerminal: func(int) bool { return true },
@@ -87,7 +87,7 @@ func TestRunTUIWithEmptyResultsAndSignal(t *testing.T) {
 		sigs := make(chan os.Signal, 1)
 		sigs <- os.Interrupt
 		env := Env{
-			In:         bytes.NewReader([]byte("q")),
+			In:         bytes.NewReader([]byte("\x03")), // ctrl+c
 			Out:        io.Discard,
 			FD:         1,
 			IsTerminal: func(int) bool { return true },
@@ -28,10 +28,15 @@ func TestPreviewSize(t *testing.T) {
 	}
 
 	anim := &gifAnimation{Width: 200, Height: 100}
-	fc, fr := fitPreviewSize(78, 36, anim)
+	fc, fr := fitPreviewSize(78, 36, anim, 0.5)
 	if fc != 78 || fr != 20 {
 		t.Fatalf("unexpected fit size: %d %d", fc, fr)
 	}
+	// Narrow, tall cells (9x24 px) need fewer rows for the same picture.
+	fc, fr = fitPreviewSize(78, 36, anim, termcaps.CellSize{Width: 9, Height: 24}.Aspect())
+	if fc != 78 || fr != 15 {
+		t.Fatalf("unexpected fit size for measured cells: %d %d", fc, fr)
+	}
 }
 
 func TestEnsureVisible(t *testing.T) {
@@ -116,9 +121,14 @@ func TestHandleInputAndLoad(t *testing.T) {
 		}
 
 		state.mode = modeQuery
-		if !handleInput(state, inputEvent{kind: keyRune, ch: 'q'}, out) {
-			t.Fatalf("expected quit on q")
+		if handleInput(state, inputEvent{kind: keyRune, ch: 'q'}, out) || state.query != "q" {
+			t.Fatalf("expected q typed into the query, got %q", state.query)
+		}
+		state.keys, _ = presetKeymap("chords")
+		if !handleInput(state, inputEvent{kind: keyRune, ch: 'q', mod: modCtrl}, out) {
+			t.Fatalf("expected quit on ctrl+q while typing")
 		}
+		state.keys = nil
 
 		state.mode = modeQuery
 		state.results = []model.Result{{Title: "A"}}
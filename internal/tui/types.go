@@ -4,7 +4,9 @@ import (
 	"time"
 
 	"github.com/steipete/gifgrep/gifdecode"
+	"github.com/steipete/gifgrep/internal/kitty"
 	"github.com/steipete/gifgrep/internal/model"
+	"github.com/steipete/gifgrep/internal/sixel"
 	"github.com/steipete/gifgrep/internal/termcaps"
 )
 
@@ -13,6 +15,7 @@ type mode int
 const (
 	modeBrowse mode = iota
 	modeQuery
+	modeFilter
 )
 
 type gifAnimation struct {
@@ -21,6 +24,12 @@ type gifAnimation struct {
 	Frames []gifdecode.Frame
 	Width  int
 	Height int
+	// sixels caches encoded frames for the sixel protocol, filled on demand.
+	sixels []*sixel.Image
+	// text caches half-block renderings for the text protocol, keyed by the
+	// cell box they were drawn for.
+	text      [][]string
+	textCells gifdecode.CellBox
 }
 
 type gifCacheEntry struct {
@@ -28,20 +37,33 @@ type gifCacheEntry struct {
 	Frames *gifdecode.Frames
 	Width  int
 	Height int
+	// Cells is the preview box Frames were scaled for; zero means full size.
+	Cells gifdecode.CellBox
+	// Stats counts every frame of RawGIF, unlike the capped and merged
+	// Frames; StatsErr is why that failed. Both stay unset until the detail
+	// pane asks (entryStats).
+	Stats    *gifdecode.Stats
+	StatsErr error
 }
 
 type appState struct {
 	query         string
+	queryTail     int    // bytes of query after the cursor
+	killed        string // last text killed in the search line, for Ctrl-Y
 	tagline       string
 	headerFlash   string
 	headerFlashAt time.Time
 	results       []model.Result
+	allResults    []model.Result // the full list while a filter is applied
+	filter        string
+	filterTail    int // bytes of filter after the cursor
 	selected      int
 	scroll        int
 	mode          mode
 	status        string
 	currentAnim   *gifAnimation
 	inline        termcaps.InlineProtocol
+	kitty         kitty.Mode
 	cache         map[string]*gifCacheEntry
 	savedPaths    map[string]string
 	renderDirty   bool
@@ -67,9 +89,30 @@ type appState struct {
 	manualAnim            bool
 	manualFrame           int
 	manualNext            time.Time
+	paused                bool
+	speedStep             int // playback speed is 2^speedStep
 	useSoftwareAnim       bool
 	useColor              bool
+	colorDepth            termcaps.ColorDepth
+	cell                  termcaps.CellSize
+	syncOutput            bool
+	osc52                 bool
+	keys                  *keymap // nil: the default keymap
+	screen                *screenBuffer
+	grid                  bool
+	gridTiles             map[int]*gridTile // by result index
+	gridResults           chan gridTileResult
+	gridSlots             chan struct{} // bounds the tile fetches
+	gridScroll            int           // first visible tile row
+	gridDirty             bool
+	lastGrid              gridLayout
 	opts                  model.Options
 	giphyAttributionShown bool
 	lastSavedPath         string
+	marked                map[string]bool // by resultKey
+	help                  bool            // the key help covers the content area
+	helpScroll            int             // first visible help row
+	detail                bool            // the list area shows the selected result
+	batch                 *batchJob
+	stills                chan stillsResult // the running still or sheet export
 }
@@ -7,6 +7,8 @@ import (
 	"unicode/utf8"
 
 	"github.com/mattn/go-runewidth"
+
+	"github.com/steipete/gifgrep/internal/termcaps"
 )
 
 func truncateRunes(s string, width int) string {
@@ -109,13 +111,15 @@ func minInt(a, b int) int {
 	return b
 }
 
-func cellAspectRatio() float64 {
+// cellAspectRatio returns the cell's width/height: GIFGREP_CELL_ASPECT if
+// set, else the measured cell, else 0.5.
+func cellAspectRatio(cell termcaps.CellSize) float64 {
 	if raw := strings.TrimSpace(os.Getenv("GIFGREP_CELL_ASPECT")); raw != "" {
 		if v, err := strconv.ParseFloat(raw, 64); err == nil && v > 0.1 && v < 2 {
 			return v
 		}
 	}
-	return 0.5
+	return cell.Aspect()
 }
 
 func useSoftwareAnimation() bool {
@@ -128,7 +132,9 @@ func useSoftwareAnimation() bool {
 	if strings.Contains(termProgram, "ghostty") || strings.Contains(term, "ghostty") {
 		return true
 	}
-	return false
+	// WezTerm plays inline GIFs itself but they can't be paused or stepped;
+	// drive its frames like Ghostty's.
+	return termcaps.IsWezTerm(os.Getenv)
 }
 
 func styleIf(enabled bool, text string, codes ...string) string {
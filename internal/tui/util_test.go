@@ -5,6 +5,9 @@ import (
 	"bytes"
 	"strings"
 	"testing"
+
+	"github.com/steipete/gifgrep/internal/kitty"
+	"github.com/steipete/gifgrep/internal/termcaps"
 )
 
 func TestHelpers(t *testing.T) {
@@ -24,7 +27,7 @@ func TestHelpers(t *testing.T) {
 	restoreCursor(out)
 	hideCursor(out)
 	showCursor(out)
-	clearImages(out)
+	clearImages(out, kitty.Mode{})
 	_ = out.Flush()
 	s := buf.String()
 	if !strings.Contains(s, "\x1b[2;3H") || !strings.Contains(s, "\x1b[?25l") || !strings.Contains(s, "\x1b[?25h") {
@@ -46,8 +49,14 @@ func TestHelpers(t *testing.T) {
 		t.Fatalf("min failed")
 	}
 
+	if cellAspectRatio(termcaps.CellSize{}) != 0.5 {
+		t.Fatalf("expected default cell aspect")
+	}
+	if cellAspectRatio(termcaps.CellSize{Width: 10, Height: 25}) != 0.4 {
+		t.Fatalf("expected measured cell aspect")
+	}
 	t.Setenv("GIFGREP_CELL_ASPECT", "0.7")
-	if cellAspectRatio() != 0.7 {
+	if cellAspectRatio(termcaps.CellSize{Width: 10, Height: 25}) != 0.7 {
 		t.Fatalf("cellAspectRatio env override failed")
 	}
 